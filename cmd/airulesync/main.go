@@ -1,28 +1,97 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
 	"github.com/alecthomas/kong"
 	"github.com/upamune/airulesync/internal/app"
+	"github.com/upamune/airulesync/internal/config"
+	"github.com/upamune/airulesync/internal/logging"
 )
 
 var cli struct {
 	// Global flags
-	Config  string `short:"c" help:"Path to config file" default:".airulesync.yaml"`
-	Verbose bool   `short:"v" help:"Enable verbose output"`
+	Config  string `short:"c" help:"Path to config file (default: the nearest .airulesync.yaml in the current directory or its parents)"`
+	Verbose bool   `short:"v" help:"Enable verbose output" xor:"verbosity"`
+	Quiet   bool   `short:"q" help:"Only print warnings and errors besides command results" xor:"verbosity"`
 
 	// Commands
 	Sync struct {
-		DryRun bool `short:"d" help:"Simulate execution without applying changes"`
+		DryRun                    bool     `short:"d" help:"Simulate execution without applying changes"`
+		Source                    []string `help:"Only synchronize from the given source directory (repeatable)" placeholder:"PATH" sep:"none"`
+		Target                    []string `help:"Only synchronize to the given target directory (repeatable)" placeholder:"PATH" sep:"none"`
+		ExcludeTarget             []string `help:"Don't synchronize to the given target directory (repeatable)" placeholder:"PATH" sep:"none"`
+		SkipPattern               []string `help:"Don't synchronize files whose file spec pattern or relative path matches the given glob (repeatable)" placeholder:"GLOB" sep:"none"`
+		QuietSuccess              bool     `help:"Only print the report when something changed"`
+		Format                    string   `help:"Format of the report (text or json)" enum:"text,json" default:"text"`
+		ReportFile                string   `help:"Write the report to the given file instead of stdout, printing only a short summary" placeholder:"FILE"`
+		Plan                      string   `help:"With --dry-run, write the actions the sync would apply (create, overwrite, delete or skip) to the given file as JSON" placeholder:"FILE"`
+		Color                     bool     `help:"Always colorize the status markers of the report (default: only on a terminal without NO_COLOR)" xor:"color"`
+		NoColor                   bool     `help:"Never colorize the report" xor:"color"`
+		OnlyChanged               bool     `help:"Only list files in the report whose target was created or changed, besides skipped and failed files"`
+		Strict                    bool     `help:"Treat warnings such as target collisions and external target directories as errors" aliases:"treat-warnings-as-errors"`
+		FailOnSkip                bool     `help:"Exit with a non-zero code when files are skipped"`
+		KeepGoing                 bool     `help:"Attempt every file after a file fails to synchronize, exiting with a non-zero code at the end, instead of stopping at the first failure"`
+		NoCreateDirs              bool     `help:"Skip target directories that don't exist instead of creating them"`
+		FailOnExternal            bool     `help:"Fail without synchronizing when a target directory is external to the repository"`
+		Dereference               bool     `help:"Write through target directories that are symbolic links (--no-dereference fails instead)" default:"true" negatable:""`
+		ResolveSymlinks           bool     `help:"Resolve symbolic links in source and target directories before adjusting relative paths"`
+		Stdout                    bool     `help:"Print the adjusted content of each file to stdout instead of writing it"`
+		ReportPathAdjustmentsOnly bool     `help:"Only report files with path adjustments and their original and adjusted paths"`
+		ListAdjustments           bool     `help:"Only print the path adjustments, one per line as target-file:line: 'original' -> 'adjusted'"`
+		PrintTree                 bool     `help:"Print the mapping of source files to target files as a tree instead of synchronizing"`
+		Prune                     bool     `help:"Remove previously synchronized target files whose source file no longer exists"`
+		NoAdjust                  bool     `help:"Copy every file verbatim, ignoring adjust_paths, rewrites and front matter overrides" xor:"adjust"`
+		ForceAdjust               bool     `help:"Adjust paths in every file, overriding adjust_paths: false" xor:"adjust"`
+		OutputDir                 string   `help:"Write target files below the given directory, mirroring the target layout, instead of into the real targets" placeholder:"DIR"`
+		WriteManifest             bool     `help:"Record the synchronized target files and their content hashes in .airulesync.lock"`
+		Progress                  bool     `help:"Print progress to stderr as each file is processed (implied by --verbose)"`
+		Limit                     int      `help:"Stop after changing N files and defer the rest to later runs (0 for no limit)" placeholder:"N"`
+		Cache                     bool     `help:"Cache the scan results of source directories in .airulesync.cache, rescanning only directories whose modification time changed"`
 	} `cmd:"" help:"Synchronize rule files according to configuration"`
 
 	Init struct {
-		Dir string `arg:"" optional:"" help:"Directory to scan for rule files"`
+		Dir       string   `arg:"" optional:"" help:"Directory to scan for rule files"`
+		Merge     bool     `help:"Add newly discovered rule files to an existing configuration file"`
+		Patterns  []string `help:"Comma-separated rule file patterns to scan for instead of the built-in list"`
+		YAMLStyle string   `name:"yaml-style" help:"Style of the generated YAML (block or flow)" enum:"block,flow" default:"block"`
+		Exclude   []string `help:"Directory names or globs to skip when detecting target directories (repeatable)" placeholder:"DIR"`
+		SourceExt []string `help:"Comma-separated source file extensions marking target directories instead of the built-in list" placeholder:"EXT"`
+		MaxDepth  int      `help:"Only detect target directories up to N levels below the scanned directory (0 for no limit)" placeholder:"N"`
+		Stdout    bool     `help:"Print the generated configuration to stdout instead of writing a file"`
+		ConfigOut string   `name:"config-out" help:"Path of the configuration file to write, as JSON for .json files and YAML otherwise" placeholder:"FILE"`
+		Profile   string   `help:"Seed the configuration with the default rule files of a toolset (cursor, cline, roo or all) instead of scanning" enum:",cursor,cline,roo,all" default:""`
 	} `cmd:"" help:"Scan directory and generate a configuration file"`
 
-	Version struct{} `cmd:"" help:"Display version information"`
+	Export struct {
+		Archive string   `required:"" help:"Path of the tar archive to write, gzip-compressed for .tar.gz and .tgz" placeholder:"FILE"`
+		Source  []string `help:"Only export from the given source directory (repeatable)" placeholder:"PATH" sep:"none"`
+		Target  []string `help:"Only export for the given target directory (repeatable)" placeholder:"PATH" sep:"none"`
+	} `cmd:"" help:"Write the adjusted rule files of every target directory to a tar archive"`
+
+	Lint struct {
+		ResolveSymlinks bool `help:"Resolve symbolic links in source and target directories before adjusting relative paths"`
+	} `cmd:"" help:"Check the configuration and rule files for likely mistakes"`
+
+	ConfigCmd struct {
+		Show        struct{} `cmd:"" help:"Print the effective configuration after merging includes, normalizing paths and applying defaults"`
+		ListSources struct {
+			Format string `help:"Output format (text or json)" enum:"text,json" default:"text"`
+		} `cmd:"" help:"Print the normalized paths of the source directories, including those discovered with source_glob"`
+		ListTargets struct {
+			Format string `help:"Output format (text or json)" enum:"text,json" default:"text"`
+		} `cmd:"" help:"Print the normalized paths of the target directories"`
+	} `cmd:"" name:"config" help:"Inspect the configuration"`
+
+	Verify struct{} `cmd:"" help:"Check that target files recorded in .airulesync.lock were not modified or deleted"`
+
+	Schema struct{} `cmd:"" hidden:"" help:"Print the JSON schema of the configuration file"`
+
+	Version struct {
+		JSON bool `name:"json" help:"Print the version information as JSON"`
+	} `cmd:"" help:"Display version information"`
 }
 
 func main() {
@@ -34,21 +103,102 @@ func main() {
 
 	// Create the application
 	application := app.NewApp(cli.Config, cli.Verbose)
+	application.Logger = logging.New(os.Stderr, cli.Verbose, cli.Quiet)
+
+	// Find the configuration file in a parent directory unless given
+	if cli.Config == "" {
+		if err := application.FindConfig(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(app.ExitConfigError)
+		}
+	}
 
 	// Execute the appropriate command
 	var err error
 	switch ctx.Command() {
 	case "sync":
-		err = application.RunSync(cli.Sync.DryRun)
+		// Colorize the report on terminals unless overridden
+		color := app.ColorAuto
+		if cli.Sync.Color {
+			color = app.ColorAlways
+		} else if cli.Sync.NoColor {
+			color = app.ColorNever
+		}
+
+		err = application.RunSync(app.SyncOptions{
+			DryRun:          cli.Sync.DryRun,
+			Sources:         cli.Sync.Source,
+			Targets:         cli.Sync.Target,
+			ExcludeTargets:  cli.Sync.ExcludeTarget,
+			SkipPatterns:    cli.Sync.SkipPattern,
+			QuietSuccess:    cli.Sync.QuietSuccess,
+			OnlyChanged:     cli.Sync.OnlyChanged,
+			Format:          app.ReportFormat(cli.Sync.Format),
+			ReportFile:      cli.Sync.ReportFile,
+			Plan:            cli.Sync.Plan,
+			Color:           color,
+			Strict:          cli.Sync.Strict,
+			FailOnSkip:      cli.Sync.FailOnSkip,
+			KeepGoing:       cli.Sync.KeepGoing,
+			NoCreateDirs:    cli.Sync.NoCreateDirs,
+			Stdout:          cli.Sync.Stdout,
+			AdjustmentsOnly: cli.Sync.ReportPathAdjustmentsOnly,
+			ListAdjustments: cli.Sync.ListAdjustments,
+			Limit:           cli.Sync.Limit,
+			PrintTree:       cli.Sync.PrintTree,
+			WriteManifest:   cli.Sync.WriteManifest,
+			FailOnExternal:  cli.Sync.FailOnExternal,
+			NoDereference:   !cli.Sync.Dereference,
+			ResolveSymlinks: cli.Sync.ResolveSymlinks,
+			Prune:           cli.Sync.Prune,
+			OutputDir:       cli.Sync.OutputDir,
+			NoAdjust:        cli.Sync.NoAdjust,
+			ForceAdjust:     cli.Sync.ForceAdjust,
+			Progress:        cli.Sync.Progress,
+			Cache:           cli.Sync.Cache,
+		})
 	case "init":
-		err = application.RunInit(cli.Init.Dir)
+		err = application.RunInit(cli.Init.Dir, app.InitOptions{
+			Merge:            cli.Init.Merge,
+			Patterns:         cli.Init.Patterns,
+			YAMLStyle:        config.YAMLStyle(cli.Init.YAMLStyle),
+			ExcludeDirs:      cli.Init.Exclude,
+			MaxDepth:         cli.Init.MaxDepth,
+			SourceExtensions: cli.Init.SourceExt,
+			Profile:          app.Profile(cli.Init.Profile),
+			Stdout:           cli.Init.Stdout,
+			ConfigOut:        cli.Init.ConfigOut,
+		})
+	case "export":
+		err = application.RunExport(app.ExportOptions{
+			Archive: cli.Export.Archive,
+			Sources: cli.Export.Source,
+			Targets: cli.Export.Target,
+		})
+	case "lint":
+		err = application.RunLint(cli.Lint.ResolveSymlinks)
+	case "config show":
+		err = application.RunConfigShow()
+	case "config list-sources":
+		err = application.RunConfigListSources(app.ReportFormat(cli.ConfigCmd.ListSources.Format))
+	case "config list-targets":
+		err = application.RunConfigListTargets(app.ReportFormat(cli.ConfigCmd.ListTargets.Format))
+	case "verify":
+		err = application.RunVerify()
+	case "schema":
+		err = application.RunSchema()
 	case "version":
-		err = application.RunVersion()
+		err = application.RunVersion(cli.Version.JSON)
 	}
 
-	// Handle errors
+	// Handle errors, exiting with the failure categories when known
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+
+		var exitErr *app.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
 		os.Exit(1)
 	}
 }
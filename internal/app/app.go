@@ -1,12 +1,18 @@
 package app
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/upamune/airulesync/internal/config"
+	"github.com/upamune/airulesync/internal/logging"
 	"github.com/upamune/airulesync/internal/scanner"
 	"github.com/upamune/airulesync/internal/sync"
 	"github.com/upamune/airulesync/internal/version"
@@ -16,6 +22,8 @@ import (
 type App struct {
 	ConfigPath string
 	Verbose    bool
+	Out        io.Writer
+	Logger     *slog.Logger
 }
 
 // NewApp creates a new application
@@ -23,19 +31,151 @@ func NewApp(configPath string, verbose bool) *App {
 	return &App{
 		ConfigPath: configPath,
 		Verbose:    verbose,
+		Out:        os.Stdout,
+		Logger:     logging.New(os.Stderr, verbose, false),
 	}
 }
 
+// FindConfig sets the configuration path to the nearest default
+// configuration file in the working directory or its parents, so commands
+// work from subdirectories. The default path is kept when there is none.
+func (a *App) FindConfig() error {
+	path, err := config.FindConfig(".", config.DefaultConfigPath())
+	if errors.Is(err, config.ErrConfigNotFound) {
+		a.ConfigPath = config.DefaultConfigPath()
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// Refer to the file relative to the working directory, as the paths in
+	// reports are
+	wd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+	dir, err := filepath.Rel(wd, filepath.Dir(path))
+	if err != nil {
+		return fmt.Errorf("failed to get relative path for %s: %w", path, err)
+	}
+
+	a.ConfigPath = filepath.Join(dir, config.DefaultConfigPath())
+	if dir != "." {
+		a.Logger.Debug("Using configuration file from parent directory", "path", a.ConfigPath)
+	}
+	return nil
+}
+
+// SyncOptions represents the options of the sync command
+type SyncOptions struct {
+	DryRun          bool
+	Sources         []string
+	Targets         []string
+	ExcludeTargets  []string
+	QuietSuccess    bool
+	Strict          bool
+	FailOnSkip      bool
+	KeepGoing       bool
+	NoCreateDirs    bool
+	Stdout          bool
+	AdjustmentsOnly bool
+	ListAdjustments bool
+	Limit           int
+	PrintTree       bool
+	WriteManifest   bool
+	Plan            string
+	FailOnExternal  bool
+	NoDereference   bool
+	ResolveSymlinks bool
+	Prune           bool
+	OutputDir       string
+	NoAdjust        bool
+	ForceAdjust     bool
+	Progress        bool
+	Cache           bool
+	SkipPatterns    []string
+	OnlyChanged     bool
+	Format          ReportFormat
+	ReportFile      string
+	Color           ColorMode
+}
+
+// ColorMode controls whether the sync report is colorized
+type ColorMode string
+
+// Supported color modes
+const (
+	// ColorAuto colorizes the report when it is written to a terminal and
+	// NO_COLOR is not set
+	ColorAuto   ColorMode = ""
+	ColorAlways ColorMode = "always"
+	ColorNever  ColorMode = "never"
+)
+
+// ReportFormat is the format of the sync report
+type ReportFormat string
+
+// Supported report formats
+const (
+	FormatText ReportFormat = "text"
+	FormatJSON ReportFormat = "json"
+)
+
 // RunSync runs the sync command
-func (a *App) RunSync(dryRun bool) error {
+func (a *App) RunSync(opts SyncOptions) error {
 	// Load configuration
 	cfg, err := config.LoadConfig(a.ConfigPath)
 	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
+		return &ExitError{Code: ExitConfigError, Err: fmt.Errorf("failed to load configuration: %w", err)}
+	}
+
+	// Restrict the run to the selected source and target directories
+	if err := cfg.SelectSourceDirs(opts.Sources); err != nil {
+		return &ExitError{Code: ExitConfigError, Err: fmt.Errorf("failed to select source directories: %w", err)}
+	}
+
+	if err := cfg.SelectTargetDirs(opts.Targets); err != nil {
+		return &ExitError{Code: ExitConfigError, Err: fmt.Errorf("failed to select target directories: %w", err)}
+	}
+
+	for _, path := range cfg.ExcludeTargetDirs(opts.ExcludeTargets) {
+		a.Logger.Warn("Excluded target directory is not configured", "path", path)
+	}
+
+	if opts.Plan != "" && !opts.DryRun {
+		return &ExitError{Code: ExitConfigError, Err: fmt.Errorf("--plan requires --dry-run")}
 	}
 
 	// Create a syncer
-	syncer := sync.NewSyncer(cfg, dryRun, a.Verbose)
+	syncer := sync.NewSyncer(cfg, opts.DryRun, a.Verbose)
+	syncer.Strict = opts.Strict
+	syncer.StopOnError = !opts.KeepGoing
+	syncer.NoCreateDirs = opts.NoCreateDirs
+	syncer.Stdout = opts.Stdout
+	syncer.Limit = opts.Limit
+	syncer.FailOnExternal = opts.FailOnExternal
+	syncer.NoDereference = opts.NoDereference
+	syncer.PathAdjuster.ResolveSymlinks = opts.ResolveSymlinks
+	syncer.Prune = opts.Prune
+	syncer.ManifestPath = filepath.Join(filepath.Dir(a.ConfigPath), sync.ManifestFile)
+	syncer.OutputDir = opts.OutputDir
+	syncer.NoAdjust = opts.NoAdjust
+	syncer.ForceAdjust = opts.ForceAdjust
+	if opts.Progress || a.Verbose {
+		syncer.Progress = os.Stderr
+	}
+	syncer.OnlyChanged = opts.OnlyChanged
+	syncer.SkipPatterns = opts.SkipPatterns
+	if opts.Cache {
+		syncer.Scanner.CachePath = filepath.Join(filepath.Dir(a.ConfigPath), scanner.CacheFile)
+	}
+	syncer.Out = a.Out
+	syncer.SetLogger(a.Logger)
+
+	// Print the sync mapping instead of synchronizing
+	if opts.PrintTree {
+		return syncer.PrintTree()
+	}
 
 	// Run the synchronization
 	report, err := syncer.Sync()
@@ -43,14 +183,123 @@ func (a *App) RunSync(dryRun bool) error {
 		return fmt.Errorf("synchronization failed: %w", err)
 	}
 
-	// Print the report
-	syncer.PrintReport(report, dryRun)
+	// Record the synchronized files next to the configuration file. Pruning
+	// relies on the record to only remove files it wrote.
+	if (opts.WriteManifest || opts.Prune) && !opts.DryRun && !opts.Stdout {
+		if err := syncer.WriteManifest(report, syncer.ManifestPath); err != nil {
+			return fmt.Errorf("failed to write manifest: %w", err)
+		}
+	}
+
+	// Write the actions the sync would apply for other tools to apply
+	if opts.Plan != "" {
+		if err := sync.WritePlan(report, opts.Plan); err != nil {
+			return err
+		}
+	}
+
+	// Print the report, unless the content was printed instead or nothing
+	// changed and only changes should be reported
+	if !opts.Stdout && (!opts.QuietSuccess || report.HasChanges()) {
+		if err := a.writeReport(syncer, report, opts); err != nil {
+			return err
+		}
+	}
+
+	// Fail with the categories of problems found in the results
+	if code := reportExitCode(report, opts.FailOnSkip, opts.Strict); code != 0 {
+		return &ExitError{Code: code, Err: fmt.Errorf("synchronization finished with %s", describeExitCode(code))}
+	}
+
+	// Signal that a real sync would change target files
+	if opts.DryRun && report.HasChanges() {
+		return &ExitError{Code: ExitPendingChanges, Err: fmt.Errorf("dry run found files that are out of sync")}
+	}
 
 	return nil
 }
 
+// writeReport prints the sync report in the selected format, writing it to
+// the report file and printing a short summary instead when one is given
+func (a *App) writeReport(syncer *sync.Syncer, report *sync.SyncReport, opts SyncOptions) error {
+	var file *os.File
+	if opts.ReportFile != "" {
+		if err := os.MkdirAll(filepath.Dir(opts.ReportFile), 0755); err != nil {
+			return fmt.Errorf("failed to create report directory: %w", err)
+		}
+
+		var err error
+		file, err = os.Create(opts.ReportFile)
+		if err != nil {
+			return fmt.Errorf("failed to create report file: %w", err)
+		}
+		defer file.Close()
+
+		syncer.Out = file
+		defer func() { syncer.Out = a.Out }()
+	}
+	syncer.Color = useColor(opts.Color, syncer.Out)
+
+	switch {
+	case opts.ListAdjustments:
+		syncer.PrintAdjustmentLines(report)
+	case opts.Format == FormatJSON:
+		if err := syncer.PrintJSONReport(report, opts.DryRun); err != nil {
+			return err
+		}
+	case opts.AdjustmentsOnly:
+		syncer.PrintAdjustmentReport(report, opts.DryRun)
+	default:
+		syncer.PrintReport(report, opts.DryRun)
+	}
+
+	if file == nil {
+		return nil
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write report file: %w", err)
+	}
+
+	synchronized, skipped, failed := report.Counts()
+	fmt.Fprintf(a.Out, "Report written to %s: %d synchronized, %d skipped, %d errors\n", opts.ReportFile, synchronized, skipped, failed)
+	return nil
+}
+
+// useColor decides whether to colorize output written to w in a color mode
+func useColor(mode ColorMode, w io.Writer) bool {
+	switch mode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// InitOptions represents the options of the init command
+type InitOptions struct {
+	Merge            bool
+	Patterns         []string
+	YAMLStyle        config.YAMLStyle
+	ExcludeDirs      []string
+	MaxDepth         int
+	SourceExtensions []string
+	Profile          Profile
+	Stdout           bool
+	ConfigOut        string
+}
+
 // RunInit runs the init command
-func (a *App) RunInit(dir string) error {
+func (a *App) RunInit(dir string, opts InitOptions) error {
 	// If no directory is specified, use the current directory
 	if dir == "" {
 		var err error
@@ -65,127 +314,232 @@ func (a *App) RunInit(dir string) error {
 		return fmt.Errorf("directory %s does not exist", dir)
 	}
 
-	// Check if configuration file already exists
+	// Check if configuration file already exists, unless the configuration
+	// is printed instead of written
 	configPath := config.DefaultConfigPath()
-	if _, err := os.Stat(configPath); err == nil {
-		fmt.Printf("Configuration file %s already exists. Skipping initialization.\n", configPath)
-		return nil
-	} else if !os.IsNotExist(err) {
-		return fmt.Errorf("failed to check if configuration file exists: %w", err)
+	if opts.ConfigOut != "" {
+		configPath = opts.ConfigOut
 	}
-
-	fmt.Printf("Scanning directory for rule files...\n")
-
-	// Create a scanner
-	s := scanner.NewScanner(nil)
-
-	// Scan the directory for rule files
-	ruleFiles, err := s.ScanDirectory(dir)
-	if err != nil {
-		return fmt.Errorf("failed to scan directory: %w", err)
+	configExists := false
+	if !opts.Stdout {
+		if _, err := os.Stat(configPath); err == nil {
+			if !opts.Merge {
+				a.Logger.Info("Configuration file already exists. Skipping initialization.", "path", configPath)
+				return nil
+			}
+			if strings.EqualFold(filepath.Ext(configPath), ".json") {
+				return fmt.Errorf("merging into JSON configuration file %s is not supported", configPath)
+			}
+			configExists = true
+		} else if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to check if configuration file exists: %w", err)
+		}
 	}
 
+	// Seed the configuration with the file specs of a profile, or with the
+	// rule files found in the directory
 	var cfg *config.Config
+	if opts.Profile != "" {
+		specs, err := ProfileFileSpecs(opts.Profile)
+		if err != nil {
+			return err
+		}
 
-	if len(ruleFiles) == 0 {
-		fmt.Println("No rule files found in the directory.")
-		// Create a default empty configuration
+		a.Logger.Info("Using file specs of profile", "profile", opts.Profile)
 		cfg = &config.Config{
-			SourceDirs: []config.SourceDir{},
+			SourceDirs: []config.SourceDir{{Path: ".", Files: specs}},
 			TargetDirs: []config.TargetDir{},
 		}
 	} else {
-		fmt.Printf("Found %d potential rule files:\n", len(ruleFiles))
-		for _, file := range ruleFiles {
-			fmt.Printf("- %s\n", filepath.Join(dir, file))
+		var err error
+		cfg, err = a.scanConfig(dir, opts)
+		if err != nil {
+			return err
 		}
+	}
 
-		// Find potential target directories
-		fmt.Println("\nDetecting potential target directories...")
-		targetDirs, err := s.FindPotentialTargetDirs(dir)
+	// Print the configuration instead of writing it
+	if opts.Stdout {
+		data, err := config.MarshalConfigForPath(cfg, configPath, opts.YAMLStyle)
 		if err != nil {
-			return fmt.Errorf("failed to find potential target directories: %w", err)
+			return err
 		}
+		if _, err := a.Out.Write(data); err != nil {
+			return fmt.Errorf("failed to write configuration: %w", err)
+		}
+		return nil
+	}
 
-		if len(targetDirs) == 0 {
-			fmt.Println("No potential target directories found.")
-		} else {
-			fmt.Printf("Detected potential target directories:\n")
-			for _, targetDir := range targetDirs {
-				fmt.Printf("- %s\n", filepath.Join(dir, targetDir))
-			}
+	// Merge newly discovered files into the existing configuration
+	if configExists {
+		added, err := config.MergeConfig(configPath, cfg)
+		if err != nil {
+			return fmt.Errorf("failed to merge configuration: %w", err)
 		}
 
-		// Generate a configuration
-		cfg = a.generateConfig(dir, ruleFiles, targetDirs)
+		a.Logger.Info("Merged new file patterns", "count", added, "path", configPath)
+		return nil
 	}
 
 	// Save the configuration
-	if err := config.SaveConfig(cfg, configPath); err != nil {
+	if err := config.SaveConfigWithStyle(cfg, configPath, opts.YAMLStyle); err != nil {
 		return fmt.Errorf("failed to save configuration: %w", err)
 	}
 
-	fmt.Printf("\nConfiguration written to %s\n", configPath)
-	fmt.Println("Review and edit the configuration as needed before running 'airulesync sync'")
+	a.Logger.Info("Configuration written", "path", configPath)
+	a.Logger.Info("Review and edit the configuration as needed before running 'airulesync sync'")
 
 	return nil
 }
 
-// generateConfig generates a configuration based on the scan results
-func (a *App) generateConfig(baseDir string, ruleFiles, targetDirs []string) *config.Config {
-	// Group rule files by directory
-	filesByDir := make(map[string][]string)
-	hasCursorRules := false
+// scanConfig generates a configuration from the rule files and potential
+// target directories found in a directory
+func (a *App) scanConfig(dir string, opts InitOptions) (*config.Config, error) {
+	a.Logger.Info("Scanning directory for rule files...")
+
+	// Create a scanner, using custom rule file patterns when given
+	s := scanner.NewScanner(nil)
+	s.ExcludeDirs = opts.ExcludeDirs
+	s.MaxDepth = opts.MaxDepth
+	if len(opts.SourceExtensions) > 0 {
+		s.SourceExtensions = opts.SourceExtensions
+	}
+	if len(opts.Patterns) > 0 {
+		s.RulePatterns = opts.Patterns
+	} else if patterns, err := scanner.LoadRulePatterns(filepath.Join(dir, scanner.RulePatternsFile)); err == nil {
+		a.Logger.Info("Using rule file patterns from file", "path", scanner.RulePatternsFile)
+		s.RulePatterns = patterns
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	// Scan the directory for rule files
+	ruleFiles, err := s.ScanDirectory(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan directory: %w", err)
+	}
+
+	if len(ruleFiles) == 0 {
+		a.Logger.Info("No rule files found in the directory.")
+		// Create a default empty configuration
+		return &config.Config{
+			SourceDirs: []config.SourceDir{},
+			TargetDirs: []config.TargetDir{},
+		}, nil
+	}
 
+	a.Logger.Info("Found potential rule files", "count", len(ruleFiles))
 	for _, file := range ruleFiles {
-		dir := filepath.Dir(file)
-		if dir == "." {
-			dir = ""
-		}
+		a.Logger.Info("- " + filepath.Join(dir, file))
+	}
 
-		// Check if this is a file in the .cursor/rules directory
-		if strings.HasPrefix(dir, ".cursor/rules") {
-			hasCursorRules = true
-			continue // Skip individual .cursor/rules files
-		}
+	// Find potential target directories
+	a.Logger.Info("Detecting potential target directories...")
+	targetDirs, err := s.FindPotentialTargetDirs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find potential target directories: %w", err)
+	}
 
-		filesByDir[dir] = append(filesByDir[dir], filepath.Base(file))
+	if len(targetDirs) == 0 {
+		a.Logger.Info("No potential target directories found.")
+	} else {
+		a.Logger.Info("Detected potential target directories", "count", len(targetDirs))
+		for _, targetDir := range targetDirs {
+			a.Logger.Info("- " + filepath.Join(dir, targetDir))
+		}
 	}
 
-	// Create source directories
-	var sourceDirs []config.SourceDir
+	// Generate a configuration
+	return GenerateConfig(dir, ruleFiles, targetDirs), nil
+}
 
-	// Create a source directory for the base directory
-	var baseFileSpecs []config.FileSpec
+// toolLayouts are the directories tools read rule files from, such as
+// .cursor/rules or .github, and the pattern covering all of their files, if
+// any. Their files belong to the source directory owning the tool directory,
+// so that targets receive them in the same layout.
+var toolLayouts = []struct {
+	dir     string
+	pattern string
+}{
+	{dir: ".cursor/rules", pattern: ".cursor/rules/*.mdc"},
+	{dir: ".github"},
+}
 
-	// If .cursor/rules directory exists, add a pattern for all .mdc files
-	if hasCursorRules {
-		baseFileSpecs = append(baseFileSpecs, config.FileSpec{
-			Pattern: ".cursor/rules/*.mdc",
-		})
+// toolLayoutOwner returns the directory owning the tool directory a
+// directory of rule files is, and the pattern of a file in it
+func toolLayoutOwner(dir, file string) (string, string, bool) {
+	for _, layout := range toolLayouts {
+		if dir != layout.dir && !strings.HasSuffix(dir, "/"+layout.dir) {
+			continue
+		}
+
+		owner := strings.TrimSuffix(strings.TrimSuffix(dir, layout.dir), "/")
+		if owner == "" {
+			owner = "."
+		}
+		pattern := layout.pattern
+		if pattern == "" {
+			pattern = layout.dir + "/" + filepath.Base(file)
+		}
+		return owner, pattern, true
 	}
+	return "", "", false
+}
+
+// GenerateConfig generates a configuration based on the scan results. Rule
+// files are grouped by the directory they live in, and each directory becomes
+// its own source directory, ordered by the number of rule files it holds.
+// Files in tool directories such as .cursor/rules or .github belong to the
+// directory owning the tool directory.
+func GenerateConfig(baseDir string, ruleFiles, targetDirs []string) *config.Config {
+	// Group rule files by directory, with the patterns of tool directories
+	// first
+	filesByDir := make(map[string][]config.FileSpec)
+	layoutFiles := make(map[string][]config.FileSpec)
+	seenLayouts := make(map[string]bool)
+	counts := make(map[string]int)
+
+	for _, file := range ruleFiles {
+		dir := filepath.ToSlash(filepath.Dir(file))
 
-	// Add other files from the base directory
-	for dir, files := range filesByDir {
-		for _, file := range files {
-			// Check if the file is in a subdirectory
-			if dir != "" {
-				baseFileSpecs = append(baseFileSpecs, config.FileSpec{
-					Pattern: filepath.Join(dir, file),
-				})
-			} else {
-				baseFileSpecs = append(baseFileSpecs, config.FileSpec{
-					Pattern: file,
-				})
+		if owner, pattern, ok := toolLayoutOwner(dir, file); ok {
+			if !seenLayouts[owner+"/"+pattern] {
+				seenLayouts[owner+"/"+pattern] = true
+				layoutFiles[owner] = append(layoutFiles[owner], config.FileSpec{Pattern: pattern})
 			}
+			counts[owner]++
+			continue
 		}
+
+		filesByDir[dir] = append(filesByDir[dir], config.FileSpec{Pattern: filepath.Base(file)})
+		counts[dir]++
+	}
+	for owner, specs := range layoutFiles {
+		filesByDir[owner] = append(specs, filesByDir[owner]...)
 	}
 
-	// Add the source directory if it has files
-	if len(baseFileSpecs) > 0 {
+	// Directories with the most rule files come first
+	dirs := make([]string, 0, len(filesByDir))
+	for dir := range filesByDir {
+		dirs = append(dirs, dir)
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		if counts[dirs[i]] != counts[dirs[j]] {
+			return counts[dirs[i]] > counts[dirs[j]]
+		}
+		return dirs[i] < dirs[j]
+	})
+
+	// Create a source directory per directory, using relative paths
+	var sourceDirs []config.SourceDir
+	for _, dir := range dirs {
+		path := "."
+		if dir != "." {
+			path = "./" + dir
+		}
 		sourceDirs = append(sourceDirs, config.SourceDir{
-			Path:  ".", // Use relative path instead of absolute path
-			Files: baseFileSpecs,
+			Path:  path,
+			Files: filesByDir[dir],
 		})
 	}
 
@@ -199,8 +553,17 @@ func (a *App) generateConfig(baseDir string, ruleFiles, targetDirs []string) *co
 	}
 }
 
-// RunVersion runs the version command
-func (a *App) RunVersion() error {
-	fmt.Println(version.FormatBuildInfo())
+// RunVersion runs the version command, printing JSON when jsonOut is set
+func (a *App) RunVersion(jsonOut bool) error {
+	if !jsonOut {
+		fmt.Fprintln(a.Out, version.FormatBuildInfo())
+		return nil
+	}
+
+	data, err := json.MarshalIndent(version.GetBuildInfo(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal build info: %w", err)
+	}
+	fmt.Fprintln(a.Out, string(data))
 	return nil
 }
@@ -1,10 +1,22 @@
 package app
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/upamune/airulesync/internal/config"
+	"gopkg.in/yaml.v3"
 )
 
 func TestRunSync(t *testing.T) {
@@ -62,7 +74,7 @@ func TestRunSync(t *testing.T) {
 	app := NewApp(".airulesync.yaml", true)
 
 	// Run the sync command
-	if err := app.RunSync(false); err != nil {
+	if err := app.RunSync(SyncOptions{}); err != nil {
 		t.Fatalf("Failed to run sync command: %v", err)
 	}
 
@@ -123,13 +135,14 @@ func TestRunInit(t *testing.T) {
 
 	// Create test files
 	files := map[string]string{
-		filepath.Join(projectDir, ".clinerules"):   "# Test clinerules file",
-		filepath.Join(projectDir, ".roomodes"):     "# Test roomodes file",
-		filepath.Join(projectDir, ".clineignore"):  "# Test clineignore file",
-		filepath.Join(projectDir, ".cursorignore"): "# Test cursorignore file",
-		filepath.Join(cursorRulesDir, "rule1.mdc"): "# Test rule1 file",
-		filepath.Join(subDirA, "main.go"):          "package main",
-		filepath.Join(subDirB, "app.js"):           "console.log('Hello');",
+		filepath.Join(projectDir, ".clinerules"):    "# Test clinerules file",
+		filepath.Join(projectDir, ".roomodes"):      "# Test roomodes file",
+		filepath.Join(projectDir, ".clineignore"):   "# Test clineignore file",
+		filepath.Join(projectDir, ".cursorignore"):  "# Test cursorignore file",
+		filepath.Join(projectDir, ".windsurfrules"): "# Test windsurfrules file",
+		filepath.Join(cursorRulesDir, "rule1.mdc"):  "# Test rule1 file",
+		filepath.Join(subDirA, "main.go"):           "package main",
+		filepath.Join(subDirB, "app.js"):            "console.log('Hello');",
 	}
 
 	for path, content := range files {
@@ -153,7 +166,7 @@ func TestRunInit(t *testing.T) {
 	app := NewApp(".airulesync.yaml", true)
 
 	// Run the init command
-	if err := app.RunInit(projectDir); err != nil {
+	if err := app.RunInit(projectDir, InitOptions{}); err != nil {
 		t.Fatalf("Failed to run init command: %v", err)
 	}
 
@@ -177,6 +190,7 @@ func TestRunInit(t *testing.T) {
 		".roomodes",
 		".clineignore",
 		".cursorignore",
+		".windsurfrules",
 		".cursor/rules/*.mdc",
 		"target_dirs: []", // Now empty target_dirs
 	}
@@ -188,6 +202,144 @@ func TestRunInit(t *testing.T) {
 	}
 }
 
+func TestRunInitWithCustomPatterns(t *testing.T) {
+	// Skip this test in short mode
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	// Create a project with built-in and custom rule files
+	tempDir := t.TempDir()
+	projectDir := filepath.Join(tempDir, "init-test-patterns")
+	writeFiles(t, map[string]string{
+		filepath.Join(projectDir, ".clinerules"):       "# Test clinerules file",
+		filepath.Join(projectDir, "AGENTS.md"):         "# Test agents file",
+		filepath.Join(projectDir, "docs", "AGENTS.md"): "# Test nested agents file",
+		filepath.Join(projectDir, "docs", "README.md"): "# Not a rule file",
+		filepath.Join(projectDir, ".windsurfrules"):    "# Test windsurfrules file",
+	})
+
+	// Test cases for pattern sources
+	testCases := []struct {
+		name         string
+		opts         InitOptions
+		patternsFile string
+	}{
+		{
+			name: "flag",
+			opts: InitOptions{Patterns: []string{"AGENTS.md", "docs/AGENTS.md"}},
+		},
+		{
+			name:         "patterns file",
+			patternsFile: "# Agent instructions\nAGENTS.md\n\ndocs/AGENTS.md\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			workDir := t.TempDir()
+			chdir(t, workDir)
+
+			patternsPath := filepath.Join(projectDir, ".airulesync.patterns")
+			os.Remove(patternsPath)
+			if tc.patternsFile != "" {
+				writeFiles(t, map[string]string{patternsPath: tc.patternsFile})
+			}
+
+			app := NewApp(".airulesync.yaml", false)
+			if err := app.RunInit(projectDir, tc.opts); err != nil {
+				t.Fatalf("Failed to run init command: %v", err)
+			}
+
+			configContent, err := os.ReadFile(filepath.Join(workDir, ".airulesync.yaml"))
+			if err != nil {
+				t.Fatalf("Failed to read configuration file: %v", err)
+			}
+
+			// Only files matching the custom patterns are discovered
+			for _, expected := range []string{"AGENTS.md", "path: ./docs"} {
+				if !contains(string(configContent), expected) {
+					t.Errorf("Expected configuration to contain '%s', got:\n%s", expected, configContent)
+				}
+			}
+			for _, unexpected := range []string{".clinerules", ".windsurfrules", "README.md"} {
+				if contains(string(configContent), unexpected) {
+					t.Errorf("Expected configuration not to contain '%s', got:\n%s", unexpected, configContent)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateConfigGroupsBySourceDir(t *testing.T) {
+	cfg := GenerateConfig(".", []string{
+		"docs/CLAUDE.md",
+		"config/.clinerules",
+		"config/.windsurfrules",
+	}, nil)
+
+	if len(cfg.SourceDirs) != 2 {
+		t.Fatalf("Expected 2 source dirs, got %d: %+v", len(cfg.SourceDirs), cfg.SourceDirs)
+	}
+
+	// The directory with the most rule files comes first
+	expected := []struct {
+		path     string
+		patterns []string
+	}{
+		{path: "./config", patterns: []string{".clinerules", ".windsurfrules"}},
+		{path: "./docs", patterns: []string{"CLAUDE.md"}},
+	}
+	for i, want := range expected {
+		got := cfg.SourceDirs[i]
+		if got.Path != want.path {
+			t.Errorf("Source dir %d: expected path %q, got %q", i, want.path, got.Path)
+		}
+		var patterns []string
+		for _, spec := range got.Files {
+			patterns = append(patterns, spec.Pattern)
+		}
+		if !reflect.DeepEqual(patterns, want.patterns) {
+			t.Errorf("Source dir %d: expected patterns %v, got %v", i, want.patterns, patterns)
+		}
+	}
+}
+
+func TestGenerateConfigKeepsToolLayouts(t *testing.T) {
+	cfg := GenerateConfig(".", []string{
+		".github/copilot-instructions.md",
+		".clinerules",
+		"web/.cursor/rules/a.mdc",
+		"web/.cursor/rules/b.mdc",
+		"web/.github/copilot-instructions.md",
+	}, nil)
+
+	// Files in tool directories belong to the directory owning them
+	expected := []struct {
+		path     string
+		patterns []string
+	}{
+		{path: "./web", patterns: []string{".cursor/rules/*.mdc", ".github/copilot-instructions.md"}},
+		{path: ".", patterns: []string{".github/copilot-instructions.md", ".clinerules"}},
+	}
+	if len(cfg.SourceDirs) != len(expected) {
+		t.Fatalf("Expected %d source dirs, got %d: %+v", len(expected), len(cfg.SourceDirs), cfg.SourceDirs)
+	}
+	for i, want := range expected {
+		got := cfg.SourceDirs[i]
+		if got.Path != want.path {
+			t.Errorf("Source dir %d: expected path %q, got %q", i, want.path, got.Path)
+		}
+		var patterns []string
+		for _, spec := range got.Files {
+			patterns = append(patterns, spec.Pattern)
+		}
+		if !reflect.DeepEqual(patterns, want.patterns) {
+			t.Errorf("Source dir %d: expected patterns %v, got %v", i, want.patterns, patterns)
+		}
+	}
+}
+
 func TestRunInitWithExistingConfig(t *testing.T) {
 	// Skip this test in short mode
 	if testing.Short() {
@@ -239,7 +391,7 @@ target_dirs:
 	app := NewApp(".airulesync.yaml", true)
 
 	// Run the init command
-	if err := app.RunInit(projectDir); err != nil {
+	if err := app.RunInit(projectDir, InitOptions{}); err != nil {
 		t.Fatalf("Failed to run init command: %v", err)
 	}
 
@@ -300,7 +452,7 @@ func TestRunInitWithNoRuleFiles(t *testing.T) {
 	app := NewApp(".airulesync.yaml", true)
 
 	// Run the init command
-	if err := app.RunInit(projectDir); err != nil {
+	if err := app.RunInit(projectDir, InitOptions{}); err != nil {
 		t.Fatalf("Failed to run init command: %v", err)
 	}
 
@@ -331,17 +483,1601 @@ func TestRunInitWithNoRuleFiles(t *testing.T) {
 	}
 }
 
-// Helper function to copy a file
-func copyFile(src, dst string) error {
-	data, err := os.ReadFile(src)
+func TestRunInitWithProfile(t *testing.T) {
+	// Skip this test in short mode
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	// Create an empty project, so the config can only come from the profile
+	projectDir := t.TempDir()
+	chdir(t, projectDir)
+
+	app := NewApp(".airulesync.yaml", false)
+	if err := app.RunInit(projectDir, InitOptions{Profile: ProfileCursor}); err != nil {
+		t.Fatalf("Failed to run init command: %v", err)
+	}
+
+	// The generated configuration has no target directories yet, so it is
+	// parsed without validation
+	data, err := os.ReadFile(".airulesync.yaml")
 	if err != nil {
-		return err
+		t.Fatalf("Failed to read configuration file: %v", err)
+	}
+	var cfg config.Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("Failed to parse generated configuration: %v", err)
 	}
 
-	return os.WriteFile(dst, data, 0644)
+	if len(cfg.SourceDirs) != 1 {
+		t.Fatalf("Expected 1 source directory, got %d", len(cfg.SourceDirs))
+	}
+
+	// The cursor profile seeds the Cursor rules and ignore file
+	expected := []struct {
+		pattern     string
+		adjustPaths bool
+	}{
+		{".cursor/rules/*.mdc", true},
+		{".cursorignore", false},
+	}
+
+	files := cfg.SourceDirs[0].Files
+	if len(files) != len(expected) {
+		t.Fatalf("Expected %d file specs, got %d: %+v", len(expected), len(files), files)
+	}
+	for i, want := range expected {
+		if files[i].Pattern != want.pattern {
+			t.Errorf("Expected file spec %d to have pattern '%s', got '%s'", i, want.pattern, files[i].Pattern)
+		}
+		if files[i].AdjustPaths == nil || *files[i].AdjustPaths != want.adjustPaths {
+			t.Errorf("Expected file spec '%s' to have adjust_paths %v, got %v", want.pattern, want.adjustPaths, files[i].AdjustPaths)
+		}
+	}
+
+	// Unknown profiles are rejected
+	if _, err := ProfileFileSpecs("vim"); err == nil {
+		t.Errorf("Expected error for unknown profile, but got nil")
+	}
 }
 
-// Helper function to check if a string contains a substring
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) && strings.Contains(s, substr)
+func TestRunInitWithStdout(t *testing.T) {
+	// Skip this test in short mode
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	// Create a project with a rule file and an existing configuration
+	projectDir := t.TempDir()
+	existingConfig := "# existing\n"
+	writeFiles(t, map[string]string{
+		filepath.Join(projectDir, ".clinerules"):      "# Test clinerules file\n",
+		filepath.Join(projectDir, ".airulesync.yaml"): existingConfig,
+	})
+	chdir(t, projectDir)
+
+	var out bytes.Buffer
+	app := NewApp(".airulesync.yaml", false)
+	app.Out = &out
+	if err := app.RunInit(projectDir, InitOptions{Stdout: true}); err != nil {
+		t.Fatalf("Failed to run init command: %v", err)
+	}
+
+	// The printed configuration has the header comments and is valid YAML
+	if !strings.HasPrefix(out.String(), "# yaml-language-server: $schema=") {
+		t.Errorf("Expected header comments, got:\n%s", out.String())
+	}
+
+	var cfg config.Config
+	if err := yaml.Unmarshal(out.Bytes(), &cfg); err != nil {
+		t.Fatalf("Failed to parse printed configuration: %v\n%s", err, out.String())
+	}
+	if len(cfg.SourceDirs) != 1 || len(cfg.SourceDirs[0].Files) != 1 || cfg.SourceDirs[0].Files[0].Pattern != ".clinerules" {
+		t.Errorf("Expected a source directory with '.clinerules', got: %+v", cfg.SourceDirs)
+	}
+
+	// The existing configuration file is left untouched
+	content, err := os.ReadFile(filepath.Join(projectDir, ".airulesync.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to read configuration file: %v", err)
+	}
+	if string(content) != existingConfig {
+		t.Errorf("Expected existing configuration to be kept, got:\n%s", content)
+	}
+}
+
+func TestRunSyncWithTargetFilter(t *testing.T) {
+	// Skip this test in short mode
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	// Create a project with three target directories
+	projectDir := t.TempDir()
+	writeFiles(t, map[string]string{
+		filepath.Join(projectDir, ".clinerules"): "# Test clinerules file\n",
+		filepath.Join(projectDir, ".airulesync.yaml"): `
+source_dirs:
+  - path: "."
+    files:
+      - ".clinerules"
+target_dirs:
+  - path: "./sub-a"
+  - path: "./sub-b"
+  - path: "./sub-c"
+`,
+	})
+	chdir(t, projectDir)
+
+	// Run the sync command for sub-a only
+	app := NewApp(".airulesync.yaml", false)
+	if err := app.RunSync(SyncOptions{Targets: []string{"sub-a"}}); err != nil {
+		t.Fatalf("Failed to run sync command: %v", err)
+	}
+
+	// Verify that only sub-a received the file
+	expected := map[string]bool{
+		"sub-a": true,
+		"sub-b": false,
+		"sub-c": false,
+	}
+	for dir, shouldExist := range expected {
+		_, err := os.Stat(filepath.Join(projectDir, dir, ".clinerules"))
+		if exists := err == nil; exists != shouldExist {
+			t.Errorf("Expected file in %s to exist=%v, got exist=%v", dir, shouldExist, exists)
+		}
+	}
+
+	// Verify that an unknown target is rejected
+	if err := app.RunSync(SyncOptions{Targets: []string{"sub-x"}}); err == nil {
+		t.Errorf("Expected error for unknown target, but got nil")
+	}
+}
+
+func TestRunSyncWithExcludeTarget(t *testing.T) {
+	// Skip this test in short mode
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	// Create a project with three target directories
+	projectDir := t.TempDir()
+	writeFiles(t, map[string]string{
+		filepath.Join(projectDir, ".clinerules"): "# Test clinerules file\n",
+		filepath.Join(projectDir, ".airulesync.yaml"): `
+source_dirs:
+  - path: "."
+    files:
+      - ".clinerules"
+target_dirs:
+  - path: "./sub-a"
+  - path: "./sub-b"
+  - path: "./sub-c"
+`,
+	})
+	chdir(t, projectDir)
+
+	// Exclude sub-b and a target that isn't configured
+	var logs bytes.Buffer
+	app := NewApp(".airulesync.yaml", false)
+	app.Out = io.Discard
+	app.Logger = slog.New(slog.NewTextHandler(&logs, nil))
+	if err := app.RunSync(SyncOptions{ExcludeTargets: []string{"./sub-b", "sub-x"}}); err != nil {
+		t.Fatalf("Failed to run sync command: %v", err)
+	}
+
+	// Verify that every target except sub-b received the file
+	expected := map[string]bool{
+		"sub-a": true,
+		"sub-b": false,
+		"sub-c": true,
+	}
+	for dir, shouldExist := range expected {
+		_, err := os.Stat(filepath.Join(projectDir, dir, ".clinerules"))
+		if exists := err == nil; exists != shouldExist {
+			t.Errorf("Expected file in %s to exist=%v, got exist=%v", dir, shouldExist, exists)
+		}
+	}
+
+	// Verify that the unknown exclusion was only warned about
+	if !strings.Contains(logs.String(), "level=WARN") || !strings.Contains(logs.String(), "sub-x") {
+		t.Errorf("Expected a warning about the unknown exclusion, got:\n%s", logs.String())
+	}
+}
+
+func TestRunSyncWithSourceFilter(t *testing.T) {
+	// Skip this test in short mode
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	// Create a project with two source and two target directories
+	projectDir := t.TempDir()
+	writeFiles(t, map[string]string{
+		filepath.Join(projectDir, "rules-a", ".clinerules"): "# Rules A\n",
+		filepath.Join(projectDir, "rules-b", ".roomodes"):   "# Rules B\n",
+		filepath.Join(projectDir, ".airulesync.yaml"): `
+source_dirs:
+  - path: "./rules-a"
+    files:
+      - ".clinerules"
+  - path: "./rules-b"
+    files:
+      - ".roomodes"
+target_dirs:
+  - path: "./sub-a"
+  - path: "./sub-b"
+`,
+	})
+	chdir(t, projectDir)
+
+	testCases := []struct {
+		name     string
+		opts     SyncOptions
+		expected map[string]bool
+	}{
+		{
+			name: "single source",
+			opts: SyncOptions{Sources: []string{"rules-a"}},
+			expected: map[string]bool{
+				"sub-a/.clinerules": true,
+				"sub-a/.roomodes":   false,
+				"sub-b/.clinerules": true,
+				"sub-b/.roomodes":   false,
+			},
+		},
+		{
+			name: "single source and single target",
+			opts: SyncOptions{Sources: []string{"./rules-b"}, Targets: []string{"sub-b"}},
+			expected: map[string]bool{
+				"sub-a/.clinerules": false,
+				"sub-a/.roomodes":   false,
+				"sub-b/.clinerules": false,
+				"sub-b/.roomodes":   true,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Start from clean target directories
+			for _, dir := range []string{"sub-a", "sub-b"} {
+				if err := os.RemoveAll(filepath.Join(projectDir, dir)); err != nil {
+					t.Fatalf("Failed to clean target directory: %v", err)
+				}
+			}
+
+			app := NewApp(".airulesync.yaml", false)
+			app.Out = io.Discard
+			if err := app.RunSync(tc.opts); err != nil {
+				t.Fatalf("Failed to run sync command: %v", err)
+			}
+
+			for path, shouldExist := range tc.expected {
+				_, err := os.Stat(filepath.Join(projectDir, path))
+				if exists := err == nil; exists != shouldExist {
+					t.Errorf("Expected %s to exist=%v, got exist=%v", path, shouldExist, exists)
+				}
+			}
+		})
+	}
+
+	// Verify that an unknown source is rejected
+	app := NewApp(".airulesync.yaml", false)
+	if err := app.RunSync(SyncOptions{Sources: []string{"rules-x"}}); err == nil {
+		t.Errorf("Expected error for unknown source, but got nil")
+	} else if !strings.Contains(err.Error(), "rules-x") {
+		t.Errorf("Expected error to name the unknown source, got: %v", err)
+	}
+}
+
+func TestRunSyncWithQuietSuccess(t *testing.T) {
+	// Skip this test in short mode
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	// Create a project with a single target directory
+	projectDir := t.TempDir()
+	writeFiles(t, map[string]string{
+		filepath.Join(projectDir, ".clinerules"): "# Test clinerules file\n",
+		filepath.Join(projectDir, ".airulesync.yaml"): `
+source_dirs:
+  - path: "."
+    files:
+      - ".clinerules"
+target_dirs:
+  - path: "./sub-a"
+`,
+	})
+	chdir(t, projectDir)
+
+	// The first run writes the file and prints the report
+	var out bytes.Buffer
+	app := NewApp(".airulesync.yaml", false)
+	app.Out = &out
+	if err := app.RunSync(SyncOptions{QuietSuccess: true}); err != nil {
+		t.Fatalf("Failed to run sync command: %v", err)
+	}
+	if !strings.Contains(out.String(), "Files synchronized: 1") {
+		t.Errorf("Expected report after a changing run, got:\n%s", out.String())
+	}
+
+	// The second run changes nothing and prints nothing
+	out.Reset()
+	if err := app.RunSync(SyncOptions{QuietSuccess: true}); err != nil {
+		t.Fatalf("Failed to run sync command: %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("Expected no output for an unchanged run, got:\n%s", out.String())
+	}
+}
+
+func TestRunSyncDryRunExitCode(t *testing.T) {
+	// Skip this test in short mode
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	// Create a project with a single target directory
+	projectDir := t.TempDir()
+	writeFiles(t, map[string]string{
+		filepath.Join(projectDir, ".clinerules"): "# Test clinerules file\n",
+		filepath.Join(projectDir, ".airulesync.yaml"): `
+source_dirs:
+  - path: "."
+    files:
+      - ".clinerules"
+target_dirs:
+  - path: "./sub-a"
+`,
+	})
+	chdir(t, projectDir)
+
+	var out bytes.Buffer
+	app := NewApp(".airulesync.yaml", false)
+	app.Out = &out
+	if err := app.RunSync(SyncOptions{}); err != nil {
+		t.Fatalf("Failed to run sync command: %v", err)
+	}
+
+	// A dry run of an already synchronized tree succeeds
+	out.Reset()
+	if err := app.RunSync(SyncOptions{DryRun: true}); err != nil {
+		t.Errorf("Expected no error for an in-sync dry run, got: %v", err)
+	}
+	if !strings.Contains(out.String(), "[DRY-RUN]") {
+		t.Errorf("Expected dry-run report, got:\n%s", out.String())
+	}
+
+	// A dry run after the source changed fails, still printing the report
+	writeFiles(t, map[string]string{
+		filepath.Join(projectDir, ".clinerules"): "# Changed clinerules file\n",
+	})
+	out.Reset()
+	err := app.RunSync(SyncOptions{DryRun: true})
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("Expected ExitError for an out-of-sync dry run, got %v", err)
+	}
+	if exitErr.Code != ExitPendingChanges {
+		t.Errorf("Expected exit code %d, got %d (%v)", ExitPendingChanges, exitErr.Code, exitErr.Categories())
+	}
+	if !strings.Contains(out.String(), "[DRY-RUN]") {
+		t.Errorf("Expected dry-run report, got:\n%s", out.String())
+	}
+
+	// The dry run didn't write the changed file
+	content, err := os.ReadFile(filepath.Join(projectDir, "sub-a", ".clinerules"))
+	if err != nil {
+		t.Fatalf("Failed to read target file: %v", err)
+	}
+	if string(content) != "# Test clinerules file\n" {
+		t.Errorf("Expected dry run to leave the target unchanged, got: %q", content)
+	}
+}
+
+func TestRunSyncWithReportFile(t *testing.T) {
+	// Skip this test in short mode
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	// Create a project with a single target directory
+	projectDir := t.TempDir()
+	writeFiles(t, map[string]string{
+		filepath.Join(projectDir, ".clinerules"): "# Test clinerules file\n",
+		filepath.Join(projectDir, ".airulesync.yaml"): `
+source_dirs:
+  - path: "."
+    files:
+      - ".clinerules"
+target_dirs:
+  - path: "./sub-a"
+`,
+	})
+	chdir(t, projectDir)
+
+	// Test cases for each report format
+	testCases := []struct {
+		name   string
+		format ReportFormat
+		check  func(t *testing.T, content []byte)
+	}{
+		{
+			name:   "text",
+			format: FormatText,
+			check: func(t *testing.T, content []byte) {
+				if !strings.Contains(string(content), "Files synchronized: 1") {
+					t.Errorf("Expected text report in report file, got:\n%s", content)
+				}
+			},
+		},
+		{
+			name:   "json",
+			format: FormatJSON,
+			check: func(t *testing.T, content []byte) {
+				var report struct {
+					Results []struct {
+						Target string `json:"target"`
+						Status string `json:"status"`
+					} `json:"results"`
+				}
+				if err := json.Unmarshal(content, &report); err != nil {
+					t.Fatalf("Failed to parse JSON report: %v\n%s", err, content)
+				}
+				if len(report.Results) != 1 || report.Results[0].Target != filepath.Join("sub-a", ".clinerules") {
+					t.Errorf("Expected a single result for 'sub-a/.clinerules', got: %+v", report.Results)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reportFile := filepath.Join(projectDir, "reports", tc.name, "report.out")
+
+			var out bytes.Buffer
+			app := NewApp(".airulesync.yaml", false)
+			app.Out = &out
+			if err := app.RunSync(SyncOptions{Format: tc.format, ReportFile: reportFile}); err != nil {
+				t.Fatalf("Failed to run sync command: %v", err)
+			}
+
+			// The report goes to the file, with its parent directories created
+			content, err := os.ReadFile(reportFile)
+			if err != nil {
+				t.Fatalf("Failed to read report file: %v", err)
+			}
+			tc.check(t, content)
+
+			// Only a short summary is printed
+			if !strings.Contains(out.String(), "Report written to "+reportFile) {
+				t.Errorf("Expected summary naming the report file, got:\n%s", out.String())
+			}
+			if strings.Contains(out.String(), "Files to synchronize") {
+				t.Errorf("Expected the full report not to be printed, got:\n%s", out.String())
+			}
+		})
+	}
+}
+
+func TestRunSyncExitCode(t *testing.T) {
+	// Skip this test in short mode
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	// Create a project where one target skips the file and another can't be written
+	projectDir := t.TempDir()
+	writeFiles(t, map[string]string{
+		filepath.Join(projectDir, ".clinerules"): "# Test clinerules file\n",
+		filepath.Join(projectDir, "sub-b"):       "not a directory",
+		filepath.Join(projectDir, ".airulesync.yaml"): `
+source_dirs:
+  - path: "."
+    files:
+      - ".clinerules"
+target_dirs:
+  - path: "./sub-a"
+    ignore_files:
+      - ".clinerules"
+  - path: "./sub-b"
+`,
+	})
+	chdir(t, projectDir)
+
+	// Test cases for the combined exit code
+	testCases := []struct {
+		name       string
+		configPath string
+		opts       SyncOptions
+		expected   int
+	}{
+		{
+			name:       "sync errors",
+			configPath: ".airulesync.yaml",
+			opts:       SyncOptions{},
+			expected:   ExitSyncErrors,
+		},
+		{
+			name:       "sync errors and skips",
+			configPath: ".airulesync.yaml",
+			opts:       SyncOptions{FailOnSkip: true},
+			expected:   ExitSyncErrors | ExitSkips,
+		},
+		{
+			name:       "config error",
+			configPath: "missing.yaml",
+			opts:       SyncOptions{},
+			expected:   ExitConfigError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			app := NewApp(tc.configPath, false)
+			app.Out = io.Discard
+
+			err := app.RunSync(tc.opts)
+			var exitErr *ExitError
+			if !errors.As(err, &exitErr) {
+				t.Fatalf("Expected ExitError, got %v", err)
+			}
+
+			if exitErr.Code != tc.expected {
+				t.Errorf("Expected exit code %d, got %d (%v)", tc.expected, exitErr.Code, exitErr.Categories())
+			}
+		})
+	}
+}
+
+func TestRunLint(t *testing.T) {
+	// Skip this test in short mode
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	// Create a project with a no-adjust file containing a relative import
+	projectDir := t.TempDir()
+	writeFiles(t, map[string]string{
+		filepath.Join(projectDir, ".clinerules"): "import \"./lib/helpers.js\"\n",
+		filepath.Join(projectDir, ".roomodes"):   "# No paths here\n",
+		filepath.Join(projectDir, ".airulesync.yaml"): `
+source_dirs:
+  - path: "."
+    files:
+      - pattern: ".clinerules"
+        adjust_paths: false
+      - pattern: ".roomodes"
+        adjust_paths: false
+target_dirs:
+  - path: "./sub-a"
+`,
+	})
+	chdir(t, projectDir)
+
+	// Run the lint command
+	var out bytes.Buffer
+	app := NewApp(".airulesync.yaml", false)
+	app.Out = &out
+	if err := app.RunLint(false); err == nil {
+		t.Errorf("Expected lint to fail, but it passed")
+	}
+
+	// Verify that only the file with an adjustable path is reported
+	expected := "Warning: '.clinerules' line 1: './lib/helpers.js' would become '../lib/helpers.js' in 'sub-a' but adjust_paths is false"
+	if !strings.Contains(out.String(), expected) {
+		t.Errorf("Expected lint output to contain:\n%s\n\nGot:\n%s", expected, out.String())
+	}
+	if strings.Contains(out.String(), ".roomodes") {
+		t.Errorf("Expected .roomodes not to be reported, got:\n%s", out.String())
+	}
+}
+
+func TestRunVerify(t *testing.T) {
+	// Skip this test in short mode
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	// Test cases for changes made to targets after the sync
+	testCases := []struct {
+		name     string
+		modify   func(t *testing.T, targetFile string)
+		expected string
+	}{
+		{
+			name:   "unmodified",
+			modify: func(t *testing.T, targetFile string) {},
+		},
+		{
+			name: "hand-edited",
+			modify: func(t *testing.T, targetFile string) {
+				if err := os.WriteFile(targetFile, []byte("# Edited in place\n"), 0644); err != nil {
+					t.Fatalf("Failed to edit target file: %v", err)
+				}
+			},
+			expected: "Modified: 'sub-a/.clinerules'",
+		},
+		{
+			name: "deleted",
+			modify: func(t *testing.T, targetFile string) {
+				if err := os.Remove(targetFile); err != nil {
+					t.Fatalf("Failed to delete target file: %v", err)
+				}
+			},
+			expected: "Deleted: 'sub-a/.clinerules'",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			projectDir := t.TempDir()
+			writeFiles(t, map[string]string{
+				filepath.Join(projectDir, ".clinerules"): "# Rules\n",
+				filepath.Join(projectDir, ".airulesync.yaml"): `
+source_dirs:
+  - path: "."
+    files:
+      - ".clinerules"
+target_dirs:
+  - path: "./sub-a"
+`,
+			})
+			chdir(t, projectDir)
+
+			// Sync and record the manifest
+			var out bytes.Buffer
+			app := NewApp(".airulesync.yaml", false)
+			app.Out = &out
+			if err := app.RunSync(SyncOptions{WriteManifest: true}); err != nil {
+				t.Fatalf("Failed to run sync command: %v", err)
+			}
+
+			tc.modify(t, filepath.Join(projectDir, "sub-a", ".clinerules"))
+
+			out.Reset()
+			err := app.RunVerify()
+			if tc.expected == "" {
+				if err != nil {
+					t.Errorf("Expected verify to pass, got %v:\n%s", err, out.String())
+				}
+				return
+			}
+
+			if err == nil {
+				t.Errorf("Expected verify to fail, but it passed")
+			}
+			if !strings.Contains(out.String(), tc.expected) {
+				t.Errorf("Expected verify output to contain '%s', got:\n%s", tc.expected, out.String())
+			}
+		})
+	}
+}
+
+func TestRunVerifyFromOtherDirectory(t *testing.T) {
+	projectDir := t.TempDir()
+	writeFiles(t, map[string]string{
+		filepath.Join(projectDir, ".clinerules"):        "# Rules\n",
+		filepath.Join(projectDir, "sub-a", "README.md"): "# Sub A\n",
+		filepath.Join(projectDir, ".airulesync.yaml"): `
+source_dirs:
+  - path: "."
+    files:
+      - ".clinerules"
+target_dirs:
+  - path: "./sub-a"
+`,
+	})
+	chdir(t, projectDir)
+
+	app := NewApp(".airulesync.yaml", false)
+	app.Out = io.Discard
+	if err := app.RunSync(SyncOptions{WriteManifest: true}); err != nil {
+		t.Fatalf("Failed to run sync command: %v", err)
+	}
+
+	// Verify from a subdirectory, where cwd-relative entries would not resolve
+	chdir(t, filepath.Join(projectDir, "sub-a"))
+
+	var out bytes.Buffer
+	app = NewApp("../.airulesync.yaml", false)
+	app.Out = &out
+	if err := app.RunVerify(); err != nil {
+		t.Errorf("Expected verify to pass, got %v:\n%s", err, out.String())
+	}
+}
+
+func TestRunSyncFailOnExternalFromSubdirectory(t *testing.T) {
+	// Targets inside the repository are not external, whichever directory
+	// the config is loaded from
+	testCases := []struct {
+		name       string
+		repository bool
+		configPath func(projectDir string) string
+	}{
+		{
+			name:       "relative config in a repository",
+			repository: true,
+			configPath: func(string) string { return "../.airulesync.yaml" },
+		},
+		{
+			name:       "absolute config in a repository",
+			repository: true,
+			configPath: func(projectDir string) string { return filepath.Join(projectDir, ".airulesync.yaml") },
+		},
+		{
+			name:       "relative config outside a repository",
+			configPath: func(string) string { return "../.airulesync.yaml" },
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			projectDir := t.TempDir()
+			writeFiles(t, map[string]string{
+				filepath.Join(projectDir, ".clinerules"):      "# Rules\n",
+				filepath.Join(projectDir, "sub", "README.md"): "# Sub\n",
+				filepath.Join(projectDir, ".airulesync.yaml"): `
+source_dirs:
+  - path: "."
+    files:
+      - ".clinerules"
+target_dirs:
+  - path: "./sub"
+`,
+			})
+			if tc.repository {
+				if err := os.MkdirAll(filepath.Join(projectDir, ".git"), 0755); err != nil {
+					t.Fatalf("Failed to create test directory: %v", err)
+				}
+			} else if config.FindRepoRoot(projectDir) != "" {
+				t.Skip("Temporary directory is inside a git repository")
+			}
+			chdir(t, filepath.Join(projectDir, "sub"))
+
+			app := NewApp(tc.configPath(projectDir), false)
+			app.Out = io.Discard
+			if err := app.RunSync(SyncOptions{FailOnExternal: true}); err != nil {
+				t.Fatalf("Expected sync to pass, got %v", err)
+			}
+			if _, err := os.Stat(".clinerules"); err != nil {
+				t.Errorf("Expected file to be synced: %v", err)
+			}
+		})
+	}
+}
+
+func TestRunLogsToInjectedLogger(t *testing.T) {
+	tempDir := t.TempDir()
+	writeFiles(t, map[string]string{
+		filepath.Join(tempDir, ".clinerules"):            "# Rules\n",
+		filepath.Join(tempDir, "sub-project", "main.go"): "package main\n",
+	})
+	chdir(t, tempDir)
+
+	handler := &recordHandler{}
+	app := NewApp(".airulesync.yaml", false)
+	app.Logger = slog.New(handler)
+
+	if err := app.RunInit(tempDir, InitOptions{}); err != nil {
+		t.Fatalf("Failed to run init command: %v", err)
+	}
+
+	// Progress messages are logged instead of printed
+	for _, expected := range []string{"Scanning directory for rule files...", "Found potential rule files", "Configuration written"} {
+		if !handler.has(slog.LevelInfo, expected) {
+			t.Errorf("Expected info record '%s', got %v", expected, handler.messages())
+		}
+	}
+
+	// Configure a target and synchronize to it
+	writeFiles(t, map[string]string{
+		filepath.Join(tempDir, ".airulesync.yaml"): "source_dirs:\n  - path: .\n    files:\n      - .clinerules\ntarget_dirs:\n  - path: sub-project\n",
+	})
+	app.Out = io.Discard
+
+	if err := app.RunSync(SyncOptions{}); err != nil {
+		t.Fatalf("Failed to run sync command: %v", err)
+	}
+
+	if !handler.has(slog.LevelDebug, "Synchronized file") {
+		t.Errorf("Expected debug record 'Synchronized file', got %v", handler.messages())
+	}
+}
+
+// recordHandler is a slog.Handler recording all records for assertions
+type recordHandler struct {
+	records []slog.Record
+}
+
+func (h *recordHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *recordHandler) Handle(_ context.Context, r slog.Record) error {
+	h.records = append(h.records, r)
+	return nil
+}
+
+func (h *recordHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+func (h *recordHandler) WithGroup(string) slog.Handler { return h }
+
+// has checks if a record with the given level and message was logged
+func (h *recordHandler) has(level slog.Level, message string) bool {
+	for _, r := range h.records {
+		if r.Level == level && r.Message == message {
+			return true
+		}
+	}
+	return false
+}
+
+// messages returns the messages of all records
+func (h *recordHandler) messages() []string {
+	var messages []string
+	for _, r := range h.records {
+		messages = append(messages, r.Message)
+	}
+	return messages
+}
+
+// Helper function to write a set of files, creating parent directories
+func writeFiles(t *testing.T, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+}
+
+// Helper function to change the working directory for the duration of a test
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(originalDir) })
+}
+
+// Helper function to copy a file
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(dst, data, 0644)
+}
+
+// Helper function to check if a string contains a substring
+func contains(s, substr string) bool {
+	return len(s) >= len(substr) && strings.Contains(s, substr)
+}
+
+func TestRunVersionJSON(t *testing.T) {
+	var out bytes.Buffer
+	app := NewApp(".airulesync.yaml", false)
+	app.Out = &out
+	if err := app.RunVersion(true); err != nil {
+		t.Fatalf("Failed to run version command: %v", err)
+	}
+
+	var info map[string]string
+	if err := json.Unmarshal(out.Bytes(), &info); err != nil {
+		t.Fatalf("Failed to parse version output as JSON: %v\n%s", err, out.String())
+	}
+
+	for _, key := range []string{"version", "commit", "buildTime", "goVersion", "platform"} {
+		if _, ok := info[key]; !ok {
+			t.Errorf("Expected JSON version output to contain key '%s', got:\n%s", key, out.String())
+		}
+	}
+
+	// The default output stays human-readable
+	out.Reset()
+	if err := app.RunVersion(false); err != nil {
+		t.Fatalf("Failed to run version command: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "airulesync version ") {
+		t.Errorf("Expected text version output, got:\n%s", out.String())
+	}
+}
+
+func TestRunExport(t *testing.T) {
+	// Skip this test in short mode
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	// Create a project with two target directories
+	projectDir := t.TempDir()
+	writeFiles(t, map[string]string{
+		filepath.Join(projectDir, ".clinerules"):                   "See [guide](./docs/guide.md)\n",
+		filepath.Join(projectDir, ".cursor", "rules", "go.mdc"):    "# Go\n",
+		filepath.Join(projectDir, ".cursor", "rules", "draft.mdc"): "# Draft\n",
+		filepath.Join(projectDir, ".airulesync.yaml"): `
+source_dirs:
+  - path: "."
+    files:
+      - ".clinerules"
+      - ".cursor/rules/*.mdc"
+target_dirs:
+  - path: "./sub-a"
+  - path: "./packages/sub-b"
+    ignore_files:
+      - ".cursor/rules/draft.mdc"
+`,
+	})
+	chdir(t, projectDir)
+
+	var out bytes.Buffer
+	app := NewApp(".airulesync.yaml", false)
+	app.Out = &out
+	if err := app.RunExport(ExportOptions{Archive: "rules.tar.gz"}); err != nil {
+		t.Fatalf("Failed to run export command: %v", err)
+	}
+
+	if !strings.Contains(out.String(), "Exported 5 files to rules.tar.gz") {
+		t.Errorf("Expected export summary, got:\n%s", out.String())
+	}
+
+	// Read the archive entries
+	f, err := os.Open("rules.tar.gz")
+	if err != nil {
+		t.Fatalf("Failed to open archive: %v", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("Failed to read gzip stream: %v", err)
+	}
+
+	entries := make(map[string]string)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read archive: %v", err)
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("Failed to read archive entry: %v", err)
+		}
+		entries[header.Name] = string(content)
+	}
+
+	// Entries hold the content adjusted for each target
+	expectedEntries := map[string]string{
+		"sub-a/.clinerules":                   "See [guide](../docs/guide.md)\n",
+		"sub-a/.cursor/rules/draft.mdc":       "# Draft\n",
+		"sub-a/.cursor/rules/go.mdc":          "# Go\n",
+		"packages/sub-b/.clinerules":          "See [guide](../../docs/guide.md)\n",
+		"packages/sub-b/.cursor/rules/go.mdc": "# Go\n",
+	}
+
+	if len(entries) != len(expectedEntries) {
+		t.Errorf("Expected %d archive entries, got %d: %v", len(expectedEntries), len(entries), entries)
+	}
+	for name, expected := range expectedEntries {
+		content, ok := entries[name]
+		if !ok {
+			t.Errorf("Expected archive entry '%s', but not found", name)
+			continue
+		}
+		if content != expected {
+			t.Errorf("Expected content of '%s' to be '%s', got '%s'", name, expected, content)
+		}
+	}
+
+	// Nothing is written to the targets
+	for _, targetDir := range []string{"sub-a", "packages"} {
+		if _, err := os.Stat(targetDir); !os.IsNotExist(err) {
+			t.Errorf("Expected target '%s' not to be written, got err=%v", targetDir, err)
+		}
+	}
+}
+
+func TestRunInitWithJSONConfigOut(t *testing.T) {
+	// Skip this test in short mode
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	// Create a project with a rule file
+	projectDir := t.TempDir()
+	writeFiles(t, map[string]string{
+		filepath.Join(projectDir, ".clinerules"): "# Test clinerules file\n",
+	})
+	chdir(t, projectDir)
+
+	app := NewApp(".airulesync.json", false)
+	if err := app.RunInit(projectDir, InitOptions{ConfigOut: ".airulesync.json"}); err != nil {
+		t.Fatalf("Failed to run init command: %v", err)
+	}
+
+	// The configuration is written as JSON referencing the schema
+	content, err := os.ReadFile(filepath.Join(projectDir, ".airulesync.json"))
+	if err != nil {
+		t.Fatalf("Failed to read configuration file: %v", err)
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(content, &raw); err != nil {
+		t.Fatalf("Expected a JSON configuration, got error: %v\n%s", err, content)
+	}
+	if raw["$schema"] != config.SchemaURL {
+		t.Errorf("Expected $schema to be %q, got: %v", config.SchemaURL, raw["$schema"])
+	}
+	if _, err := os.Stat(filepath.Join(projectDir, ".airulesync.yaml")); !os.IsNotExist(err) {
+		t.Errorf("Expected no YAML configuration file to be written, got: %v", err)
+	}
+
+	// The configuration parses back like a YAML configuration
+	var cfg config.Config
+	if err := yaml.Unmarshal(content, &cfg); err != nil {
+		t.Fatalf("Failed to parse JSON configuration: %v\n%s", err, content)
+	}
+	if len(cfg.SourceDirs) != 1 || len(cfg.SourceDirs[0].Files) != 1 || cfg.SourceDirs[0].Files[0].Pattern != ".clinerules" {
+		t.Errorf("Expected a source directory with '.clinerules', got: %+v", cfg.SourceDirs)
+	}
+}
+
+func TestRunConfigShow(t *testing.T) {
+	// Create a project whose configuration includes a shared file and has
+	// paths needing normalization and environment expansion
+	t.Setenv("AIRULESYNC_TEST_APP", "web")
+	projectDir := t.TempDir()
+	writeFiles(t, map[string]string{
+		filepath.Join(projectDir, ".airulesync.yaml"): `include:
+  - shared/targets.yaml
+source_dirs:
+  - path: ./rules/
+    files:
+      - ".clinerules"
+target_dirs:
+  - path: ./apps/${AIRULESYNC_TEST_APP}
+`,
+		filepath.Join(projectDir, "shared", "targets.yaml"): `source_dirs: []
+target_dirs:
+  - path: ../services//api/
+`,
+	})
+	chdir(t, projectDir)
+
+	var out bytes.Buffer
+	app := NewApp(".airulesync.yaml", false)
+	app.Out = &out
+	if err := app.RunConfigShow(); err != nil {
+		t.Fatalf("Failed to run config show command: %v", err)
+	}
+
+	var cfg config.Config
+	if err := yaml.Unmarshal(out.Bytes(), &cfg); err != nil {
+		t.Fatalf("Failed to parse printed configuration: %v\n%s", err, out.String())
+	}
+
+	// Paths are normalized and the included target is merged in
+	if len(cfg.SourceDirs) != 1 || cfg.SourceDirs[0].Path != "rules" {
+		t.Errorf("Expected normalized source directory 'rules', got: %+v", cfg.SourceDirs)
+	}
+	if len(cfg.TargetDirs) != 2 || cfg.TargetDirs[0].Path != filepath.Join("services", "api") {
+		t.Errorf("Expected included target directory 'services/api' first, got: %+v", cfg.TargetDirs)
+	}
+
+	// Environment variables are expanded
+	if len(cfg.TargetDirs) == 2 && cfg.TargetDirs[1].Path != filepath.Join("apps", "web") {
+		t.Errorf("Expected expanded target directory 'apps/web', got '%s'", cfg.TargetDirs[1].Path)
+	}
+	if len(cfg.Include) != 0 {
+		t.Errorf("Expected includes to be resolved, got: %v", cfg.Include)
+	}
+
+	// Defaults are spelled out
+	if cfg.CaseInsensitive == nil || cfg.MaxAdjustSize != config.DefaultMaxAdjustSize {
+		t.Errorf("Expected defaults to be filled in, got case_insensitive=%v max_adjust_size=%d", cfg.CaseInsensitive, cfg.MaxAdjustSize)
+	}
+}
+
+func TestRunSchema(t *testing.T) {
+	var out bytes.Buffer
+	app := NewApp(".airulesync.yaml", false)
+	app.Out = &out
+	if err := app.RunSchema(); err != nil {
+		t.Fatalf("Failed to run schema command: %v", err)
+	}
+
+	var schema map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &schema); err != nil {
+		t.Fatalf("Printed schema is not valid JSON: %v\n%s", err, out.String())
+	}
+	if schema["title"] != config.SchemaTitle {
+		t.Errorf("Expected schema title %q, got %v", config.SchemaTitle, schema["title"])
+	}
+}
+
+func TestRunSyncColor(t *testing.T) {
+	// Skip this test in short mode
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	// Create a project with a synchronized and an ignored file
+	projectDir := t.TempDir()
+	writeFiles(t, map[string]string{
+		filepath.Join(projectDir, ".clinerules"): "# Test clinerules file\n",
+		filepath.Join(projectDir, ".roomodes"):   "{}\n",
+		filepath.Join(projectDir, ".airulesync.yaml"): `
+source_dirs:
+  - path: "."
+    files:
+      - ".clinerules"
+      - ".roomodes"
+target_dirs:
+  - path: "./sub-a"
+    ignore_files:
+      - ".roomodes"
+`,
+	})
+	chdir(t, projectDir)
+
+	// Test cases for the color modes and report formats
+	testCases := []struct {
+		name     string
+		color    ColorMode
+		format   ReportFormat
+		expected bool
+	}{
+		{name: "never", color: ColorNever, format: FormatText},
+		{name: "auto without terminal", color: ColorAuto, format: FormatText},
+		{name: "always", color: ColorAlways, format: FormatText, expected: true},
+		{name: "always with JSON", color: ColorAlways, format: FormatJSON},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			app := NewApp(".airulesync.yaml", false)
+			app.Out = &out
+			if err := app.RunSync(SyncOptions{Color: tc.color, Format: tc.format}); err != nil {
+				t.Fatalf("Failed to run sync command: %v", err)
+			}
+
+			if colored := strings.Contains(out.String(), "\033["); colored != tc.expected {
+				t.Errorf("Expected ANSI codes in output to be %v, got:\n%q", tc.expected, out.String())
+			}
+		})
+	}
+
+	// Synchronized and skipped files are marked in different colors
+	var out bytes.Buffer
+	app := NewApp(".airulesync.yaml", false)
+	app.Out = &out
+	if err := app.RunSync(SyncOptions{Color: ColorAlways}); err != nil {
+		t.Fatalf("Failed to run sync command: %v", err)
+	}
+	if !strings.Contains(out.String(), "\033[32m-\033[0m '") || !strings.Contains(out.String(), "\033[33m-\033[0m '") {
+		t.Errorf("Expected green and yellow status markers, got:\n%q", out.String())
+	}
+}
+
+func TestRunConfigList(t *testing.T) {
+	// Create a project with paths needing normalization and a discovered source
+	projectDir := t.TempDir()
+	writeFiles(t, map[string]string{
+		filepath.Join(projectDir, "rules", ".clinerules"):           "# Rules\n",
+		filepath.Join(projectDir, "packages", "api", ".clinerules"): "# API rules\n",
+		filepath.Join(projectDir, ".airulesync.yaml"): `
+source_glob: "packages/*/.clinerules"
+source_dirs:
+  - path: ./rules/
+    files:
+      - ".clinerules"
+target_dirs:
+  - path: ./sub-a/
+  - path: sub-b//nested
+`,
+	})
+	chdir(t, projectDir)
+
+	// Test cases for both lists in both formats
+	testCases := []struct {
+		name     string
+		run      func(app *App) error
+		expected string
+	}{
+		{
+			name:     "sources as text",
+			run:      func(app *App) error { return app.RunConfigListSources(FormatText) },
+			expected: "rules\n" + filepath.Join("packages", "api") + "\n",
+		},
+		{
+			name:     "targets as text",
+			run:      func(app *App) error { return app.RunConfigListTargets(FormatText) },
+			expected: "sub-a\n" + filepath.Join("sub-b", "nested") + "\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			app := NewApp(".airulesync.yaml", false)
+			app.Out = &out
+			if err := tc.run(app); err != nil {
+				t.Fatalf("Failed to list directories: %v", err)
+			}
+			if out.String() != tc.expected {
+				t.Errorf("Expected output:\n%s\nGot:\n%s", tc.expected, out.String())
+			}
+		})
+	}
+
+	// The JSON list parses back to the normalized paths
+	var out bytes.Buffer
+	app := NewApp(".airulesync.yaml", false)
+	app.Out = &out
+	if err := app.RunConfigListTargets(FormatJSON); err != nil {
+		t.Fatalf("Failed to list target directories: %v", err)
+	}
+	var targets []string
+	if err := json.Unmarshal(out.Bytes(), &targets); err != nil {
+		t.Fatalf("Failed to parse JSON list: %v\n%s", err, out.String())
+	}
+	if strings.Join(targets, ",") != "sub-a,"+filepath.Join("sub-b", "nested") {
+		t.Errorf("Expected normalized target directories, got %v", targets)
+	}
+}
+
+func TestRunSyncStrictWithExternalTarget(t *testing.T) {
+	// Skip this test in short mode
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	// Create a project synchronizing to a target marked external
+	projectDir := t.TempDir()
+	writeFiles(t, map[string]string{
+		filepath.Join(projectDir, ".clinerules"): "# Test clinerules file\n",
+		filepath.Join(projectDir, ".airulesync.yaml"): `
+source_dirs:
+  - path: "."
+    files:
+      - ".clinerules"
+target_dirs:
+  - path: "./vendored"
+    external: true
+`,
+	})
+	chdir(t, projectDir)
+
+	// The cross-repository warning doesn't fail a normal run
+	var out bytes.Buffer
+	app := NewApp(".airulesync.yaml", false)
+	app.Out = &out
+	if err := app.RunSync(SyncOptions{}); err != nil {
+		t.Fatalf("Expected sync to pass without strict mode, got %v", err)
+	}
+	if !strings.Contains(out.String(), "Warning: Cross-repository paths may require manual verification") {
+		t.Errorf("Expected cross-repository warning in report, got:\n%s", out.String())
+	}
+
+	// Strict mode promotes the warning to an error
+	out.Reset()
+	err := app.RunSync(SyncOptions{Strict: true})
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("Expected ExitError in strict mode, got %v", err)
+	}
+	if exitErr.Code != ExitWarnings {
+		t.Errorf("Expected exit code %d, got %d (%v)", ExitWarnings, exitErr.Code, exitErr.Categories())
+	}
+
+	// The warning is part of the JSON report
+	out.Reset()
+	if err := app.RunSync(SyncOptions{Format: FormatJSON}); err != nil {
+		t.Fatalf("Expected sync to pass without strict mode, got %v", err)
+	}
+	var report struct {
+		Warnings []string `json:"warnings"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to parse JSON report: %v\n%s", err, out.String())
+	}
+	if len(report.Warnings) != 1 || !strings.Contains(report.Warnings[0], "vendored is external to the repository") {
+		t.Errorf("Expected a warning about the external target, got %v", report.Warnings)
+	}
+}
+
+func TestRunSyncWarnsAboutExternalTargetOutsideRepository(t *testing.T) {
+	// Skip this test in short mode
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	// Create a project outside of any repository with a sibling target
+	tempDir := t.TempDir()
+	if config.FindRepoRoot(tempDir) != "" {
+		t.Skip("Temporary directory is inside a git repository")
+	}
+	projectDir := filepath.Join(tempDir, "project")
+	writeFiles(t, map[string]string{
+		filepath.Join(projectDir, ".clinerules"): "# Test clinerules file\n",
+		filepath.Join(projectDir, ".airulesync.yaml"): `
+source_dirs:
+  - path: "."
+    files:
+      - ".clinerules"
+target_dirs:
+  - path: "../other"
+  - path: "./sub"
+`,
+	})
+	chdir(t, projectDir)
+
+	var out bytes.Buffer
+	app := NewApp(".airulesync.yaml", false)
+	app.Out = &out
+	if err := app.RunSync(SyncOptions{}); err != nil {
+		t.Fatalf("Failed to run sync command: %v", err)
+	}
+
+	// Only the target outside the project gets the warning
+	if count := strings.Count(out.String(), "Warning: Cross-repository paths may require manual verification"); count != 1 {
+		t.Errorf("Expected one cross-repository warning in report, got %d:\n%s", count, out.String())
+	}
+}
+
+func TestRunSyncKeepGoing(t *testing.T) {
+	// Skip this test in short mode
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	// Create a project with two targets that can't be written, as a
+	// directory is in place of each target file, and one that can
+	projectDir := t.TempDir()
+	writeFiles(t, map[string]string{
+		filepath.Join(projectDir, ".clinerules"):                  "# Test clinerules file\n",
+		filepath.Join(projectDir, "sub-a", ".clinerules", "keep"): "a directory in place of the target file",
+		filepath.Join(projectDir, "sub-b", ".clinerules", "keep"): "a directory in place of the target file",
+		filepath.Join(projectDir, ".airulesync.yaml"): `
+source_dirs:
+  - path: "."
+    files:
+      - ".clinerules"
+target_dirs:
+  - path: "./sub-a"
+  - path: "./sub-b"
+  - path: "./sub-c"
+`,
+	})
+	chdir(t, projectDir)
+
+	// Test cases for stopping at the first failure and attempting every file
+	testCases := []struct {
+		name     string
+		opts     SyncOptions
+		statuses []string
+		synced   bool
+	}{
+		{
+			name:     "stop at first error",
+			opts:     SyncOptions{Format: FormatJSON},
+			statuses: []string{"error"},
+		},
+		{
+			name:     "keep going",
+			opts:     SyncOptions{Format: FormatJSON, KeepGoing: true},
+			statuses: []string{"error", "error", "changed"},
+			synced:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.RemoveAll(filepath.Join(projectDir, "sub-c"))
+
+			var out bytes.Buffer
+			app := NewApp(".airulesync.yaml", false)
+			app.Out = &out
+
+			// Both fail with the sync errors exit code at the end
+			err := app.RunSync(tc.opts)
+			var exitErr *ExitError
+			if !errors.As(err, &exitErr) || exitErr.Code != ExitSyncErrors {
+				t.Fatalf("Expected ExitError with code %d, got %v", ExitSyncErrors, err)
+			}
+
+			var report struct {
+				Results []struct {
+					Status string `json:"status"`
+				} `json:"results"`
+				Warnings []string `json:"warnings"`
+			}
+			if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+				t.Fatalf("Failed to parse JSON report: %v\n%s", err, out.String())
+			}
+
+			var statuses []string
+			for _, result := range report.Results {
+				statuses = append(statuses, result.Status)
+			}
+			if strings.Join(statuses, ",") != strings.Join(tc.statuses, ",") {
+				t.Errorf("Expected result statuses %v, got %v", tc.statuses, statuses)
+			}
+
+			if _, err := os.Stat(filepath.Join(projectDir, "sub-c", ".clinerules")); (err == nil) != tc.synced {
+				t.Errorf("Expected sub-c/.clinerules to exist: %v, got err=%v", tc.synced, err)
+			}
+
+			// Stopping early is reported
+			stoppedWarning := "stopped after the first error, 2 files were not attempted"
+			if hasWarning := len(report.Warnings) == 1 && report.Warnings[0] == stoppedWarning; hasWarning == tc.synced {
+				t.Errorf("Expected the stop warning only without keep going, got %v", report.Warnings)
+			}
+		})
+	}
+}
+
+func TestRunSyncFromSubdirectory(t *testing.T) {
+	// Skip this test in short mode
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	// Create a project and run from a nested directory of it
+	projectDir := t.TempDir()
+	writeFiles(t, map[string]string{
+		filepath.Join(projectDir, ".clinerules"):         "# Test clinerules file\n",
+		filepath.Join(projectDir, "a", "b", "notes.txt"): "not a rule file\n",
+		filepath.Join(projectDir, ".airulesync.yaml"): `
+source_dirs:
+  - path: "."
+    files:
+      - ".clinerules"
+target_dirs:
+  - path: "./sub"
+`,
+	})
+	chdir(t, filepath.Join(projectDir, "a", "b"))
+
+	app := NewApp("", false)
+	app.Out = io.Discard
+	if err := app.FindConfig(); err != nil {
+		t.Fatalf("FindConfig failed: %v", err)
+	}
+
+	// The configuration of the project is found, with paths relative to it
+	expectedPath := filepath.Join("..", "..", ".airulesync.yaml")
+	if app.ConfigPath != expectedPath {
+		t.Errorf("Expected config path %s, got %s", expectedPath, app.ConfigPath)
+	}
+	if err := app.RunSync(SyncOptions{}); err != nil {
+		t.Fatalf("RunSync failed: %v", err)
+	}
+
+	// The target is synced in the project instead of the working directory
+	content, err := os.ReadFile(filepath.Join(projectDir, "sub", ".clinerules"))
+	if err != nil {
+		t.Fatalf("Failed to read synced file: %v", err)
+	}
+	if string(content) != "# Test clinerules file\n" {
+		t.Errorf("Unexpected synced content: %q", content)
+	}
+	if _, err := os.Stat("sub"); !os.IsNotExist(err) {
+		t.Errorf("Expected no target in the working directory, got %v", err)
+	}
+}
+
+func TestRunSyncSelectsDirsRelativeToConfig(t *testing.T) {
+	// Skip this test in short mode
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	// Create a project whose configuration lives in a subdirectory
+	projectDir := t.TempDir()
+	writeFiles(t, map[string]string{
+		filepath.Join(projectDir, "exp1", ".clinerules"): "# Test clinerules file\n",
+		filepath.Join(projectDir, "exp1", ".airulesync.yaml"): `
+source_dirs:
+  - path: "."
+    files:
+      - ".clinerules"
+target_dirs:
+  - path: "./tgt"
+  - path: "./other"
+`,
+	})
+	chdir(t, projectDir)
+
+	// Targets are selected relative to the configuration file or the
+	// working directory
+	testCases := []struct {
+		name string
+		opts SyncOptions
+	}{
+		{name: "config-relative", opts: SyncOptions{Targets: []string{"./tgt"}}},
+		{name: "cwd-relative", opts: SyncOptions{Targets: []string{"exp1/tgt"}}},
+		{name: "excluded config-relative", opts: SyncOptions{ExcludeTargets: []string{"other"}}},
+		{name: "excluded cwd-relative", opts: SyncOptions{ExcludeTargets: []string{"./exp1/other"}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, dir := range []string{"tgt", "other"} {
+				if err := os.RemoveAll(filepath.Join(projectDir, "exp1", dir)); err != nil {
+					t.Fatalf("Failed to remove target: %v", err)
+				}
+			}
+
+			var out bytes.Buffer
+			app := NewApp(filepath.Join("exp1", ".airulesync.yaml"), false)
+			app.Out = &out
+			if err := app.RunSync(tc.opts); err != nil {
+				t.Fatalf("RunSync failed: %v\n%s", err, out.String())
+			}
+
+			if _, err := os.Stat(filepath.Join(projectDir, "exp1", "tgt", ".clinerules")); err != nil {
+				t.Errorf("Expected the selected target to be synced: %v", err)
+			}
+			if _, err := os.Stat(filepath.Join(projectDir, "exp1", "other")); !os.IsNotExist(err) {
+				t.Errorf("Expected the other target to be left alone, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRunSyncListAdjustments(t *testing.T) {
+	// Skip this test in short mode
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	// Create a project with one file with two adjusted paths and one without
+	projectDir := t.TempDir()
+	writeFiles(t, map[string]string{
+		filepath.Join(projectDir, ".clinerules"): "See [Guide](./guide.md)\nNo paths here\nSee [Setup](./docs/setup.md)\n",
+		filepath.Join(projectDir, ".roomodes"):   "# No paths here\n",
+		filepath.Join(projectDir, ".airulesync.yaml"): `
+source_dirs:
+  - path: "."
+    files:
+      - ".clinerules"
+      - ".roomodes"
+target_dirs:
+  - path: "./sub"
+`,
+	})
+	chdir(t, projectDir)
+
+	var out bytes.Buffer
+	app := NewApp(".airulesync.yaml", false)
+	app.Out = &out
+
+	// The dry run still signals pending changes
+	err := app.RunSync(SyncOptions{DryRun: true, ListAdjustments: true})
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != ExitPendingChanges {
+		t.Fatalf("Expected ExitError with code %d, got %v", ExitPendingChanges, err)
+	}
+
+	// Only the adjustments are printed
+	target := filepath.Join("sub", ".clinerules")
+	expected := target + ":1: './guide.md' -> '../guide.md'\n" +
+		target + ":3: './docs/setup.md' -> '../docs/setup.md'\n"
+	if out.String() != expected {
+		t.Errorf("Expected output:\n%s\nGot:\n%s", expected, out.String())
+	}
 }
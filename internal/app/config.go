@@ -0,0 +1,96 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/upamune/airulesync/internal/config"
+	"github.com/upamune/airulesync/internal/scanner"
+)
+
+// RunConfigShow runs the config show command, which prints the configuration
+// as it is used after merging includes, normalizing paths and applying defaults
+func (a *App) RunConfigShow() error {
+	cfg, err := config.LoadConfig(a.ConfigPath)
+	if err != nil {
+		return &ExitError{Code: ExitConfigError, Err: fmt.Errorf("failed to load configuration: %w", err)}
+	}
+
+	data, err := config.MarshalConfig(cfg.Effective(), config.YAMLStyleBlock)
+	if err != nil {
+		return err
+	}
+	if _, err := a.Out.Write(data); err != nil {
+		return fmt.Errorf("failed to write configuration: %w", err)
+	}
+	return nil
+}
+
+// RunConfigListSources runs the config list-sources command, which prints the
+// normalized paths of the source directories, including those discovered with
+// the source glob
+func (a *App) RunConfigListSources(format ReportFormat) error {
+	cfg, err := config.LoadConfig(a.ConfigPath)
+	if err != nil {
+		return &ExitError{Code: ExitConfigError, Err: fmt.Errorf("failed to load configuration: %w", err)}
+	}
+
+	s := scanner.NewScanner(cfg)
+	s.Logger = a.Logger
+	sourceDirs, err := s.SourceDirs()
+	if err != nil {
+		return err
+	}
+
+	var paths []string
+	for _, sourceDir := range sourceDirs {
+		paths = append(paths, sourceDir.Path)
+	}
+	return a.printPaths(paths, format)
+}
+
+// RunConfigListTargets runs the config list-targets command, which prints the
+// normalized paths of the target directories
+func (a *App) RunConfigListTargets(format ReportFormat) error {
+	cfg, err := config.LoadConfig(a.ConfigPath)
+	if err != nil {
+		return &ExitError{Code: ExitConfigError, Err: fmt.Errorf("failed to load configuration: %w", err)}
+	}
+
+	var paths []string
+	for _, targetDir := range cfg.TargetDirs {
+		paths = append(paths, targetDir.Path)
+	}
+	return a.printPaths(paths, format)
+}
+
+// printPaths prints paths one per line, or as a JSON array
+func (a *App) printPaths(paths []string, format ReportFormat) error {
+	if format != FormatJSON {
+		for _, path := range paths {
+			fmt.Fprintln(a.Out, path)
+		}
+		return nil
+	}
+
+	if paths == nil {
+		paths = []string{}
+	}
+	data, err := json.MarshalIndent(paths, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal paths: %w", err)
+	}
+	fmt.Fprintln(a.Out, string(data))
+	return nil
+}
+
+// RunSchema runs the schema command, which prints the JSON schema of the
+// configuration file for editor validation
+func (a *App) RunSchema() error {
+	data, err := config.JSONSchema()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(a.Out, string(data))
+	return nil
+}
@@ -0,0 +1,88 @@
+package app
+
+import (
+	"strings"
+
+	"github.com/upamune/airulesync/internal/sync"
+)
+
+// Exit code bits combined into the exit code of a failed run
+const (
+	// ExitSyncErrors is set when one or more files failed to synchronize
+	ExitSyncErrors = 1 << iota
+	// ExitConfigError is set when the configuration could not be loaded or applied
+	ExitConfigError
+	// ExitSkips is set when files were skipped and skips are treated as failures
+	ExitSkips
+	// ExitConflicts is set when target collisions were promoted to errors
+	ExitConflicts
+	// ExitPendingChanges is set when a dry run found files a sync would change
+	ExitPendingChanges
+	// ExitWarnings is set when warnings were found and are treated as errors
+	ExitWarnings
+)
+
+// exitCategories describes each exit code bit
+var exitCategories = []struct {
+	code        int
+	description string
+}{
+	{ExitSyncErrors, "sync errors"},
+	{ExitConfigError, "configuration error"},
+	{ExitSkips, "skipped files"},
+	{ExitConflicts, "target conflicts"},
+	{ExitPendingChanges, "pending changes"},
+	{ExitWarnings, "warnings"},
+}
+
+// ExitError is an error carrying the exit code the process should terminate with
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+// Error returns the message of the underlying error
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// Categories returns the descriptions of the failure categories set in the exit code
+func (e *ExitError) Categories() []string {
+	var categories []string
+	for _, category := range exitCategories {
+		if e.Code&category.code != 0 {
+			categories = append(categories, category.description)
+		}
+	}
+	return categories
+}
+
+// reportExitCode computes the exit code bits for the results of a sync run,
+// treating its warnings as errors in strict mode
+func reportExitCode(report *sync.SyncReport, failOnSkip, strict bool) int {
+	code := 0
+	if strict && len(report.Warnings) > 0 {
+		code |= ExitWarnings
+	}
+	for _, result := range report.Results {
+		switch {
+		case result.Error != nil && result.ConflictsWith != "":
+			code |= ExitConflicts
+		case result.Error != nil:
+			code |= ExitSyncErrors
+		case result.Skipped && result.SkipKind != sync.SkipOverridden && failOnSkip:
+			code |= ExitSkips
+		}
+	}
+	return code
+}
+
+// describeExitCode returns a human readable summary of the exit code bits
+func describeExitCode(code int) string {
+	return strings.Join((&ExitError{Code: code}).Categories(), ", ")
+}
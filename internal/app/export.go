@@ -0,0 +1,83 @@
+package app
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/upamune/airulesync/internal/config"
+	"github.com/upamune/airulesync/internal/sync"
+)
+
+// ExportOptions represents the options of the export command
+type ExportOptions struct {
+	Archive string
+	Sources []string
+	Targets []string
+}
+
+// RunExport runs the export command, which writes the adjusted files of every
+// target directory to a tar archive, gzip-compressed for .tar.gz and .tgz files
+func (a *App) RunExport(opts ExportOptions) error {
+	// Load configuration
+	cfg, err := config.LoadConfig(a.ConfigPath)
+	if err != nil {
+		return &ExitError{Code: ExitConfigError, Err: fmt.Errorf("failed to load configuration: %w", err)}
+	}
+
+	// Restrict the export to the selected source and target directories
+	if err := cfg.SelectSourceDirs(opts.Sources); err != nil {
+		return &ExitError{Code: ExitConfigError, Err: fmt.Errorf("failed to select source directories: %w", err)}
+	}
+
+	if err := cfg.SelectTargetDirs(opts.Targets); err != nil {
+		return &ExitError{Code: ExitConfigError, Err: fmt.Errorf("failed to select target directories: %w", err)}
+	}
+
+	syncer := sync.NewSyncer(cfg, false, a.Verbose)
+	syncer.Out = a.Out
+	syncer.SetLogger(a.Logger)
+
+	f, err := os.Create(opts.Archive)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer f.Close()
+
+	var w io.Writer = f
+	var gz *gzip.Writer
+	if strings.HasSuffix(opts.Archive, ".gz") || strings.HasSuffix(opts.Archive, ".tgz") {
+		gz = gzip.NewWriter(f)
+		w = gz
+	}
+
+	report, err := syncer.Export(w)
+	if err != nil {
+		return fmt.Errorf("export failed: %w", err)
+	}
+
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return fmt.Errorf("failed to compress archive: %w", err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	exported := 0
+	for _, result := range report.Results {
+		if result.Success {
+			exported++
+		}
+	}
+	fmt.Fprintf(a.Out, "Exported %d files to %s\n", exported, opts.Archive)
+
+	if code := reportExitCode(report, false, false); code != 0 {
+		return &ExitError{Code: code, Err: fmt.Errorf("export finished with %s", describeExitCode(code))}
+	}
+
+	return nil
+}
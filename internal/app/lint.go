@@ -0,0 +1,84 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/upamune/airulesync/internal/config"
+	"github.com/upamune/airulesync/internal/pathadjust"
+	"github.com/upamune/airulesync/internal/scanner"
+	"github.com/upamune/airulesync/internal/sync"
+)
+
+// RunLint runs the lint command, which checks the configuration for likely
+// mistakes. Paths are adjusted as sync adjusts them, resolving symbolic links
+// when resolveSymlinks is set.
+func (a *App) RunLint(resolveSymlinks bool) error {
+	// Load configuration
+	cfg, err := config.LoadConfig(a.ConfigPath)
+	if err != nil {
+		return &ExitError{Code: ExitConfigError, Err: fmt.Errorf("failed to load configuration: %w", err)}
+	}
+
+	// Build the scanner and path adjuster the way sync does
+	syncer := sync.NewSyncer(cfg, true, a.Verbose)
+	syncer.Scanner.Logger = a.Logger
+	syncer.PathAdjuster.Logger = a.Logger
+	syncer.PathAdjuster.ResolveSymlinks = resolveSymlinks
+
+	// Scan source directories for files to synchronize
+	files, err := syncer.Scanner.ScanSourceDirs()
+	if err != nil {
+		return fmt.Errorf("failed to scan source directories: %w", err)
+	}
+
+	warnings, err := lintUnadjustedFiles(cfg, files, syncer.Scanner, syncer.PathAdjuster)
+	if err != nil {
+		return err
+	}
+
+	for _, warning := range warnings {
+		fmt.Fprintf(a.Out, "Warning: %s\n", warning)
+	}
+
+	if len(warnings) > 0 {
+		return fmt.Errorf("lint found %d warnings", len(warnings))
+	}
+
+	fmt.Fprintln(a.Out, "No problems found")
+	return nil
+}
+
+// lintUnadjustedFiles warns about files with adjust_paths disabled that contain
+// relative paths which would be changed by path adjustment
+func lintUnadjustedFiles(cfg *config.Config, files []scanner.FileInfo, s *scanner.Scanner, adjuster *pathadjust.PathAdjuster) ([]string, error) {
+	var warnings []string
+
+	for _, file := range files {
+		if file.AdjustPaths {
+			continue
+		}
+
+		content, err := s.ReadFile(file.SourcePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read source file %s: %w", file.SourcePath, err)
+		}
+
+		// Only detect adjustments, the adjusted content is discarded
+		for _, targetDir := range cfg.TargetDirs {
+			adjustments, _, err := adjuster.AdjustContent(content, file.AdjustBase(), filepath.Join(targetDir.Path, file.SourceDirConfig.GetTargetPrefix()))
+			if err != nil {
+				return nil, fmt.Errorf("failed to check %s: %w", file.SourcePath, err)
+			}
+
+			for _, adj := range adjustments {
+				warnings = append(warnings, fmt.Sprintf(
+					"'%s' line %d: '%s' would become '%s' in '%s' but adjust_paths is false",
+					file.SourcePath, adj.LineNumber, adj.OriginalPath, adj.AdjustedPath, targetDir.Path,
+				))
+			}
+		}
+	}
+
+	return warnings, nil
+}
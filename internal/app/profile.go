@@ -0,0 +1,57 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/upamune/airulesync/internal/config"
+)
+
+// Profile is a toolset whose default rule files init seeds a configuration with
+type Profile string
+
+// Supported init profiles
+const (
+	ProfileCursor Profile = "cursor"
+	ProfileCline  Profile = "cline"
+	ProfileRoo    Profile = "roo"
+	ProfileAll    Profile = "all"
+)
+
+// profileFileSpecs are the default file specs of each toolset
+var profileFileSpecs = map[Profile][]config.FileSpec{
+	ProfileCursor: {
+		{Pattern: ".cursor/rules/*.mdc", AdjustPaths: boolPtr(true)},
+		{Pattern: ".cursorignore", AdjustPaths: boolPtr(false)},
+	},
+	ProfileCline: {
+		{Pattern: ".clinerules", AdjustPaths: boolPtr(true)},
+		{Pattern: ".clineignore", AdjustPaths: boolPtr(false)},
+	},
+	ProfileRoo: {
+		{Pattern: ".roomodes", AdjustPaths: boolPtr(true)},
+		{Pattern: ".rooignore", AdjustPaths: boolPtr(false)},
+	},
+}
+
+// ProfileFileSpecs returns the file specs a profile seeds the generated
+// configuration with. The all profile combines every toolset.
+func ProfileFileSpecs(profile Profile) ([]config.FileSpec, error) {
+	if profile == ProfileAll {
+		var specs []config.FileSpec
+		for _, p := range []Profile{ProfileCursor, ProfileCline, ProfileRoo} {
+			specs = append(specs, profileFileSpecs[p]...)
+		}
+		return specs, nil
+	}
+
+	specs, ok := profileFileSpecs[profile]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q (expected cursor, cline, roo or all)", profile)
+	}
+	return specs, nil
+}
+
+// boolPtr returns a pointer to a bool value
+func boolPtr(b bool) *bool {
+	return &b
+}
@@ -0,0 +1,38 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/upamune/airulesync/internal/sync"
+)
+
+// RunVerify runs the verify command, which checks that the target files listed
+// in the manifest were not modified or deleted since they were synchronized
+func (a *App) RunVerify() error {
+	manifestPath := filepath.Join(filepath.Dir(a.ConfigPath), sync.ManifestFile)
+	manifest, err := sync.LoadManifest(manifestPath)
+	if err != nil {
+		return &ExitError{Code: ExitConfigError, Err: fmt.Errorf("failed to load manifest: %w", err)}
+	}
+
+	mismatches, err := manifest.Verify()
+	if err != nil {
+		return fmt.Errorf("failed to verify manifest: %w", err)
+	}
+
+	for _, mismatch := range mismatches {
+		if mismatch.Deleted {
+			fmt.Fprintf(a.Out, "Deleted: '%s'\n", mismatch.Path)
+		} else {
+			fmt.Fprintf(a.Out, "Modified: '%s'\n", mismatch.Path)
+		}
+	}
+
+	if len(mismatches) > 0 {
+		return fmt.Errorf("verify found %d of %d target files modified or deleted", len(mismatches), len(manifest.Files))
+	}
+
+	fmt.Fprintf(a.Out, "All %d target files match %s\n", len(manifest.Files), manifestPath)
+	return nil
+}
@@ -4,36 +4,96 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
 
+	"github.com/upamune/airulesync/internal/ignore"
 	"gopkg.in/yaml.v3"
 )
 
 // Config represents the main configuration structure
 type Config struct {
+	Schema      string `yaml:"$schema,omitempty" jsonschema:"description=URL of the JSON schema referenced by JSON configuration files"`
+	Name        string `yaml:"name,omitempty" jsonschema:"description=Name identifying this configuration in reports"`
+	Description string `yaml:"description,omitempty" jsonschema:"description=Description of what this configuration synchronizes"`
+
+	Include    []string    `yaml:"include,omitempty" jsonschema:"description=Config files whose source and target directories are merged in before the local ones (relative to this file)"`
 	SourceDirs []SourceDir `yaml:"source_dirs" jsonschema:"description=List of source directories containing rule files to be synchronized"`
+	SourceGlob string      `yaml:"source_glob,omitempty" jsonschema:"description=Glob of rule files such as **/.clinerules whose directories are added as source directories synchronizing the files matching its last element"`
 	TargetDirs []TargetDir `yaml:"target_dirs" jsonschema:"description=List of target directories where rule files will be synchronized to"`
+	Rewrites   []Rewrite   `yaml:"rewrites,omitempty" jsonschema:"description=Regular expression rewrites applied to each line of synchronized files after path adjustment"`
+
+	CaseInsensitive *bool `yaml:"case_insensitive,omitempty" jsonschema:"description=Whether file and ignore patterns match regardless of case (default: true on macOS and Windows)"`
+
+	PreserveSeparators bool `yaml:"preserve_separators,omitempty" jsonschema:"description=Whether adjusted Windows-style relative paths keep their backslashes instead of being normalized to forward slashes"`
+
+	AdjustFrontmatterGlobs bool `yaml:"adjust_frontmatter_globs,omitempty" jsonschema:"description=Whether relative front matter globs such as ./src/**/*.ts are adjusted like paths instead of being left unchanged as match patterns"`
+
+	AdjustExtensions []string `yaml:"adjust_extensions,omitempty" jsonschema:"description=File extensions of quoted paths adjusted as general file paths such as rs for ./src/main.rs (default: common source and documentation extensions)"`
+
+	MaxAdjustSize int64 `yaml:"max_adjust_size,omitempty" jsonschema:"description=Size in bytes above which files are copied verbatim instead of having their paths adjusted (default: 1048576; negative for no limit)"`
+
+	Header        string `yaml:"header,omitempty" jsonschema:"description=Comment prepended to synchronized files where {source} and {target} are replaced by the source and target file paths"`
+	HeaderComment string `yaml:"header_comment,omitempty" jsonschema:"description=Comment prefix of the header such as # or <!-- (default: inferred from the file extension)"`
+
+	// Ignore holds the rules of the .airulesyncignore file next to the
+	// configuration file, applied to all source and target directories
+	Ignore *ignore.Matcher `yaml:"-" json:"-"`
+
+	// Dir is the directory of the configuration file, which relative paths
+	// are resolved against
+	Dir string `yaml:"-" json:"-"`
 }
 
 // SourceDir represents a source directory configuration
 type SourceDir struct {
-	Path        string     `yaml:"path" jsonschema:"description=Path to the source directory"`
-	Overwrite   *bool      `yaml:"overwrite,omitempty" jsonschema:"description=Whether to overwrite existing files in target directories (default: true)"`
-	Files       []FileSpec `yaml:"files" jsonschema:"description=List of files to synchronize from this source directory"`
-	IgnoreFiles []string   `yaml:"ignore_files,omitempty" jsonschema:"description=List of file patterns to ignore when synchronizing"`
+	Path         string        `yaml:"path" jsonschema:"description=Path to the source directory (relative to this file)"`
+	Overwrite    OverwriteMode `yaml:"overwrite,omitempty" jsonschema:"description=Whether to overwrite existing files in target directories: a boolean or always/never/prompt/if-newer (default: true)"`
+	Files        []FileSpec    `yaml:"files" jsonschema:"description=List of files to synchronize from this source directory"`
+	IgnoreFiles  []string      `yaml:"ignore_files,omitempty" jsonschema:"description=List of file patterns to ignore when synchronizing"`
+	GlobBase     string        `yaml:"glob_base,omitempty" jsonschema:"description=Directory file patterns are matched against (default: path). Paths in files are still adjusted relative to path"`
+	TargetPrefix string        `yaml:"target_prefix,omitempty" jsonschema:"description=Subdirectory of every target directory the files of this source directory are synchronized into such as rules"`
+	Priority     int           `yaml:"priority,omitempty" jsonschema:"description=Order in which source directories are synchronized: higher priorities go first and win when several write the same target file (default: 0; equal priorities keep their configured order)"`
 }
 
 // TargetDir represents a target directory configuration
 type TargetDir struct {
-	Path        string   `yaml:"path" jsonschema:"description=Path to the target directory"`
-	External    bool     `yaml:"external,omitempty" jsonschema:"description=Whether this directory is external to the project (default: false)"`
-	IgnoreFiles []string `yaml:"ignore_files,omitempty" jsonschema:"description=List of file patterns to ignore when synchronizing to this target directory"`
+	Path                 string                 `yaml:"path" jsonschema:"description=Path to the target directory (relative to this file)"`
+	External             bool                   `yaml:"external,omitempty" jsonschema:"description=Whether this directory is external to the project (default: detected from the enclosing git repository)"`
+	IgnoreFiles          []string               `yaml:"ignore_files,omitempty" jsonschema:"description=List of file patterns to ignore when synchronizing to this target directory"`
+	FrontmatterOverrides map[string]interface{} `yaml:"frontmatter_overrides,omitempty" jsonschema:"description=Front matter keys to set or override in files synchronized to this target directory"`
+	Rename               map[string]string      `yaml:"rename,omitempty" jsonschema:"description=File names to use in this target directory keyed by the path of the source file relative to its source directory (overrides target_name)"`
+	RequireMarker        string                 `yaml:"require_marker,omitempty" jsonschema:"description=Marker file relative to this target directory that must exist for files to be synchronized to it such as .airulesync-enabled"`
+
+	// externalSet records whether External was set in the configuration file
+	externalSet bool
+}
+
+// Rewrite represents a regular expression replacement applied to each line of synchronized files
+type Rewrite struct {
+	Pattern     string `yaml:"pattern" jsonschema:"description=Regular expression matched against each line"`
+	Replacement string `yaml:"replacement" jsonschema:"description=Replacement for each match where $1 refers to the first capture group"`
+}
+
+// Compile compiles the pattern of the rewrite
+func (r *Rewrite) Compile() (*regexp.Regexp, error) {
+	re, err := regexp.Compile(r.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rewrite pattern %s: %w", r.Pattern, err)
+	}
+	return re, nil
 }
 
 // FileSpec represents a file specification
 type FileSpec struct {
-	Pattern     string `yaml:"pattern,omitempty" jsonschema:"description=File pattern to match (glob pattern)"`
-	AdjustPaths *bool  `yaml:"adjust_paths,omitempty" jsonschema:"description=Whether to adjust relative paths in the file (default: true)"`
-	Overwrite   *bool  `yaml:"overwrite,omitempty" jsonschema:"description=Whether to overwrite existing files (overrides directory setting)"`
+	Pattern              string                 `yaml:"pattern,omitempty" jsonschema:"description=File pattern to match (glob pattern). A pattern starting with ! removes the files matched by the patterns before it"`
+	AdjustPaths          *bool                  `yaml:"adjust_paths,omitempty" jsonschema:"description=Whether to adjust relative paths in the file (default: true)"`
+	Overwrite            OverwriteMode          `yaml:"overwrite,omitempty" jsonschema:"description=Whether to overwrite existing files: a boolean or always/never/prompt/if-newer (overrides directory setting)"`
+	FrontmatterOverrides map[string]interface{} `yaml:"frontmatter_overrides,omitempty" jsonschema:"description=Front matter keys to set or override in the synchronized files (overrides target directory setting)"`
+	TargetName           string                 `yaml:"target_name,omitempty" jsonschema:"description=File name to use for the synchronized files instead of the source file name"`
+	Header               string                 `yaml:"header,omitempty" jsonschema:"description=Comment prepended to the synchronized files (overrides the global header)"`
+	HeaderComment        string                 `yaml:"header_comment,omitempty" jsonschema:"description=Comment prefix of the header (overrides the global header_comment)"`
 }
 
 // UnmarshalYAML implements the yaml.Unmarshaler interface for FileSpec
@@ -55,6 +115,18 @@ func (f *FileSpec) GetPattern() string {
 	return f.Pattern
 }
 
+// IsNegation returns whether the file spec is a negation such as
+// !.cursor/rules/drafts/*.mdc, removing the files matched by the specs
+// before it instead of matching files
+func (f *FileSpec) IsNegation() bool {
+	return strings.HasPrefix(f.Pattern, "!")
+}
+
+// NegatedPattern returns the pattern of the files a negation removes
+func (f *FileSpec) NegatedPattern() string {
+	return strings.TrimPrefix(f.Pattern, "!")
+}
+
 // ShouldAdjustPaths returns whether paths should be adjusted for this file spec
 func (f *FileSpec) ShouldAdjustPaths() bool {
 	if f.AdjustPaths == nil {
@@ -63,45 +135,129 @@ func (f *FileSpec) ShouldAdjustPaths() bool {
 	return *f.AdjustPaths
 }
 
-// ShouldOverwrite returns whether files should be overwritten for this file spec
+// ShouldOverwrite returns whether files may be overwritten for this file spec
 func (f *FileSpec) ShouldOverwrite(dirDefault bool) bool {
-	if f.Overwrite == nil {
+	if f.Overwrite == "" {
 		return dirDefault // Use directory default
 	}
-	return *f.Overwrite
+	return f.Overwrite != OverwriteNever
 }
 
-// GetDirectoryOverwrite returns the overwrite setting for the source directory
+// GetOverwriteMode returns the overwrite mode for this file spec
+func (f *FileSpec) GetOverwriteMode(dirMode OverwriteMode) OverwriteMode {
+	if f.Overwrite == "" {
+		return dirMode // Use directory default
+	}
+	return f.Overwrite
+}
+
+// GetDirectoryOverwrite returns whether files may be overwritten for the source directory
 func (s *SourceDir) GetDirectoryOverwrite() bool {
-	if s.Overwrite == nil {
-		return true // Default is true
+	return s.GetDirectoryOverwriteMode() != OverwriteNever
+}
+
+// GetDirectoryOverwriteMode returns the overwrite mode for the source directory
+func (s *SourceDir) GetDirectoryOverwriteMode() OverwriteMode {
+	if s.Overwrite == "" {
+		return OverwriteAlways // Default is always
+	}
+	return s.Overwrite
+}
+
+// IsCaseInsensitive returns whether patterns match regardless of case,
+// defaulting to the usual case sensitivity of the file system of the OS
+func (c *Config) IsCaseInsensitive() bool {
+	if c.CaseInsensitive == nil {
+		return runtime.GOOS == "darwin" || runtime.GOOS == "windows"
+	}
+	return *c.CaseInsensitive
+}
+
+// DefaultMaxAdjustSize is the size in bytes above which files are copied
+// verbatim when max_adjust_size is not set
+const DefaultMaxAdjustSize = 1 << 20
+
+// GetMaxAdjustSize returns the size in bytes above which files are copied
+// verbatim, or a negative size if files of any size are adjusted
+func (c *Config) GetMaxAdjustSize() int64 {
+	if c.MaxAdjustSize == 0 {
+		return DefaultMaxAdjustSize
+	}
+	return c.MaxAdjustSize
+}
+
+// Effective returns a copy of the configuration as it is used: includes are
+// left out, as their directories and rewrites are already merged in, and
+// options left unset are filled in with their defaults
+func (c *Config) Effective() *Config {
+	effective := *c
+	effective.Schema = ""
+	effective.Include = nil
+
+	caseInsensitive := c.IsCaseInsensitive()
+	effective.CaseInsensitive = &caseInsensitive
+	effective.MaxAdjustSize = c.GetMaxAdjustSize()
+
+	return &effective
+}
+
+// GetTargetPrefix returns the subdirectory of the target directories files
+// are synchronized into, or an empty string for the target directories
+// themselves
+func (s *SourceDir) GetTargetPrefix() string {
+	if s == nil || s.TargetPrefix == "." {
+		return ""
+	}
+	return s.TargetPrefix
+}
+
+// GetGlobBase returns the directory file patterns are matched against
+func (s *SourceDir) GetGlobBase() string {
+	if s.GlobBase == "" {
+		return s.Path // Default is the source directory
 	}
-	return *s.Overwrite
+	return s.GlobBase
 }
 
 // Validate validates the configuration
 func (c *Config) Validate() error {
-	if len(c.SourceDirs) == 0 {
-		return fmt.Errorf("no source directories specified")
+	if strings.ContainsAny(c.Name, "\r\n") {
+		return &ValidationError{Field: "name", Message: "name must be a single line"}
+	}
+
+	if len(c.SourceDirs) == 0 && c.SourceGlob == "" {
+		return &ValidationError{Field: "source_dirs", Message: "no source directories specified"}
 	}
 
 	if len(c.TargetDirs) == 0 {
-		return fmt.Errorf("no target directories specified")
+		return &ValidationError{Field: "target_dirs", Message: "no target directories specified"}
 	}
 
 	// Validate source directories
 	for i, src := range c.SourceDirs {
 		if src.Path == "" {
-			return fmt.Errorf("source directory %d has no path", i+1)
+			return &ValidationError{Field: fmt.Sprintf("source_dirs[%d].path", i), Message: fmt.Sprintf("source directory %d has no path", i+1)}
+		}
+
+		if src.TargetPrefix != "" && !isLocalPath(src.TargetPrefix) {
+			return &ValidationError{Field: fmt.Sprintf("source_dirs[%d].target_prefix", i), Message: fmt.Sprintf("source directory %s has target prefix %s outside of the target directories", src.Path, src.TargetPrefix)}
 		}
 
 		if len(src.Files) == 0 {
-			return fmt.Errorf("source directory %s has no files specified", src.Path)
+			return &ValidationError{Field: fmt.Sprintf("source_dirs[%d].files", i), Message: fmt.Sprintf("source directory %s has no files specified", src.Path)}
 		}
 
 		for j, file := range src.Files {
 			if file.Pattern == "" {
-				return fmt.Errorf("file %d in source directory %s has no pattern", j+1, src.Path)
+				return &ValidationError{Field: fmt.Sprintf("source_dirs[%d].files[%d].pattern", i, j), Message: fmt.Sprintf("file %d in source directory %s has no pattern", j+1, src.Path)}
+			}
+
+			if file.IsNegation() && file.NegatedPattern() == "" {
+				return &ValidationError{Field: fmt.Sprintf("source_dirs[%d].files[%d].pattern", i, j), Message: fmt.Sprintf("file %d in source directory %s negates an empty pattern", j+1, src.Path)}
+			}
+
+			if file.TargetName != "" && !isFileName(file.TargetName) {
+				return &ValidationError{Field: fmt.Sprintf("source_dirs[%d].files[%d].target_name", i, j), Message: fmt.Sprintf("file %s in source directory %s has invalid target name %s", file.Pattern, src.Path, file.TargetName)}
 			}
 		}
 	}
@@ -109,39 +265,278 @@ func (c *Config) Validate() error {
 	// Validate target directories
 	for i, tgt := range c.TargetDirs {
 		if tgt.Path == "" {
-			return fmt.Errorf("target directory %d has no path", i+1)
+			return &ValidationError{Field: fmt.Sprintf("target_dirs[%d].path", i), Message: fmt.Sprintf("target directory %d has no path", i+1)}
+		}
+
+		for source, name := range tgt.Rename {
+			if !isFileName(name) {
+				return &ValidationError{Field: fmt.Sprintf("target_dirs[%d].rename", i), Message: fmt.Sprintf("target directory %s has invalid name %s for %s", tgt.Path, name, source)}
+			}
+		}
+	}
+
+	// Validate rewrites
+	for i, rewrite := range c.Rewrites {
+		if rewrite.Pattern == "" {
+			return &ValidationError{Field: fmt.Sprintf("rewrites[%d].pattern", i), Message: fmt.Sprintf("rewrite %d has no pattern", i+1)}
+		}
+
+		if _, err := rewrite.Compile(); err != nil {
+			return &ValidationError{Field: fmt.Sprintf("rewrites[%d].pattern", i), Message: err.Error(), Err: err}
 		}
 	}
 
 	return nil
 }
 
-// LoadConfig loads the configuration from a file
-func LoadConfig(configPath string) (*Config, error) {
-	data, err := os.ReadFile(configPath)
+// isLocalPath checks if path is a relative path that stays within the
+// directory it is relative to
+func isLocalPath(path string) bool {
+	if filepath.IsAbs(path) {
+		return false
+	}
+	clean := filepath.ToSlash(filepath.Clean(path))
+	return clean != ".." && !strings.HasPrefix(clean, "../")
+}
+
+// isFileName checks if name is a plain file name without directory components
+func isFileName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
+// SelectSourceDirs restricts the source directories to the given paths.
+// Paths are matched against the normalized configured paths, relative to the
+// working directory or to the configuration file, and an error is returned
+// for any path that doesn't match a configured source directory. Directories
+// discovered with the source glob are not selected.
+func (c *Config) SelectSourceDirs(paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	c.SourceGlob = ""
+
+	selected := make([]bool, len(paths))
+	var sourceDirs []SourceDir
+	for _, src := range c.SourceDirs {
+		if c.matchDirPaths(src.Path, paths, selected) {
+			sourceDirs = append(sourceDirs, src)
+		}
+	}
+
+	for i, path := range paths {
+		if !selected[i] {
+			return fmt.Errorf("source directory %s is not configured", path)
+		}
+	}
+
+	c.SourceDirs = sourceDirs
+	return nil
+}
+
+// SelectTargetDirs restricts the target directories to the given paths.
+// Paths are matched against the normalized configured paths, relative to the
+// working directory or to the configuration file, and an error is returned
+// for any path that doesn't match a configured target directory.
+func (c *Config) SelectTargetDirs(paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	selected := make([]bool, len(paths))
+	var targetDirs []TargetDir
+	for _, tgt := range c.TargetDirs {
+		if c.matchDirPaths(tgt.Path, paths, selected) {
+			targetDirs = append(targetDirs, tgt)
+		}
+	}
+
+	for i, path := range paths {
+		if !selected[i] {
+			return fmt.Errorf("target directory %s is not configured", path)
+		}
+	}
+
+	c.TargetDirs = targetDirs
+	return nil
+}
+
+// ExcludeTargetDirs removes the target directories with the given paths.
+// Paths are matched against the normalized configured paths, relative to the
+// working directory or to the configuration file, and the paths that don't
+// match any target directory are returned.
+func (c *Config) ExcludeTargetDirs(paths []string) []string {
+	excluded := make([]bool, len(paths))
+	var targetDirs []TargetDir
+	for _, tgt := range c.TargetDirs {
+		if c.matchDirPaths(tgt.Path, paths, excluded) {
+			continue
+		}
+		targetDirs = append(targetDirs, tgt)
+	}
+
+	var unknown []string
+	for i, path := range paths {
+		if !excluded[i] {
+			unknown = append(unknown, path)
+		}
+	}
+
+	c.TargetDirs = targetDirs
+	return unknown
+}
+
+// matchDirPaths reports whether any of the given paths names a configured
+// directory, marking the matching paths in matched. A relative path matches
+// relative to the working directory or to the configuration file.
+func (c *Config) matchDirPaths(dir string, paths []string, matched []bool) bool {
+	absDir := absPath(dir)
+	found := false
+	for i, path := range paths {
+		if absPath(path) == absDir || (!filepath.IsAbs(path) && absPath(filepath.Join(c.Dir, path)) == absDir) {
+			matched[i] = true
+			found = true
+		}
+	}
+	return found
+}
+
+// absPath returns the absolute form of a path, or the cleaned path if it
+// can't be resolved
+func absPath(path string) string {
+	abs, err := filepath.Abs(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		return filepath.Clean(path)
 	}
+	return abs
+}
 
-	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+// LoadConfig loads the configuration from a file. Relative directory paths
+// and the source glob are resolved against the directory of the file, so the
+// configuration works the same from any working directory.
+func LoadConfig(configPath string) (*Config, error) {
+	config, err := readConfig(configPath, nil)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := config.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
+	config.Dir = configDir(configPath)
+	config.rebasePaths(config.Dir)
+
 	// Normalize paths
 	for i := range config.SourceDirs {
 		config.SourceDirs[i].Path = filepath.Clean(config.SourceDirs[i].Path)
+		if config.SourceDirs[i].GlobBase != "" {
+			config.SourceDirs[i].GlobBase = filepath.Clean(config.SourceDirs[i].GlobBase)
+		}
+		if config.SourceDirs[i].TargetPrefix != "" {
+			config.SourceDirs[i].TargetPrefix = filepath.Clean(config.SourceDirs[i].TargetPrefix)
+		}
 	}
 
 	for i := range config.TargetDirs {
 		config.TargetDirs[i].Path = filepath.Clean(config.TargetDirs[i].Path)
 	}
 
-	return &config, nil
+	// Read the repository-level ignore file next to the configuration file
+	ignorePath := filepath.Join(filepath.Dir(configPath), ignore.File)
+	if _, err := os.Stat(ignorePath); err == nil {
+		config.Ignore, err = ignore.Load(ignorePath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Mark targets outside the repository as external unless configured
+	if err := config.DetectExternalTargets(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// expandEnv replaces ${VAR} and $VAR references to environment variables in
+// the directory paths, source glob and includes of the configuration.
+// Undefined variables are replaced by the empty string.
+func (c *Config) expandEnv() {
+	for i := range c.SourceDirs {
+		c.SourceDirs[i].Path = os.ExpandEnv(c.SourceDirs[i].Path)
+		c.SourceDirs[i].GlobBase = os.ExpandEnv(c.SourceDirs[i].GlobBase)
+		c.SourceDirs[i].TargetPrefix = os.ExpandEnv(c.SourceDirs[i].TargetPrefix)
+	}
+	for i := range c.TargetDirs {
+		c.TargetDirs[i].Path = os.ExpandEnv(c.TargetDirs[i].Path)
+	}
+	c.SourceGlob = os.ExpandEnv(c.SourceGlob)
+	for i := range c.Include {
+		c.Include[i] = os.ExpandEnv(c.Include[i])
+	}
+}
+
+// rebasePaths resolves the relative directory paths and source glob of the
+// configuration against dir
+func (c *Config) rebasePaths(dir string) {
+	rebase := func(path string) string {
+		if path == "" || filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(dir, path)
+	}
+
+	for i := range c.SourceDirs {
+		c.SourceDirs[i].Path = rebase(c.SourceDirs[i].Path)
+		c.SourceDirs[i].GlobBase = rebase(c.SourceDirs[i].GlobBase)
+	}
+	for i := range c.TargetDirs {
+		c.TargetDirs[i].Path = rebase(c.TargetDirs[i].Path)
+	}
+	c.SourceGlob = rebase(c.SourceGlob)
+}
+
+// configDir returns the directory of a configuration file. An absolute
+// directory containing or inside the working directory is made relative to
+// it to keep paths in reports short.
+func configDir(configPath string) string {
+	dir := filepath.Dir(configPath)
+	if !filepath.IsAbs(dir) {
+		return dir
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return dir
+	}
+	rel, err := filepath.Rel(wd, dir)
+	if err != nil {
+		return dir
+	}
+	if !strings.HasPrefix(rel, "..") || strings.Trim(filepath.ToSlash(rel), "./") == "" {
+		return rel
+	}
+	return dir
+}
+
+// FindConfig returns the path of the nearest file with the given name in dir
+// or its parents, like git finds its .git directory. The error matches
+// ErrConfigNotFound when there is none.
+func FindConfig(dir, name string) (string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to get absolute path for %s: %w", dir, err)
+	}
+
+	for current := absDir; ; current = filepath.Dir(current) {
+		path := filepath.Join(current, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+		if filepath.Dir(current) == current {
+			return "", fmt.Errorf("%w: no %s in %s or its parents", ErrConfigNotFound, name, absDir)
+		}
+	}
 }
 
 // DefaultConfigPath returns the default configuration path
@@ -149,20 +544,92 @@ func DefaultConfigPath() string {
 	return ".airulesync.yaml"
 }
 
+// YAMLStyle controls how sequences are emitted when saving a configuration
+type YAMLStyle string
+
+const (
+	// YAMLStyleBlock emits every sequence in block style
+	YAMLStyleBlock YAMLStyle = "block"
+	// YAMLStyleFlow emits short sequences in flow style, e.g. [a, b]
+	YAMLStyleFlow YAMLStyle = "flow"
+)
+
+// flowMaxItems is the maximum number of items of a sequence emitted in flow style
+const flowMaxItems = 8
+
 // SaveConfig saves the configuration to a file
 func SaveConfig(config *Config, configPath string) error {
-	data, err := yaml.Marshal(config)
+	return SaveConfigWithStyle(config, configPath, YAMLStyleBlock)
+}
+
+// SaveConfigWithStyle saves the configuration to a file using the given YAML style
+func SaveConfigWithStyle(config *Config, configPath string, style YAMLStyle) error {
+	data, err := MarshalConfigForPath(config, configPath, style)
 	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+		return err
 	}
 
-	// Add header comments
-	headerComments := []byte("# yaml-language-server: $schema=https://raw.githubusercontent.com/upamune/airulesync/refs/heads/main/schema.json\n# vim: set ts=2 sw=2 tw=0 fo=cnqoj\n")
-	dataWithComments := append(headerComments, data...)
-
-	if err := os.WriteFile(configPath, dataWithComments, 0644); err != nil {
+	if err := os.WriteFile(configPath, data, 0644); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
 	return nil
 }
+
+// MarshalConfig returns the YAML of the configuration in the given style,
+// preceded by the header comments for editor integration
+func MarshalConfig(config *Config, style YAMLStyle) ([]byte, error) {
+	// The schema is referenced by the header comment instead
+	withoutSchema := *config
+	withoutSchema.Schema = ""
+
+	var node yaml.Node
+	if err := node.Encode(&withoutSchema); err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	switch style {
+	case YAMLStyleBlock, "":
+	case YAMLStyleFlow:
+		applyFlowStyle(&node)
+	default:
+		return nil, fmt.Errorf("unknown YAML style %q", style)
+	}
+
+	data, err := yaml.Marshal(&node)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	// Add header comments
+	headerComments := []byte("# yaml-language-server: $schema=" + SchemaURL + "\n# vim: set ts=2 sw=2 tw=0 fo=cnqoj\n")
+	return append(headerComments, data...), nil
+}
+
+// applyFlowStyle switches short sequences of scalars, or of mappings holding
+// only scalars, to flow style
+func applyFlowStyle(node *yaml.Node) {
+	for _, child := range node.Content {
+		applyFlowStyle(child)
+	}
+
+	if node.Kind != yaml.SequenceNode || len(node.Content) > flowMaxItems {
+		return
+	}
+
+	for _, item := range node.Content {
+		switch item.Kind {
+		case yaml.ScalarNode:
+		case yaml.MappingNode:
+			for _, value := range item.Content {
+				if value.Kind != yaml.ScalarNode {
+					return
+				}
+			}
+		default:
+			return
+		}
+	}
+
+	node.Style = yaml.FlowStyle
+}
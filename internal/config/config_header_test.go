@@ -2,8 +2,11 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"gopkg.in/yaml.v3"
 )
 
 func TestSaveConfigWithHeaderComments(t *testing.T) {
@@ -60,3 +63,132 @@ func TestSaveConfigWithHeaderComments(t *testing.T) {
 		t.Errorf("YAML content not found in config file")
 	}
 }
+
+func TestSaveConfigWithStyle(t *testing.T) {
+	// Create a config with short lists
+	cfg := &Config{
+		SourceDirs: []SourceDir{
+			{
+				Path: ".",
+				Files: []FileSpec{
+					{Pattern: ".clinerules"},
+					{Pattern: ".roomodes"},
+				},
+				IgnoreFiles: []string{"*.bak", "*.tmp"},
+			},
+		},
+		TargetDirs: []TargetDir{},
+	}
+
+	// Test cases for YAML styles
+	testCases := []struct {
+		style      YAMLStyle
+		expected   []string
+		unexpected []string
+	}{
+		{
+			style: YAMLStyleFlow,
+			expected: []string{
+				"files: [{pattern: .clinerules}, {pattern: .roomodes}]",
+				"ignore_files: ['*.bak', '*.tmp']",
+				"target_dirs: []",
+			},
+			unexpected: []string{"- pattern: .clinerules"},
+		},
+		{
+			style: YAMLStyleBlock,
+			expected: []string{
+				"- pattern: .clinerules",
+				"- '*.bak'",
+				"target_dirs: []",
+			},
+			unexpected: []string{"[{pattern: .clinerules}"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.style), func(t *testing.T) {
+			tempFile := filepath.Join(t.TempDir(), "config.yaml")
+			if err := SaveConfigWithStyle(cfg, tempFile, tc.style); err != nil {
+				t.Fatalf("Failed to save config: %v", err)
+			}
+
+			data, err := os.ReadFile(tempFile)
+			if err != nil {
+				t.Fatalf("Failed to read config file: %v", err)
+			}
+			content := string(data)
+
+			for _, expected := range tc.expected {
+				if !strings.Contains(content, expected) {
+					t.Errorf("Expected config to contain '%s', got:\n%s", expected, content)
+				}
+			}
+			for _, unexpected := range tc.unexpected {
+				if strings.Contains(content, unexpected) {
+					t.Errorf("Expected config not to contain '%s', got:\n%s", unexpected, content)
+				}
+			}
+
+			// Both styles load back to the same configuration
+			var loaded Config
+			if err := yaml.Unmarshal(data, &loaded); err != nil {
+				t.Fatalf("Failed to parse saved config: %v", err)
+			}
+			if len(loaded.SourceDirs[0].Files) != 2 || len(loaded.SourceDirs[0].IgnoreFiles) != 2 {
+				t.Errorf("Expected saved config to round-trip, got %+v", loaded.SourceDirs[0])
+			}
+		})
+	}
+}
+
+func TestSaveConfigAsJSON(t *testing.T) {
+	cfg := &Config{
+		Name: "json-rules",
+		SourceDirs: []SourceDir{
+			{
+				Path:  ".",
+				Files: []FileSpec{{Pattern: ".clinerules"}, {Pattern: "CLAUDE.md", Overwrite: OverwriteNever}},
+			},
+		},
+		TargetDirs: []TargetDir{{Path: "./services/api"}},
+	}
+
+	// A configuration saved with a .json extension is written as JSON
+	configPath := filepath.Join(t.TempDir(), ".airulesync.json")
+	if err := SaveConfig(cfg, configPath); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read config file: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "{\n  \"$schema\": \""+SchemaURL+"\",\n  \"name\": \"json-rules\",") {
+		t.Errorf("Expected JSON starting with the $schema key, got:\n%s", data)
+	}
+	if strings.Contains(string(data), "yaml-language-server") {
+		t.Errorf("Expected no YAML header comments in JSON config, got:\n%s", data)
+	}
+
+	// The JSON configuration parses back
+	loaded, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load JSON config: %v\n%s", err, data)
+	}
+	if loaded.Name != "json-rules" || len(loaded.SourceDirs) != 1 || len(loaded.SourceDirs[0].Files) != 2 || len(loaded.TargetDirs) != 1 {
+		t.Errorf("Expected the saved configuration to be loaded, got: %+v", loaded)
+	}
+	if spec := loaded.SourceDirs[0].Files[1]; spec.Pattern != "CLAUDE.md" || spec.Overwrite != OverwriteNever {
+		t.Errorf("Expected CLAUDE.md with overwrite: never, got: %+v", spec)
+	}
+
+	// Saving the loaded configuration as YAML doesn't keep the $schema key
+	yamlData, err := MarshalConfig(loaded, YAMLStyleBlock)
+	if err != nil {
+		t.Fatalf("Failed to marshal config: %v", err)
+	}
+	if strings.Contains(string(yamlData), "$schema:") {
+		t.Errorf("Expected no $schema key in YAML config, got:\n%s", yamlData)
+	}
+}
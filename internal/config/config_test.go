@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -37,7 +38,8 @@ target_dirs:
 		t.Fatalf("Failed to write test config file: %v", err)
 	}
 
-	// Load the configuration
+	// Load the configuration from its directory
+	t.Chdir(tempDir)
 	cfg, err := LoadConfig(configPath)
 	if err != nil {
 		t.Fatalf("Failed to load valid config: %v", err)
@@ -101,6 +103,47 @@ target_dirs:
 	}
 }
 
+func TestConfigMetadataRoundTrip(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+
+	// Write a configuration with a name and description
+	if err := os.WriteFile(configPath, []byte(`
+name: monorepo-rules
+description: Shares the root rules with every service
+source_dirs:
+  - path: "."
+    files:
+      - ".clinerules"
+target_dirs:
+  - path: "./services/api"
+`), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if config.Name != "monorepo-rules" || config.Description != "Shares the root rules with every service" {
+		t.Errorf("Expected name and description to be loaded, got %q and %q", config.Name, config.Description)
+	}
+
+	// The metadata survives saving and loading the configuration again
+	savedPath := filepath.Join(tempDir, "saved.yaml")
+	if err := SaveConfig(config, savedPath); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	saved, err := LoadConfig(savedPath)
+	if err != nil {
+		t.Fatalf("Failed to load saved config: %v", err)
+	}
+	if saved.Name != config.Name || saved.Description != config.Description {
+		t.Errorf("Expected name and description to round-trip, got %q and %q", saved.Name, saved.Description)
+	}
+}
+
 func TestLoadInvalidConfig(t *testing.T) {
 	// Create a temporary config file
 	tempDir := t.TempDir()
@@ -125,6 +168,20 @@ source_dirs:
   - path: "./src/main-project"
     files:
       - ".clinerules"
+`,
+		},
+		{
+			name: "multi-line name",
+			config: `
+name: |
+  rules
+  sync
+source_dirs:
+  - path: "./src/main-project"
+    files:
+      - ".clinerules"
+target_dirs:
+  - path: "./src/sub-project-a"
 `,
 		},
 		{
@@ -157,6 +214,32 @@ source_dirs:
     files: []
 target_dirs:
   - path: "./src/sub-project-a"
+`,
+		},
+		{
+			name: "invalid rewrite pattern",
+			config: `
+source_dirs:
+  - path: "./src/main-project"
+    files:
+      - ".clinerules"
+target_dirs:
+  - path: "./src/sub-project-a"
+rewrites:
+  - pattern: "@include\\((.*"
+    replacement: "$1"
+`,
+		},
+		{
+			name: "target name with directory",
+			config: `
+source_dirs:
+  - path: "./src/main-project"
+    files:
+      - pattern: ".clinerules"
+        target_name: "docs/CLAUDE.md"
+target_dirs:
+  - path: "./src/sub-project-a"
 `,
 		},
 		{
@@ -288,3 +371,261 @@ overwrite: false
 		})
 	}
 }
+
+func TestUnmarshalConfigWithAnchors(t *testing.T) {
+	// Anchored file specs and files lists reused through aliases and merge keys
+	data := `
+x-spec: &claude
+  pattern: "CLAUDE.md"
+  overwrite: never
+x-files: &files
+  - &clinerules ".clinerules"
+  - *claude
+x-source: &source
+  overwrite: never
+  files: *files
+x-target: &target
+  external: false
+source_dirs:
+  - path: alias
+    files: *files
+  - <<: *source
+    path: merged
+  - path: items
+    files:
+      - *clinerules
+      - <<: *claude
+        target_name: AGENTS.md
+target_dirs:
+  - <<: *target
+    path: ../merged
+  - <<: [*target]
+    path: ../merged-list
+  - path: ../plain
+`
+
+	var config Config
+	if err := yaml.Unmarshal([]byte(data), &config); err != nil {
+		t.Fatalf("Failed to unmarshal config: %v", err)
+	}
+
+	if len(config.SourceDirs) != 3 {
+		t.Fatalf("Expected 3 source directories, got %+v", config.SourceDirs)
+	}
+
+	// The anchored files list resolves to a string and a struct spec,
+	// both through a direct alias and through a merge key
+	for _, sourceDir := range config.SourceDirs[:2] {
+		files := sourceDir.Files
+		if len(files) != 2 || files[0].Pattern != ".clinerules" || files[1].Pattern != "CLAUDE.md" || files[1].Overwrite != OverwriteNever {
+			t.Errorf("Expected aliased files in %s to resolve, got %+v", sourceDir.Path, files)
+		}
+	}
+	if config.SourceDirs[1].Overwrite != OverwriteNever {
+		t.Errorf("Expected merged overwrite setting, got %q", config.SourceDirs[1].Overwrite)
+	}
+
+	// Aliased items and merged file specs resolve too
+	files := config.SourceDirs[2].Files
+	if len(files) != 2 || files[0].Pattern != ".clinerules" {
+		t.Errorf("Expected aliased string file spec, got %+v", files)
+	} else if files[1].Pattern != "CLAUDE.md" || files[1].Overwrite != OverwriteNever || files[1].TargetName != "AGENTS.md" {
+		t.Errorf("Expected merged file spec with target name, got %+v", files[1])
+	}
+
+	// An external flag set through a merge key counts as set explicitly
+	expected := []bool{true, true, false}
+	for i, targetDir := range config.TargetDirs {
+		if targetDir.externalSet != expected[i] {
+			t.Errorf("Expected external flag of %s to be set explicitly: %v, got %v", targetDir.Path, expected[i], targetDir.externalSet)
+		}
+	}
+}
+
+func TestOverwriteModeUnmarshalYAML(t *testing.T) {
+	// Test cases for the boolean and string forms of overwrite
+	testCases := []struct {
+		name        string
+		yaml        string
+		expected    OverwriteMode
+		shouldError bool
+	}{
+		{name: "true", yaml: "overwrite: true", expected: OverwriteAlways},
+		{name: "false", yaml: "overwrite: false", expected: OverwriteNever},
+		{name: "always", yaml: "overwrite: always", expected: OverwriteAlways},
+		{name: "never", yaml: "overwrite: never", expected: OverwriteNever},
+		{name: "prompt", yaml: "overwrite: prompt", expected: OverwritePrompt},
+		{name: "if-newer", yaml: "overwrite: if-newer", expected: OverwriteIfNewer},
+		{name: "invalid", yaml: "overwrite: sometimes", shouldError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var sourceDir SourceDir
+			err := yaml.Unmarshal([]byte(tc.yaml), &sourceDir)
+
+			if tc.shouldError {
+				if err == nil {
+					t.Errorf("Expected error, but got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Failed to unmarshal YAML: %v", err)
+			}
+
+			if sourceDir.GetDirectoryOverwriteMode() != tc.expected {
+				t.Errorf("Expected overwrite mode '%s', got '%s'", tc.expected, sourceDir.GetDirectoryOverwriteMode())
+			}
+		})
+	}
+}
+
+func TestLoadConfigResolvesPathsAgainstConfigDir(t *testing.T) {
+	// Create a configuration in a subdirectory and run from a sibling
+	tempDir := t.TempDir()
+	configDir := filepath.Join(tempDir, "project", "config")
+	workDir := filepath.Join(tempDir, "elsewhere")
+	for _, dir := range []string{configDir, workDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+	}
+	content := `
+source_dirs:
+  - path: ".."
+    glob_base: "../rules"
+    files:
+      - ".clinerules"
+target_dirs:
+  - path: "../packages/app"
+  - path: "."
+`
+	if err := os.WriteFile(filepath.Join(configDir, ".airulesync.yaml"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Chdir(workDir)
+
+	cfg, err := LoadConfig(filepath.Join("..", "project", "config", ".airulesync.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Relative paths resolve against the configuration file's directory
+	expected := map[string]string{
+		"source path":   filepath.Join(tempDir, "project"),
+		"glob base":     filepath.Join(tempDir, "project", "rules"),
+		"first target":  filepath.Join(tempDir, "project", "packages", "app"),
+		"second target": configDir,
+	}
+	actual := map[string]string{
+		"source path":   cfg.SourceDirs[0].Path,
+		"glob base":     cfg.SourceDirs[0].GlobBase,
+		"first target":  cfg.TargetDirs[0].Path,
+		"second target": cfg.TargetDirs[1].Path,
+	}
+	for name, want := range expected {
+		got, err := filepath.Abs(actual[name])
+		if err != nil {
+			t.Fatalf("Failed to get absolute path: %v", err)
+		}
+		if got != want {
+			t.Errorf("Expected %s %s, got %s", name, want, got)
+		}
+	}
+}
+
+func TestLoadConfigExpandsEnvironmentVariables(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("AIRULESYNC_TEST_RULES", filepath.Join(tempDir, "shared"))
+	t.Setenv("AIRULESYNC_TEST_APP", "web")
+
+	content := `
+source_dirs:
+  - path: "${AIRULESYNC_TEST_RULES}/rules"
+    files:
+      - ".clinerules"
+target_dirs:
+  - path: "./apps/$AIRULESYNC_TEST_APP"
+  - path: "./apps/${AIRULESYNC_TEST_UNDEFINED}api"
+`
+	configPath := filepath.Join(tempDir, ".airulesync.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Variables expand before relative paths are resolved, and undefined
+	// variables expand to nothing
+	expected := []string{
+		filepath.Join(tempDir, "shared", "rules"),
+		filepath.Join(tempDir, "apps", "web"),
+		filepath.Join(tempDir, "apps", "api"),
+	}
+	actual := []string{cfg.SourceDirs[0].Path, cfg.TargetDirs[0].Path, cfg.TargetDirs[1].Path}
+	for i, want := range expected {
+		got, err := filepath.Abs(actual[i])
+		if err != nil {
+			t.Fatalf("Failed to get absolute path: %v", err)
+		}
+		if got != want {
+			t.Errorf("Expected path %s, got %s", want, got)
+		}
+	}
+}
+
+func TestFindConfig(t *testing.T) {
+	// Create a project with a nested directory
+	projectDir := t.TempDir()
+	nestedDir := filepath.Join(projectDir, "a", "b")
+	if err := os.MkdirAll(nestedDir, 0755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+	configPath := filepath.Join(projectDir, ".airulesync.yaml")
+	content := `
+source_dirs:
+  - path: "."
+    files:
+      - ".clinerules"
+target_dirs:
+  - path: "./sub"
+  - path: "/abs/target"
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	// The configuration file is found from the nested directory
+	path, err := FindConfig(nestedDir, DefaultConfigPath())
+	if err != nil {
+		t.Fatalf("FindConfig failed: %v", err)
+	}
+	if path != configPath {
+		t.Errorf("Expected %s, got %s", configPath, path)
+	}
+
+	// Relative paths are resolved against the directory of the file
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.SourceDirs[0].Path != projectDir {
+		t.Errorf("Expected source path %s, got %s", projectDir, cfg.SourceDirs[0].Path)
+	}
+	if want := filepath.Join(projectDir, "sub"); cfg.TargetDirs[0].Path != want {
+		t.Errorf("Expected target path %s, got %s", want, cfg.TargetDirs[0].Path)
+	}
+	if cfg.TargetDirs[1].Path != "/abs/target" {
+		t.Errorf("Expected absolute target path to be kept, got %s", cfg.TargetDirs[1].Path)
+	}
+
+	// A missing configuration file matches ErrConfigNotFound
+	if _, err := FindConfig(nestedDir, "missing.yaml"); !errors.Is(err, ErrConfigNotFound) {
+		t.Errorf("Expected ErrConfigNotFound, got %v", err)
+	}
+}
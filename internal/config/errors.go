@@ -0,0 +1,27 @@
+package config
+
+import "errors"
+
+// ErrConfigNotFound is returned when a configuration file, or a file it
+// includes, doesn't exist
+var ErrConfigNotFound = errors.New("config file not found")
+
+// ValidationError is an invalid setting of a configuration
+type ValidationError struct {
+	// Field is the path of the invalid setting, such as source_dirs[0].path
+	Field string
+	// Message describes the problem
+	Message string
+	// Err is the underlying error, if any
+	Err error
+}
+
+// Error returns the description of the problem
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the underlying error
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
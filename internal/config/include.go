@@ -0,0 +1,75 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// readConfig reads a config file and merges the source and target directories
+// and rewrites of its included files in before its own. Directory paths of
+// included files are rebased from their directory onto the directory of the
+// including file. stack holds the files currently being included to detect
+// include cycles.
+func readConfig(configPath string, stack []string) (*Config, error) {
+	absPath, err := filepath.Abs(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for config file: %w", err)
+	}
+
+	for i, included := range stack {
+		if included == absPath {
+			cycle := append(append([]string{}, stack[i:]...), absPath)
+			return nil, fmt.Errorf("include cycle detected: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	stack = append(stack, absPath)
+
+	data, err := os.ReadFile(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %w", ErrConfigNotFound, err)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	config.expandEnv()
+
+	if len(config.Include) == 0 {
+		return &config, nil
+	}
+
+	// Included directories come first, in include order
+	var sourceDirs []SourceDir
+	var targetDirs []TargetDir
+	var rewrites []Rewrite
+	for _, include := range config.Include {
+		includePath := include
+		if !filepath.IsAbs(includePath) {
+			includePath = filepath.Join(filepath.Dir(configPath), includePath)
+		}
+
+		included, err := readConfig(includePath, stack)
+		if err != nil {
+			return nil, fmt.Errorf("failed to include %s: %w", include, err)
+		}
+		included.rebasePaths(filepath.Dir(include))
+
+		sourceDirs = append(sourceDirs, included.SourceDirs...)
+		targetDirs = append(targetDirs, included.TargetDirs...)
+		rewrites = append(rewrites, included.Rewrites...)
+	}
+
+	config.SourceDirs = append(sourceDirs, config.SourceDirs...)
+	config.TargetDirs = append(targetDirs, config.TargetDirs...)
+	config.Rewrites = append(rewrites, config.Rewrites...)
+
+	return &config, nil
+}
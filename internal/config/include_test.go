@@ -0,0 +1,121 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigWithInclude(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// The repo config includes a shared fragment, which includes a base fragment
+	files := map[string]string{
+		filepath.Join(tempDir, "repo", ".airulesync.yaml"): `
+include:
+  - ../shared/rules.yaml
+source_dirs:
+  - path: ./local
+    files:
+      - .clinerules
+target_dirs:
+  - path: ./sub-project
+`,
+		filepath.Join(tempDir, "shared", "rules.yaml"): `
+include:
+  - base/base.yaml
+source_dirs:
+  - path: ./shared
+    files:
+      - .roomodes
+`,
+		filepath.Join(tempDir, "shared", "base", "base.yaml"): `
+source_dirs:
+  - path: ./base
+    files:
+      - .cursor/rules/*.mdc
+target_dirs:
+  - path: ./base-target
+`,
+	}
+
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test config file: %v", err)
+		}
+	}
+
+	cfg, err := LoadConfig(filepath.Join(tempDir, "repo", ".airulesync.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to load config with includes: %v", err)
+	}
+
+	// Included directories come before the local ones, with paths relative to
+	// the file they are configured in
+	expectedSources := []string{"shared/base/base", "shared/shared", "repo/local"}
+	if len(cfg.SourceDirs) != len(expectedSources) {
+		t.Fatalf("Expected %d source directories, got %d", len(expectedSources), len(cfg.SourceDirs))
+	}
+	for i, expected := range expectedSources {
+		expected = filepath.Join(tempDir, filepath.FromSlash(expected))
+		if cfg.SourceDirs[i].Path != expected {
+			t.Errorf("Expected source directory %d to be '%s', got '%s'", i, expected, cfg.SourceDirs[i].Path)
+		}
+	}
+
+	expectedTargets := []string{"shared/base/base-target", "repo/sub-project"}
+	if len(cfg.TargetDirs) != len(expectedTargets) {
+		t.Fatalf("Expected %d target directories, got %d", len(expectedTargets), len(cfg.TargetDirs))
+	}
+	for i, expected := range expectedTargets {
+		expected = filepath.Join(tempDir, filepath.FromSlash(expected))
+		if cfg.TargetDirs[i].Path != expected {
+			t.Errorf("Expected target directory %d to be '%s', got '%s'", i, expected, cfg.TargetDirs[i].Path)
+		}
+	}
+}
+
+func TestLoadConfigWithIncludeCycle(t *testing.T) {
+	tempDir := t.TempDir()
+
+	files := map[string]string{
+		"a.yaml": `
+include:
+  - b.yaml
+target_dirs:
+  - path: ./target
+`,
+		"b.yaml": `
+include:
+  - a.yaml
+source_dirs:
+  - path: ./source
+    files:
+      - .clinerules
+`,
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test config file: %v", err)
+		}
+	}
+
+	_, err := LoadConfig(filepath.Join(tempDir, "a.yaml"))
+	if err == nil {
+		t.Fatalf("Expected an error for an include cycle, but got nil")
+	}
+
+	expected := "include cycle detected: " + strings.Join([]string{
+		filepath.Join(tempDir, "a.yaml"),
+		filepath.Join(tempDir, "b.yaml"),
+		filepath.Join(tempDir, "a.yaml"),
+	}, " -> ")
+	if !strings.Contains(err.Error(), expected) {
+		t.Errorf("Expected error to contain '%s', got '%v'", expected, err)
+	}
+}
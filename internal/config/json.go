@@ -0,0 +1,110 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaURL is the URL of the JSON schema of configuration files
+const SchemaURL = "https://raw.githubusercontent.com/upamune/airulesync/refs/heads/main/schema.json"
+
+// MarshalConfigForPath returns the configuration in the format matching the
+// extension of a path: JSON for .json files and YAML in the given style otherwise
+func MarshalConfigForPath(config *Config, configPath string, style YAMLStyle) ([]byte, error) {
+	if strings.EqualFold(filepath.Ext(configPath), ".json") {
+		return MarshalConfigJSON(config)
+	}
+	return MarshalConfig(config, style)
+}
+
+// MarshalConfigJSON returns the configuration as indented JSON. As JSON can't
+// carry the schema comment of YAML files, the schema is referenced by a
+// $schema key instead.
+func MarshalConfigJSON(config *Config) ([]byte, error) {
+	withSchema := *config
+	withSchema.Schema = SchemaURL
+
+	// Encode through YAML to use the same field names and value formats
+	var node yaml.Node
+	if err := node.Encode(&withSchema); err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	data, err := json.MarshalIndent(jsonValue(&node), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// jsonValue converts a YAML node to a value encoding/json marshals with the
+// keys of mappings in the same order
+func jsonValue(node *yaml.Node) any {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil
+		}
+		return jsonValue(node.Content[0])
+	case yaml.MappingNode:
+		object := make(jsonObject, 0, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			object = append(object, jsonField{key: node.Content[i].Value, value: jsonValue(node.Content[i+1])})
+		}
+		return object
+	case yaml.SequenceNode:
+		array := make([]any, 0, len(node.Content))
+		for _, item := range node.Content {
+			array = append(array, jsonValue(item))
+		}
+		return array
+	case yaml.AliasNode:
+		return jsonValue(node.Alias)
+	default:
+		var value any
+		if err := node.Decode(&value); err != nil {
+			return node.Value
+		}
+		return value
+	}
+}
+
+// jsonField is a key and value of a JSON object
+type jsonField struct {
+	key   string
+	value any
+}
+
+// jsonObject is a JSON object keeping the order of its keys
+type jsonObject []jsonField
+
+// MarshalJSON implements the json.Marshaler interface for jsonObject
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		key, err := json.Marshal(field.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.value)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MergeConfig merges the source directories of a generated configuration into
+// an existing configuration file. Only file patterns that are not already
+// present are appended; comments, ordering and all other settings of the
+// existing file are preserved. It returns the number of patterns added.
+func MergeConfig(configPath string, generated *Config) (int, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return 0, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	// An empty file has no document node yet
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return 0, fmt.Errorf("config file %s is not a mapping", configPath)
+	}
+
+	sourceDirs := mappingValue(root, "source_dirs")
+	if sourceDirs == nil {
+		sourceDirs = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		root.Content = append(root.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "source_dirs"},
+			sourceDirs,
+		)
+	}
+	if sourceDirs.Kind != yaml.SequenceNode {
+		return 0, fmt.Errorf("source_dirs in %s is not a list", configPath)
+	}
+
+	// Empty flow sequences such as `source_dirs: []` are expanded to block style
+	sourceDirs.Style = 0
+
+	added := 0
+	for _, src := range generated.SourceDirs {
+		existing := findSourceDirNode(sourceDirs, src.Path)
+		if existing == nil {
+			var node yaml.Node
+			if err := node.Encode(src); err != nil {
+				return 0, fmt.Errorf("failed to encode source directory %s: %w", src.Path, err)
+			}
+			sourceDirs.Content = append(sourceDirs.Content, &node)
+			added += len(src.Files)
+			continue
+		}
+
+		files := mappingValue(existing, "files")
+		if files == nil {
+			files = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			existing.Content = append(existing.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "files"},
+				files,
+			)
+		}
+		files.Style = 0
+
+		patterns := make(map[string]bool)
+		for _, file := range files.Content {
+			patterns[filePatternOf(file)] = true
+		}
+
+		for _, file := range src.Files {
+			if patterns[file.Pattern] {
+				continue
+			}
+			patterns[file.Pattern] = true
+			files.Content = append(files.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: file.Pattern})
+			added++
+		}
+	}
+
+	// Hand-written configurations conventionally use two space indentation
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return 0, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return 0, fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	if err := os.WriteFile(configPath, out.Bytes(), 0644); err != nil {
+		return 0, fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	return added, nil
+}
+
+// mappingValue returns the value node for a key in a mapping node, or nil
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// findSourceDirNode returns the source directory node with the given path, or nil
+func findSourceDirNode(sourceDirs *yaml.Node, path string) *yaml.Node {
+	for _, node := range sourceDirs.Content {
+		if node.Kind != yaml.MappingNode {
+			continue
+		}
+		if value := mappingValue(node, "path"); value != nil && filepath.Clean(value.Value) == filepath.Clean(path) {
+			return node
+		}
+	}
+	return nil
+}
+
+// filePatternOf returns the pattern of a file spec node in either of its forms
+func filePatternOf(node *yaml.Node) string {
+	if node.Kind == yaml.ScalarNode {
+		return node.Value
+	}
+	if value := mappingValue(node, "pattern"); value != nil {
+		return value.Value
+	}
+	return ""
+}
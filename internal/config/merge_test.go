@@ -0,0 +1,96 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMergeConfig(t *testing.T) {
+	// Create a hand-written configuration with comments
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, ".airulesync.yaml")
+
+	existingConfig := `# Shared rules for the whole monorepo
+source_dirs:
+  - path: .
+    files:
+      - .clinerules # keep in sync with the wiki
+      - pattern: .roomodes
+        adjust_paths: false
+target_dirs:
+  - path: ./sub-a
+    external: true
+`
+	if err := os.WriteFile(configPath, []byte(existingConfig), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	// Merge a generated configuration with one known and two new patterns
+	generated := &Config{
+		SourceDirs: []SourceDir{
+			{
+				Path: ".",
+				Files: []FileSpec{
+					{Pattern: ".clinerules"},
+					{Pattern: ".roomodes"},
+					{Pattern: ".cursor/rules/*.mdc"},
+				},
+			},
+			{
+				Path:  "config",
+				Files: []FileSpec{{Pattern: ".windsurfrules"}},
+			},
+		},
+	}
+
+	added, err := MergeConfig(configPath, generated)
+	if err != nil {
+		t.Fatalf("Failed to merge config: %v", err)
+	}
+
+	if added != 2 {
+		t.Errorf("Expected 2 added patterns, got %d", added)
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read config file: %v", err)
+	}
+	content := string(data)
+
+	// Verify that comments survive
+	for _, comment := range []string{"# Shared rules for the whole monorepo", "# keep in sync with the wiki"} {
+		if !strings.Contains(content, comment) {
+			t.Errorf("Expected comment '%s' to survive the merge, got:\n%s", comment, content)
+		}
+	}
+
+	// Verify that existing patterns are not duplicated
+	if strings.Count(content, ".roomodes") != 1 {
+		t.Errorf("Expected .roomodes to appear once, got:\n%s", content)
+	}
+
+	// Verify that the merged configuration loads with the new and existing settings
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load merged config: %v", err)
+	}
+
+	if len(cfg.SourceDirs) != 2 {
+		t.Fatalf("Expected 2 source directories, got %d", len(cfg.SourceDirs))
+	}
+
+	if len(cfg.SourceDirs[0].Files) != 3 || cfg.SourceDirs[0].Files[2].Pattern != ".cursor/rules/*.mdc" {
+		t.Errorf("Expected new pattern appended to the existing source directory, got %+v", cfg.SourceDirs[0].Files)
+	}
+
+	if cfg.SourceDirs[0].Files[1].ShouldAdjustPaths() {
+		t.Errorf("Expected existing file options to be preserved")
+	}
+
+	if len(cfg.TargetDirs) != 1 || cfg.TargetDirs[0].Path != filepath.Join(tempDir, "sub-a") || !cfg.TargetDirs[0].External {
+		t.Errorf("Expected target directories to be untouched, got %+v", cfg.TargetDirs)
+	}
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/invopop/jsonschema"
+	"gopkg.in/yaml.v3"
+)
+
+// OverwriteMode is how existing target files are handled
+type OverwriteMode string
+
+const (
+	// OverwriteAlways overwrites existing target files
+	OverwriteAlways OverwriteMode = "always"
+	// OverwriteNever keeps existing target files
+	OverwriteNever OverwriteMode = "never"
+	// OverwritePrompt asks before overwriting an existing, differing target
+	// file, and keeps it when not running on a terminal
+	OverwritePrompt OverwriteMode = "prompt"
+	// OverwriteIfNewer overwrites existing target files only when the source
+	// file was modified after them, keeping manual edits to targets
+	OverwriteIfNewer OverwriteMode = "if-newer"
+)
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface for OverwriteMode.
+// Booleans are accepted for compatibility: true is always and false is never.
+func (m *OverwriteMode) UnmarshalYAML(value *yaml.Node) error {
+	var overwrite bool
+	if value.Tag == "!!bool" {
+		if err := value.Decode(&overwrite); err != nil {
+			return err
+		}
+		if overwrite {
+			*m = OverwriteAlways
+		} else {
+			*m = OverwriteNever
+		}
+		return nil
+	}
+
+	var mode string
+	if err := value.Decode(&mode); err != nil {
+		return err
+	}
+
+	switch OverwriteMode(mode) {
+	case OverwriteAlways, OverwriteNever, OverwritePrompt, OverwriteIfNewer:
+		*m = OverwriteMode(mode)
+		return nil
+	default:
+		return fmt.Errorf("invalid overwrite mode %q: must be a boolean or one of always, never, prompt, if-newer", mode)
+	}
+}
+
+// JSONSchema describes the boolean and string forms of OverwriteMode
+func (OverwriteMode) JSONSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{
+		OneOf: []*jsonschema.Schema{
+			{Type: "boolean"},
+			{Type: "string", Enum: []interface{}{string(OverwriteAlways), string(OverwriteNever), string(OverwritePrompt), string(OverwriteIfNewer)}},
+		},
+	}
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface for TargetDir,
+// remembering whether the external flag was set explicitly
+func (t *TargetDir) UnmarshalYAML(value *yaml.Node) error {
+	type targetDirAlias TargetDir
+	if err := value.Decode((*targetDirAlias)(t)); err != nil {
+		return err
+	}
+
+	t.externalSet = hasKey(value, "external")
+	return nil
+}
+
+// hasKey checks if a mapping node sets a key, directly, through an alias or
+// through the mappings merged in with << merge keys
+func hasKey(node *yaml.Node, key string) bool {
+	if node.Kind == yaml.AliasNode {
+		return hasKey(node.Alias, key)
+	}
+	if node.Kind != yaml.MappingNode {
+		return false
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		name, value := node.Content[i], node.Content[i+1]
+		if name.Tag != "!!merge" {
+			if name.Value == key {
+				return true
+			}
+			continue
+		}
+
+		// A merge key holds a mapping or a sequence of mappings
+		merged := []*yaml.Node{value}
+		if value.Kind == yaml.SequenceNode {
+			merged = value.Content
+		}
+		for _, mapping := range merged {
+			if hasKey(mapping, key) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FindRepoRoot returns the nearest directory containing .git, starting at dir
+// and moving up through its ancestors, or an empty string if there is none
+func FindRepoRoot(dir string) string {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// DetectExternalTargets marks target directories as external when they lie
+// outside the repository containing the configuration file, or inside another
+// repository nested in it. Target directories with an explicit external flag
+// are kept as configured, and nothing is changed outside of a repository.
+func (c *Config) DetectExternalTargets() error {
+	dir := c.Dir
+	if dir == "" {
+		dir = "."
+	}
+	configDir, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path for configuration directory %s: %w", dir, err)
+	}
+
+	repoRoot := FindRepoRoot(configDir)
+	if repoRoot == "" {
+		return nil
+	}
+
+	for i := range c.TargetDirs {
+		if c.TargetDirs[i].externalSet {
+			continue
+		}
+
+		targetPath, err := filepath.Abs(c.TargetDirs[i].Path)
+		if err != nil {
+			return fmt.Errorf("failed to get absolute path for target directory %s: %w", c.TargetDirs[i].Path, err)
+		}
+
+		c.TargetDirs[i].External = !isWithin(repoRoot, targetPath) || FindRepoRoot(targetPath) != repoRoot
+	}
+
+	return nil
+}
+
+// isWithin checks if path is dir or lies below it
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigDetectsExternalTargets(t *testing.T) {
+	// Create a repository with a nested repository and a sibling repository
+	tempDir := t.TempDir()
+	repoDir := filepath.Join(tempDir, "repo")
+	for _, dir := range []string{
+		filepath.Join(repoDir, ".git"),
+		filepath.Join(repoDir, "packages", "app"),
+		filepath.Join(repoDir, "vendor", "rules", ".git"),
+		filepath.Join(tempDir, "sibling", ".git"),
+	} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+	}
+
+	configPath := filepath.Join(repoDir, ".airulesync.yaml")
+	content := `
+source_dirs:
+  - path: "."
+    files:
+      - ".clinerules"
+target_dirs:
+  - path: "./packages/app"
+  - path: "../sibling"
+  - path: "./vendor/rules"
+  - path: "./not-yet-created"
+  - path: "../sibling/docs"
+    external: false
+  - path: "./packages"
+    external: true
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Chdir(repoDir)
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	expected := map[string]bool{
+		"packages/app":    false,
+		"../sibling":      true,
+		"vendor/rules":    true,
+		"not-yet-created": false,
+		"../sibling/docs": false,
+		"packages":        true,
+	}
+
+	for _, targetDir := range cfg.TargetDirs {
+		want, ok := expected[targetDir.Path]
+		if !ok {
+			t.Errorf("Unexpected target directory '%s'", targetDir.Path)
+			continue
+		}
+		if targetDir.External != want {
+			t.Errorf("Expected target directory '%s' to have external=%v, got %v", targetDir.Path, want, targetDir.External)
+		}
+	}
+}
+
+func TestLoadConfigOutsideRepository(t *testing.T) {
+	// Without a repository, the external flag is kept as configured
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, ".airulesync.yaml")
+	content := `
+source_dirs:
+  - path: "."
+    files:
+      - ".clinerules"
+target_dirs:
+  - path: "../elsewhere"
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Chdir(tempDir)
+
+	if FindRepoRoot(tempDir) != "" {
+		t.Skip("Temporary directory is inside a git repository")
+	}
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.TargetDirs[0].External {
+		t.Errorf("Expected external to stay unset outside a repository")
+	}
+}
+
+func TestLoadConfigDetectsExternalTargetsFromOtherDirectory(t *testing.T) {
+	// The repository is found from the configuration file, not the working
+	// directory
+	tempDir := t.TempDir()
+	repoDir := filepath.Join(tempDir, "repo")
+	for _, dir := range []string{
+		filepath.Join(repoDir, ".git"),
+		filepath.Join(repoDir, "packages", "app"),
+		filepath.Join(tempDir, "sibling"),
+	} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+	}
+
+	configPath := filepath.Join(repoDir, ".airulesync.yaml")
+	content := `
+source_dirs:
+  - path: "."
+    files:
+      - ".clinerules"
+target_dirs:
+  - path: "./packages/app"
+  - path: "../sibling"
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Chdir(tempDir)
+
+	if FindRepoRoot(tempDir) != "" {
+		t.Skip("Temporary directory is inside a git repository")
+	}
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.TargetDirs[0].External {
+		t.Errorf("Expected target directory '%s' inside the repository not to be external", cfg.TargetDirs[0].Path)
+	}
+	if !cfg.TargetDirs[1].External {
+		t.Errorf("Expected target directory '%s' outside the repository to be external", cfg.TargetDirs[1].Path)
+	}
+}
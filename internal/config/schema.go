@@ -0,0 +1,34 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/invopop/jsonschema"
+)
+
+// SchemaTitle is the title of the JSON schema of the configuration file
+const SchemaTitle = "AIRuleSync Configuration Schema"
+
+// JSONSchema returns the JSON schema of the configuration file as indented JSON
+func JSONSchema() ([]byte, error) {
+	// Create a reflector
+	r := &jsonschema.Reflector{
+		RequiredFromJSONSchemaTags: true,
+		FieldNameTag:               "yaml", // Use yaml tag for field names
+	}
+
+	// Generate schema from Config struct
+	schema := r.Reflect(&Config{})
+
+	// Add schema metadata
+	schema.Title = SchemaTitle
+	schema.Description = "Schema for the AIRuleSync configuration file (.airulesync.yaml)"
+	schema.Version = "https://json-schema.org/draft/2020-12/schema"
+
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal schema to JSON: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,111 @@
+package frontmatter
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// delimiter is the line that opens and closes a front matter block
+const delimiter = "---"
+
+// Split splits content into the lines of its leading front matter block and
+// the remaining body. ok is false when the content has no front matter.
+func Split(content []byte) (lines []string, body []byte, ok bool) {
+	all := strings.SplitAfter(string(content), "\n")
+	if len(all) == 0 || strings.TrimRight(all[0], "\r\n") != delimiter {
+		return nil, content, false
+	}
+
+	for i := 1; i < len(all); i++ {
+		if strings.TrimRight(all[i], "\r\n") == delimiter {
+			for _, line := range all[1:i] {
+				lines = append(lines, strings.TrimRight(line, "\r\n"))
+			}
+			return lines, []byte(strings.Join(all[i+1:], "")), true
+		}
+	}
+
+	// An unterminated block is not front matter
+	return nil, content, false
+}
+
+// Join assembles front matter lines and a body back into file content
+func Join(lines []string, body []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(delimiter + "\n")
+	for _, line := range lines {
+		buf.WriteString(line)
+		buf.WriteString("\n")
+	}
+	buf.WriteString(delimiter + "\n")
+	buf.Write(body)
+	return buf.Bytes()
+}
+
+// Apply sets the given keys in the front matter of content, replacing existing
+// values and appending missing keys. Other lines are kept untouched. Content
+// without front matter is returned unchanged.
+func Apply(content []byte, overrides map[string]interface{}) ([]byte, error) {
+	if len(overrides) == 0 {
+		return content, nil
+	}
+
+	lines, body, ok := Split(content)
+	if !ok {
+		return content, nil
+	}
+
+	// Apply the overrides in a deterministic order
+	keys := make([]string, 0, len(overrides))
+	for key := range overrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		data, err := yaml.Marshal(map[string]interface{}{key: overrides[key]})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal front matter key %s: %w", key, err)
+		}
+		replacement := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+
+		start, end := findKey(lines, key)
+		if start < 0 {
+			lines = append(lines, replacement...)
+			continue
+		}
+
+		updated := append([]string{}, lines[:start]...)
+		updated = append(updated, replacement...)
+		lines = append(updated, lines[end:]...)
+	}
+
+	return Join(lines, body), nil
+}
+
+// findKey returns the line range [start, end) holding a top-level key and its
+// value, or -1 if the key is not present
+func findKey(lines []string, key string) (int, int) {
+	for i, line := range lines {
+		if !strings.HasPrefix(line, key+":") {
+			continue
+		}
+
+		// The value continues on indented lines and block sequence items
+		end := i + 1
+		for end < len(lines) && isContinuation(lines[end]) {
+			end++
+		}
+		return i, end
+	}
+	return -1, -1
+}
+
+// isContinuation checks if a line continues the value of the previous key
+func isContinuation(line string) bool {
+	return strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "- ")
+}
@@ -0,0 +1,59 @@
+package frontmatter
+
+import (
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	// Test cases for front matter overrides
+	testCases := []struct {
+		name      string
+		content   string
+		overrides map[string]interface{}
+		expected  string
+	}{
+		{
+			name:      "replace existing key",
+			content:   "---\ndescription: Go rules\nalwaysApply: false\n---\n# Body\n",
+			overrides: map[string]interface{}{"alwaysApply": true},
+			expected:  "---\ndescription: Go rules\nalwaysApply: true\n---\n# Body\n",
+		},
+		{
+			name:      "append missing key",
+			content:   "---\ndescription: Go rules\n---\n# Body\n",
+			overrides: map[string]interface{}{"alwaysApply": true},
+			expected:  "---\ndescription: Go rules\nalwaysApply: true\n---\n# Body\n",
+		},
+		{
+			name:      "replace block sequence value",
+			content:   "---\nglobs:\n  - \"*.go\"\n  - \"*.mod\"\nalwaysApply: false\n---\n",
+			overrides: map[string]interface{}{"globs": "*.ts"},
+			expected:  "---\nglobs: '*.ts'\nalwaysApply: false\n---\n",
+		},
+		{
+			name:      "content without front matter",
+			content:   "# Body\n",
+			overrides: map[string]interface{}{"alwaysApply": true},
+			expected:  "# Body\n",
+		},
+		{
+			name:      "unterminated front matter",
+			content:   "---\ndescription: Go rules\n",
+			overrides: map[string]interface{}{"alwaysApply": true},
+			expected:  "---\ndescription: Go rules\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := Apply([]byte(tc.content), tc.overrides)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if string(result) != tc.expected {
+				t.Errorf("Expected content:\n%s\n\nGot:\n%s", tc.expected, string(result))
+			}
+		})
+	}
+}
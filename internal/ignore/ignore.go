@@ -0,0 +1,198 @@
+// Package ignore implements matching of paths against ignore files using the
+// gitignore syntax.
+package ignore
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// File is the name of the repository-level ignore file
+const File = ".airulesyncignore"
+
+// rule is a single pattern of an ignore file
+type rule struct {
+	pattern string
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// Matcher matches paths against the rules of an ignore file. Paths are
+// matched relative to the directory of the ignore file.
+type Matcher struct {
+	base  string
+	rules []rule
+}
+
+// Load reads an ignore file. Paths are matched relative to its directory.
+func Load(path string) (*Matcher, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ignore file: %w", err)
+	}
+
+	base, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for ignore file: %w", err)
+	}
+
+	return Parse(base, data)
+}
+
+// Parse parses the content of an ignore file, matching paths relative to base
+func Parse(base string, data []byte) (*Matcher, error) {
+	m := &Matcher{base: base}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		r := rule{pattern: line}
+		if strings.HasPrefix(line, "!") {
+			r.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+
+		if strings.HasSuffix(line, "/") {
+			r.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		re, err := regexp.Compile(patternToRegexp(line))
+		if err != nil {
+			return nil, fmt.Errorf("invalid ignore pattern %s on line %d: %w", r.pattern, lineNum, err)
+		}
+		r.re = re
+		m.rules = append(m.rules, r)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ignore file: %w", err)
+	}
+
+	return m, nil
+}
+
+// Patterns returns the patterns of the rules in the order of the ignore file,
+// as written
+func (m *Matcher) Patterns() []string {
+	if m == nil {
+		return nil
+	}
+	patterns := make([]string, len(m.rules))
+	for i, r := range m.rules {
+		patterns[i] = r.pattern
+	}
+	return patterns
+}
+
+// patternToRegexp converts a gitignore pattern to a regular expression matching
+// slash-separated paths relative to the ignore file. Patterns without a slash
+// other than a trailing one match at any depth.
+func patternToRegexp(pattern string) string {
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	prefix := "^"
+	if !anchored {
+		prefix += "(?:.*/)?"
+	}
+	return prefix + GlobToRegexp(pattern) + "$"
+}
+
+// GlobToRegexp converts a glob pattern to an unanchored regular expression
+// matching slash-separated paths. Besides the wildcards of filepath.Match, a
+// "**/" matches any number of directories and any other "**" matches any
+// sequence of characters including slashes.
+func GlobToRegexp(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// Match returns the pattern ignoring a path, if any. A path is ignored when
+// the last rule matching it is not a negation, or when one of its parent
+// directories is ignored. Paths outside the base directory are never ignored.
+func (m *Matcher) Match(path string, isDir bool) (string, bool) {
+	if m == nil || len(m.rules) == 0 {
+		return "", false
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	relPath, err := filepath.Rel(m.base, absPath)
+	if err != nil || relPath == "." || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+
+	parts := strings.Split(filepath.ToSlash(relPath), "/")
+	for i := 1; i <= len(parts); i++ {
+		pattern, ignored := m.match(strings.Join(parts[:i], "/"), i < len(parts) || isDir)
+		if ignored {
+			return pattern, true
+		}
+	}
+
+	return "", false
+}
+
+// match applies the rules to a single relative path, the last matching rule winning
+func (m *Matcher) match(relPath string, isDir bool) (string, bool) {
+	pattern, ignored := "", false
+	for _, r := range m.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if r.re.MatchString(relPath) {
+			pattern, ignored = r.pattern, !r.negate
+		}
+	}
+	return pattern, ignored
+}
@@ -0,0 +1,63 @@
+package ignore
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	base := t.TempDir()
+	content := `# Backups
+*.bak
+!keep.bak
+
+/drafts
+build/
+docs/**/private.md
+\#notes.md
+`
+
+	m, err := Parse(base, []byte(content))
+	if err != nil {
+		t.Fatalf("Failed to parse ignore file: %v", err)
+	}
+
+	testCases := []struct {
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{path: "rules.bak", expected: true},
+		{path: "sub/rules.bak", expected: true},
+		{path: "keep.bak", expected: false},
+		{path: "sub/keep.bak", expected: false},
+		{path: "rules.md", expected: false},
+		{path: "drafts", expected: true},
+		{path: "drafts/rules.md", expected: true},
+		{path: "sub/drafts/rules.md", expected: false},
+		{path: "build", isDir: true, expected: true},
+		{path: "build", expected: false},
+		{path: "sub/build/out.md", expected: true},
+		{path: "docs/private.md", expected: true},
+		{path: "docs/a/b/private.md", expected: true},
+		{path: "other/private.md", expected: false},
+		{path: "#notes.md", expected: true},
+		{path: "../outside.bak", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			_, ignored := m.Match(filepath.Join(base, filepath.FromSlash(tc.path)), tc.isDir)
+			if ignored != tc.expected {
+				t.Errorf("Expected Match('%s') to be %v, got %v", tc.path, tc.expected, ignored)
+			}
+		})
+	}
+}
+
+func TestMatchNilMatcher(t *testing.T) {
+	var m *Matcher
+	if _, ignored := m.Match("rules.bak", false); ignored {
+		t.Errorf("Expected a nil matcher to ignore nothing")
+	}
+}
@@ -0,0 +1,134 @@
+// Package logging provides the leveled logger used for command output that
+// isn't part of a command's result, such as progress messages and warnings.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// New creates a logger writing plain lines to w. Verbose enables debug
+// records, and quiet only keeps warnings and errors.
+func New(w io.Writer, verbose, quiet bool) *slog.Logger {
+	return slog.New(NewHandler(w, Level(verbose, quiet)))
+}
+
+// Discard returns a logger dropping all records
+func Discard() *slog.Logger {
+	return slog.New(slog.DiscardHandler)
+}
+
+// Level returns the minimum level logged for the verbose and quiet flags
+func Level(verbose, quiet bool) slog.Level {
+	switch {
+	case quiet:
+		return slog.LevelWarn
+	case verbose:
+		return slog.LevelDebug
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// Handler is a slog.Handler writing each record as a single line: the
+// message, prefixed with the level for warnings and errors, followed by the
+// attributes as key=value pairs
+type Handler struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	level  slog.Leveler
+	attrs  []slog.Attr
+	prefix string
+}
+
+// NewHandler creates a handler writing records of at least the given level to w
+func NewHandler(w io.Writer, level slog.Leveler) *Handler {
+	return &Handler{
+		mu:    &sync.Mutex{},
+		w:     w,
+		level: level,
+	}
+}
+
+// Enabled reports whether records of the given level are written
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle writes a record
+func (h *Handler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	switch {
+	case r.Level >= slog.LevelError:
+		b.WriteString("Error: ")
+	case r.Level >= slog.LevelWarn:
+		b.WriteString("Warning: ")
+	}
+	b.WriteString(r.Message)
+
+	for _, attr := range h.attrs {
+		writeAttr(&b, "", attr)
+	}
+	r.Attrs(func(attr slog.Attr) bool {
+		writeAttr(&b, h.prefix, attr)
+		return true
+	})
+	b.WriteString("\n")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+// WithAttrs returns a handler adding the given attributes to every record
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append([]slog.Attr{}, h.attrs...)
+	for _, attr := range attrs {
+		attr.Key = h.prefix + attr.Key
+		clone.attrs = append(clone.attrs, attr)
+	}
+	return &clone
+}
+
+// WithGroup returns a handler qualifying the keys of later attributes with a group name
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.prefix = h.prefix + name + "."
+	return &clone
+}
+
+// writeAttr writes an attribute as a key=value pair, flattening groups
+func writeAttr(b *strings.Builder, prefix string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, groupAttr := range attr.Value.Group() {
+			writeAttr(b, prefix, groupAttr)
+		}
+		return
+	}
+
+	value := attr.Value.String()
+	if value == "" || strings.ContainsAny(value, " =\"\t\n") {
+		value = strconv.Quote(value)
+	}
+	b.WriteString(" ")
+	b.WriteString(prefix + attr.Key)
+	b.WriteString("=")
+	b.WriteString(value)
+}
@@ -0,0 +1,47 @@
+package logging
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	var out strings.Builder
+	logger := New(&out, false, false)
+
+	logger.Debug("Hidden debug message")
+	logger.Info("Configuration written", "path", ".airulesync.yaml")
+	logger.Warn("Failed to adjust path", "path", "./a b.md", "error", errors.New("boom"))
+	logger.With("source", "src").WithGroup("target").Error("Failed to synchronize", "path", "dst")
+
+	expected := "Configuration written path=.airulesync.yaml\n" +
+		"Warning: Failed to adjust path path=\"./a b.md\" error=boom\n" +
+		"Error: Failed to synchronize source=src target.path=dst\n"
+	if out.String() != expected {
+		t.Errorf("Expected output:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestLevel(t *testing.T) {
+	testCases := []struct {
+		name     string
+		verbose  bool
+		quiet    bool
+		expected slog.Level
+	}{
+		{name: "default", expected: slog.LevelInfo},
+		{name: "verbose", verbose: true, expected: slog.LevelDebug},
+		{name: "quiet", quiet: true, expected: slog.LevelWarn},
+		{name: "quiet wins over verbose", verbose: true, quiet: true, expected: slog.LevelWarn},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if level := Level(tc.verbose, tc.quiet); level != tc.expected {
+				t.Errorf("Expected level %v, got %v", tc.expected, level)
+			}
+		})
+	}
+}
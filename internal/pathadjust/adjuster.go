@@ -0,0 +1,51 @@
+package pathadjust
+
+// Adjuster adjusts references in a line with logic the built-in patterns
+// can't express, such as a project-specific reference syntax. Adjusters are
+// set on PathAdjuster.Adjusters and run on every line outside of front matter
+// and fenced code blocks.
+type Adjuster interface {
+	// Adjust returns the line with its references adjusted for the target
+	// directory and the adjustments made. A zero LineNumber in the results
+	// is set to the line number of ctx.
+	Adjust(line string, ctx AdjustContext) (string, []AdjustmentResult)
+}
+
+// AdjustContext describes the line passed to an Adjuster
+type AdjustContext struct {
+	LineNumber int
+	SourceDir  string
+	TargetDir  string
+
+	adjuster *PathAdjuster
+}
+
+// AdjustPath returns a path relative to the source directory rewritten
+// relative to the target directory, as the built-in patterns do
+func (c AdjustContext) AdjustPath(path string) (string, error) {
+	return c.adjuster.adjustPath(path, c.SourceDir, c.TargetDir)
+}
+
+// runAdjusters runs the custom adjusters on a line in order, each on the
+// output of the previous one
+func (p *PathAdjuster) runAdjusters(line string, lineNum int, sourceDir, targetDir string) (string, []AdjustmentResult) {
+	ctx := AdjustContext{
+		LineNumber: lineNum,
+		SourceDir:  sourceDir,
+		TargetDir:  targetDir,
+		adjuster:   p,
+	}
+
+	var adjustments []AdjustmentResult
+	for _, adjuster := range p.Adjusters {
+		adjustedLine, results := adjuster.Adjust(line, ctx)
+		for i := range results {
+			if results[i].LineNumber == 0 {
+				results[i].LineNumber = lineNum
+			}
+		}
+		line = adjustedLine
+		adjustments = append(adjustments, results...)
+	}
+	return line, adjustments
+}
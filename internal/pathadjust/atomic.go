@@ -0,0 +1,53 @@
+package pathadjust
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// writeTemp writes content to a temporary file. Tests replace it to inject
+// write errors.
+var writeTemp = func(f *os.File, content []byte) error {
+	_, err := f.Write(content)
+	return err
+}
+
+// writeFileAtomic writes a file by writing a temporary file in the same
+// directory and renaming it into place, so an interrupted write never leaves
+// a partially written file behind. Existing files keep their mode, and a
+// symlinked target is written through to the file it points to rather than
+// replaced by a regular file.
+func writeFileAtomic(name string, content []byte, perm os.FileMode) (err error) {
+	if resolved, evalErr := filepath.EvalSymlinks(name); evalErr == nil {
+		name = resolved
+	}
+
+	if info, statErr := os.Stat(name); statErr == nil {
+		perm = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".tmp-*")
+	if err != nil {
+		return err
+	}
+
+	// Remove the temporary file unless it was renamed into place
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err = writeTemp(tmp, content); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(perm); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
@@ -0,0 +1,53 @@
+package pathadjust
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+// Encoding is the encoding of file content as far as path adjustment cares
+type Encoding string
+
+// Detected encodings
+const (
+	// EncodingUTF8 is UTF-8 text, with or without a byte order mark, which
+	// is adjusted line by line
+	EncodingUTF8 Encoding = "UTF-8"
+	// EncodingUTF16LE and EncodingUTF16BE are UTF-16 text detected by its
+	// byte order mark
+	EncodingUTF16LE Encoding = "UTF-16LE"
+	EncodingUTF16BE Encoding = "UTF-16BE"
+	// EncodingBinary is content with NUL bytes near the start
+	EncodingBinary Encoding = "binary"
+	// EncodingUnknown is text in another encoding than UTF-8, such as Latin-1
+	EncodingUnknown Encoding = "unknown"
+)
+
+// utf8BOM is the byte order mark some editors put at the start of UTF-8 files
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// DetectEncoding returns the encoding of content. Only UTF-8 content can be
+// adjusted; content in other encodings must be copied as is.
+func DetectEncoding(content []byte) Encoding {
+	switch {
+	case bytes.HasPrefix(content, []byte{0xFF, 0xFE}):
+		return EncodingUTF16LE
+	case bytes.HasPrefix(content, []byte{0xFE, 0xFF}):
+		return EncodingUTF16BE
+	case IsBinary(content):
+		return EncodingBinary
+	case utf8.Valid(bytes.TrimPrefix(content, utf8BOM)):
+		return EncodingUTF8
+	default:
+		return EncodingUnknown
+	}
+}
+
+// SplitBOM splits the UTF-8 byte order mark off the start of content, if any.
+// Appending to the returned mark never overwrites content.
+func SplitBOM(content []byte) (bom, rest []byte) {
+	if bytes.HasPrefix(content, utf8BOM) {
+		return content[:len(utf8BOM):len(utf8BOM)], content[len(utf8BOM):]
+	}
+	return nil, content
+}
@@ -4,22 +4,64 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
-	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
+
+	"github.com/upamune/airulesync/internal/logging"
 )
 
 // PathAdjuster is responsible for adjusting paths in files
 type PathAdjuster struct {
 	Verbose bool
+	Logger  *slog.Logger
+
+	// PreserveSeparators keeps the backslashes of Windows-style relative
+	// paths such as .\sub\file.js in adjusted paths instead of normalizing
+	// them to forward slashes
+	PreserveSeparators bool
+
+	// ResolveSymlinks resolves symbolic links in the source and target
+	// directories before computing adjusted paths, so that paths are correct
+	// when either directory is reached through a symlink
+	ResolveSymlinks bool
+
+	// Extensions are the file extensions of quoted paths detected as general
+	// file paths, such as "./docs/guide.md" (default: DefaultExtensions)
+	Extensions []string
+
+	// AdjustGlobs adjusts the relative patterns of front matter globs such as
+	// ./src/**/*.ts like paths. Globs are match patterns and are left
+	// unchanged by default.
+	AdjustGlobs bool
+
+	// WriteAttempts is the number of times a target file write is attempted
+	// when it fails with a transient error such as EAGAIN or EBUSY
+	WriteAttempts int
+	// RetryDelay is the delay before the first retry of a failed write
+	RetryDelay time.Duration
+
+	// Adjusters are custom adjusters run on each line after the built-in
+	// patterns. NoBuiltinPatterns leaves lines to them alone.
+	Adjusters         []Adjuster
+	NoBuiltinPatterns bool
+
+	writeFile func(name string, data []byte, perm os.FileMode) error
+
+	// patterns caches the line patterns with the quoted path pattern of Extensions
+	patterns []pathPattern
 }
 
 // NewPathAdjuster creates a new path adjuster
 func NewPathAdjuster(verbose bool) *PathAdjuster {
 	return &PathAdjuster{
-		Verbose: verbose,
+		Verbose:       verbose,
+		Logger:        logging.Discard(),
+		WriteAttempts: DefaultWriteAttempts,
+		RetryDelay:    DefaultRetryDelay,
 	}
 }
 
@@ -28,6 +70,9 @@ type AdjustmentResult struct {
 	OriginalPath string
 	AdjustedPath string
 	LineNumber   int
+	// Matcher is the name of the detection pattern that found the path,
+	// such as markdown-link
+	Matcher string
 }
 
 // AdjustPaths adjusts paths in a file based on the relationship between source and target directories
@@ -38,24 +83,72 @@ func (p *PathAdjuster) AdjustPaths(sourceFile, targetFile, sourceDir, targetDir
 		return nil, fmt.Errorf("failed to read source file: %w", err)
 	}
 
+	// Copy binary and non-UTF-8 files as they are, as line processing would
+	// corrupt them
+	if encoding := DetectEncoding(content); encoding != EncodingUTF8 {
+		if encoding == EncodingBinary {
+			p.Logger.Warn("Copying binary file without adjusting paths", "path", sourceFile)
+		} else {
+			p.Logger.Warn("Copying non-UTF-8 file without adjusting paths", "path", sourceFile, "encoding", encoding)
+		}
+		if err := p.WriteFile(targetFile, content); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+
 	// Detect and adjust paths
+	adjustments, adjustedContent, err := p.AdjustContent(content, sourceDir, targetDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Write the adjusted content to the target file
+	if err := p.WriteFile(targetFile, adjustedContent); err != nil {
+		return nil, err
+	}
+
+	return adjustments, nil
+}
+
+// AdjustContent adjusts paths in content without reading or writing any file.
+// Binary and non-UTF-8 content is returned unchanged, and a UTF-8 byte order
+// mark is kept.
+func (p *PathAdjuster) AdjustContent(content []byte, sourceDir, targetDir string) ([]AdjustmentResult, []byte, error) {
+	if DetectEncoding(content) != EncodingUTF8 {
+		return nil, content, nil
+	}
+
+	bom, content := SplitBOM(content)
 	adjustments, adjustedContent, err := p.processContent(content, sourceDir, targetDir)
 	if err != nil {
-		return nil, fmt.Errorf("failed to process content: %w", err)
+		return nil, nil, fmt.Errorf("failed to process content: %w", err)
 	}
+	return adjustments, append(bom, adjustedContent...), nil
+}
+
+// binarySniffLen is the length of the content prefix searched for NUL bytes
+const binarySniffLen = 8000
 
+// IsBinary reports whether content looks binary, i.e. has a NUL byte near the start
+func IsBinary(content []byte) bool {
+	return bytes.IndexByte(content[:min(len(content), binarySniffLen)], 0) != -1
+}
+
+// WriteFile writes content to a target file, creating the target directory if needed
+func (p *PathAdjuster) WriteFile(targetFile string, content []byte) error {
 	// Ensure the target directory exists
 	targetDirPath := filepath.Dir(targetFile)
 	if err := os.MkdirAll(targetDirPath, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create target directory: %w", err)
+		return fmt.Errorf("failed to create target directory: %w", err)
 	}
 
-	// Write the adjusted content to the target file
-	if err := os.WriteFile(targetFile, adjustedContent, 0644); err != nil {
-		return nil, fmt.Errorf("failed to write target file: %w", err)
+	// Write the content to the target file
+	if err := p.writeWithRetry(targetFile, content); err != nil {
+		return fmt.Errorf("failed to write target file: %w", err)
 	}
 
-	return adjustments, nil
+	return nil
 }
 
 // processContent processes the content of a file and adjusts paths
@@ -65,10 +158,39 @@ func (p *PathAdjuster) processContent(content []byte, sourceDir, targetDir strin
 	scanner := bufio.NewScanner(bytes.NewReader(content))
 	lineNum := 0
 
+	// Front matter values are adjusted by key, as some keys hold match
+	// patterns or prose rather than file references
+	inFrontmatter := false
+	frontmatterKey := ""
+
+	// Content of Markdown fenced code blocks is kept as is, as it holds
+	// literal examples rather than references
+	fence := ""
+
 	for scanner.Scan() {
 		lineNum++
 		line := scanner.Text()
-		adjustedLine, lineAdjustments := p.adjustLine(line, lineNum, sourceDir, targetDir)
+
+		var adjustedLine string
+		var lineAdjustments []AdjustmentResult
+		switch {
+		case lineNum == 1 && line == frontmatterDelimiter:
+			inFrontmatter = true
+			adjustedLine = line
+		case inFrontmatter && line == frontmatterDelimiter:
+			inFrontmatter = false
+			adjustedLine = line
+		case fence != "" || isFence(line):
+			fence = nextFence(fence, line)
+			adjustedLine = line
+		case inFrontmatter:
+			if match := frontmatterKeyPattern.FindStringSubmatch(line); match != nil {
+				frontmatterKey = match[1]
+			}
+			adjustedLine, lineAdjustments = p.adjustFrontmatterLine(line, frontmatterKey, lineNum, sourceDir, targetDir)
+		default:
+			adjustedLine, lineAdjustments = p.adjustLine(line, lineNum, sourceDir, targetDir)
+		}
 		adjustments = append(adjustments, lineAdjustments...)
 		outputBuffer.WriteString(adjustedLine)
 		outputBuffer.WriteString("\n")
@@ -81,30 +203,175 @@ func (p *PathAdjuster) processContent(content []byte, sourceDir, targetDir strin
 	return adjustments, outputBuffer.Bytes(), nil
 }
 
-// adjustLine adjusts paths in a single line
+// isFence checks if a line opens or closes a Markdown fenced code block
+func isFence(line string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	return strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")
+}
+
+// nextFence returns the fence of the code block open after a line, given the
+// fence of the block open before it. A block is closed by a fence of the same
+// kind at least as long as its opening fence.
+func nextFence(fence, line string) string {
+	if !isFence(line) {
+		return fence
+	}
+
+	trimmed := strings.TrimLeft(line, " ")
+	info := strings.TrimLeft(trimmed, trimmed[:1])
+	current := trimmed[:len(trimmed)-len(info)]
+
+	if fence == "" {
+		return current
+	}
+	if strings.HasPrefix(current, fence) && strings.TrimSpace(info) == "" {
+		return ""
+	}
+	return fence
+}
+
+// frontmatterDelimiter is the line that opens and closes a front matter block
+const frontmatterDelimiter = "---"
+
+// frontmatterKeyPattern matches a top-level key of a front matter line
+var frontmatterKeyPattern = regexp.MustCompile(`^([\w-]+)\s*:`)
+
+// frontmatterValuePattern matches an unquoted relative path value of a front matter key
+var frontmatterValuePattern = pathPattern{"frontmatter-value", regexp.MustCompile(`^[\w-]+\s*:\s*([./]\S+)\s*$`)}
+
+// globFrontmatterKeys are front matter keys holding match patterns, such as the
+// globs of Cursor .mdc rules, which are only adjusted like paths with
+// AdjustGlobs
+var globFrontmatterKeys = map[string]bool{
+	"globs": true,
+}
+
+// literalFrontmatterKeys are front matter keys holding prose, such as the
+// description of Cursor .mdc rules, which is never adjusted
+var literalFrontmatterKeys = map[string]bool{
+	"description": true,
+}
+
+// frontmatterGlobPattern matches a relative pattern of a globs value, given as
+// a scalar, a comma-separated list, a flow sequence or a block sequence item
+var frontmatterGlobPattern = pathPattern{"frontmatter-glob", regexp.MustCompile(`(?:^|[\s\[,"':])(\.{1,2}/[^\s,"'\]]+)`)}
+
+// adjustFrontmatterLine adjusts paths in a line of a front matter block
+// belonging to the given top-level key
+func (p *PathAdjuster) adjustFrontmatterLine(line, key string, lineNum int, sourceDir, targetDir string) (string, []AdjustmentResult) {
+	if literalFrontmatterKeys[key] || p.NoBuiltinPatterns {
+		return line, nil
+	}
+
+	if globFrontmatterKeys[key] {
+		if !p.AdjustGlobs {
+			return line, nil
+		}
+		return p.adjustMatches(line, lineNum, []pathPattern{frontmatterGlobPattern}, sourceDir, targetDir)
+	}
+
+	adjustedLine, adjustments := p.adjustMatches(line, lineNum, []pathPattern{frontmatterValuePattern}, sourceDir, targetDir)
+	if len(adjustments) > 0 {
+		return adjustedLine, adjustments
+	}
+
+	return p.adjustMatches(line, lineNum, p.linePatterns(), sourceDir, targetDir)
+}
+
+// pathPattern is a named pattern detecting paths, with the path in its
+// first capturing group
+type pathPattern struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// DefaultExtensions are the file extensions of quoted paths detected as
+// general file paths when no extensions are configured
+var DefaultExtensions = []string{
+	"md", "mdc", "txt", "json", "yaml", "yml", "toml", "xml", "html", "css",
+	"js", "jsx", "ts", "tsx", "go", "py", "rb", "rs", "java", "kt", "swift",
+	"c", "cpp", "h", "hpp", "cs", "php", "sh",
+}
+
+// quotedPathPattern returns the pattern detecting quoted paths with one of
+// the given file extensions
+func quotedPathPattern(extensions []string) pathPattern {
+	quoted := make([]string, 0, len(extensions))
+	for _, ext := range extensions {
+		quoted = append(quoted, regexp.QuoteMeta(strings.TrimPrefix(ext, ".")))
+	}
+	return pathPattern{"quoted-path", regexp.MustCompile(`["']([./][^'"]+\.(?:` + strings.Join(quoted, "|") + `))["']`)}
+}
+
+// linePatterns are the patterns detecting paths in lines outside of front
+// matter, where the quoted path pattern is replaced by the one of the
+// configured extensions
+var linePatterns = []pathPattern{
+	// Import/require statements in various languages
+	{"import", regexp.MustCompile(`(import|from|require)\s+['"]([./][^'"]+)['"]`)},
+	// JSON/YAML path references
+	{"json-path", regexp.MustCompile(`["'](?:path|file|src|source|location|include)["']\s*:\s*["']([./][^'"]+)["']`)},
+	// File path references in configuration files
+	{"config-attribute", regexp.MustCompile(`(?:file|path|source|target|output|input)=["']([./][^'"]+)["']`)},
+	// Markdown links and references
+	{"markdown-link", regexp.MustCompile(`\[.*?\]\(([./][^)]+)\)`)},
+	// HTML href and src attributes
+	{"html-attribute", regexp.MustCompile(`(?:href|src)=["']([./][^'"]+)["']`)},
+	// Quoted elements of bracketed or comma-separated lists, such as
+	// ["./src/**", "./lib/**"]
+	{"array-element", regexp.MustCompile(`[\[,]\s*["']([./][^'"]+)["']`)},
+	// General file paths with common extensions
+	quotedPathPattern(DefaultExtensions),
+	// Single-quoted shell script paths in shell snippets and here-docs
+	{"shell-script", regexp.MustCompile(`'([./][^'\s]+\.(?:sh|bash|zsh))'`)},
+}
+
+// adjustLine adjusts paths in a single line with the built-in patterns and
+// the custom adjusters
 func (p *PathAdjuster) adjustLine(line string, lineNum int, sourceDir, targetDir string) (string, []AdjustmentResult) {
 	var adjustments []AdjustmentResult
-	adjustedLine := line
+	if !p.NoBuiltinPatterns {
+		line, adjustments = p.adjustMatches(line, lineNum, p.linePatterns(), sourceDir, targetDir)
+	}
+	if len(p.Adjusters) == 0 {
+		return line, adjustments
+	}
 
-	// Define patterns for path detection
-	patterns := []*regexp.Regexp{
-		// Import/require statements in various languages
-		regexp.MustCompile(`(import|from|require)\s+['"]([./][^'"]+)['"]`),
-		// JSON/YAML path references
-		regexp.MustCompile(`["'](?:path|file|src|source|location|include)["']\s*:\s*["']([./][^'"]+)["']`),
-		// File path references in configuration files
-		regexp.MustCompile(`(?:file|path|source|target|output|input)=["']([./][^'"]+)["']`),
-		// Markdown links and references
-		regexp.MustCompile(`\[.*?\]\(([./][^)]+)\)`),
-		// HTML href and src attributes
-		regexp.MustCompile(`(?:href|src)=["']([./][^'"]+)["']`),
-		// General file paths
-		regexp.MustCompile(`["']([./][^'"]+\.(md|txt|json|yaml|yml|js|ts|go|py|java|c|cpp|h|hpp|css|html|xml))["']`),
+	line, custom := p.runAdjusters(line, lineNum, sourceDir, targetDir)
+	return line, append(adjustments, custom...)
+}
+
+// linePatterns returns the line patterns detecting quoted paths with the
+// configured extensions
+func (p *PathAdjuster) linePatterns() []pathPattern {
+	if len(p.Extensions) == 0 {
+		return linePatterns
 	}
 
+	if p.patterns == nil {
+		p.patterns = make([]pathPattern, len(linePatterns))
+		for i, pattern := range linePatterns {
+			if pattern.name == "quoted-path" {
+				pattern = quotedPathPattern(p.Extensions)
+			}
+			p.patterns[i] = pattern
+		}
+	}
+	return p.patterns
+}
+
+// adjustMatches adjusts the paths matched by the given patterns in a single line
+func (p *PathAdjuster) adjustMatches(line string, lineNum int, patterns []pathPattern, sourceDir, targetDir string) (string, []AdjustmentResult) {
+	var adjustments []AdjustmentResult
+	adjustedLine := line
+
+	// Spans of the line holding adjusted paths, which are not adjusted again
+	// when another pattern matches them too
+	var adjustedSpans []span
+
 	for _, pattern := range patterns {
 		// Find all matches in the line
-		matches := pattern.FindAllStringSubmatchIndex(adjustedLine, -1)
+		matches := pattern.re.FindAllStringSubmatchIndex(adjustedLine, -1)
 
 		// Process matches in reverse order to avoid offset issues
 		for i := len(matches) - 1; i >= 0; i-- {
@@ -123,35 +390,51 @@ func (p *PathAdjuster) adjustLine(line string, lineNum int, sourceDir, targetDir
 				continue
 			}
 
+			if overlapsAny(adjustedSpans, pathStartIdx, pathEndIdx) {
+				continue
+			}
+
 			originalPath := adjustedLine[pathStartIdx:pathEndIdx]
 
+			// Treat backslashes as separators of Windows-style relative paths
+			path := originalPath
+			backslashed := isBackslashRelative(originalPath)
+			if backslashed {
+				path = strings.ReplaceAll(originalPath, `\`, "/")
+			}
+
 			// Skip paths that don't start with ./ or ../
-			if !strings.HasPrefix(originalPath, "./") && !strings.HasPrefix(originalPath, "../") {
+			if !strings.HasPrefix(path, "./") && !strings.HasPrefix(path, "../") {
 				continue
 			}
 
 			// Adjust the path
-			adjustedPath, err := p.adjustPath(originalPath, sourceDir, targetDir)
+			adjustedPath, err := p.adjustPath(path, sourceDir, targetDir)
 			if err != nil {
-				if p.Verbose {
-					fmt.Fprintf(os.Stderr, "Warning: Failed to adjust path %s: %v\n", originalPath, err)
-				}
+				p.Logger.Debug("Failed to adjust path", "path", originalPath, "error", err)
 				continue
 			}
+			if backslashed && p.PreserveSeparators {
+				adjustedPath = strings.ReplaceAll(adjustedPath, "/", `\`)
+			}
 
 			// Skip if the path didn't change
 			if adjustedPath == originalPath {
 				continue
 			}
 
-			// Replace the path in the line
+			// Replace the path in the line. Matches are processed in reverse
+			// order, so the offsets of the remaining matches stay valid, and
+			// spans after the path are moved by the change in length.
 			adjustedLine = adjustedLine[:pathStartIdx] + adjustedPath + adjustedLine[pathEndIdx:]
+			adjustedSpans = replaceSpan(adjustedSpans, pathStartIdx, pathEndIdx, len(adjustedPath))
 
 			// Record the adjustment
 			adjustments = append(adjustments, AdjustmentResult{
 				OriginalPath: originalPath,
 				AdjustedPath: adjustedPath,
 				LineNumber:   lineNum,
+				Matcher:      pattern.name,
 			})
 		}
 	}
@@ -159,6 +442,56 @@ func (p *PathAdjuster) adjustLine(line string, lineNum int, sourceDir, targetDir
 	return adjustedLine, adjustments
 }
 
+// span is a range of byte offsets in a line
+type span struct {
+	start, end int
+}
+
+// overlapsAny checks if the range from start to end overlaps any of the spans
+func overlapsAny(spans []span, start, end int) bool {
+	for _, s := range spans {
+		if start < s.end && s.start < end {
+			return true
+		}
+	}
+	return false
+}
+
+// replaceSpan records the replacement of the range from start to end with
+// text of the given length, moving the spans after it by the change in length
+func replaceSpan(spans []span, start, end, length int) []span {
+	delta := length - (end - start)
+	for i := range spans {
+		if spans[i].start >= end {
+			spans[i].start += delta
+			spans[i].end += delta
+		}
+	}
+	return append(spans, span{start, start + length})
+}
+
+// isBackslashRelative checks if a path is a Windows-style relative path
+// starting with .\ or ..\. Backslashes in other paths are left alone, as
+// outside of Windows they are escapes or file name characters.
+func isBackslashRelative(path string) bool {
+	return strings.HasPrefix(path, `.\`) || strings.HasPrefix(path, `..\`)
+}
+
+// resolveSymlinks returns an absolute path with symbolic links resolved. Paths
+// that don't exist yet, such as new target directories, are resolved up to
+// their deepest existing parent.
+func resolveSymlinks(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+
+	parent := filepath.Dir(path)
+	if parent == path {
+		return path
+	}
+	return filepath.Join(resolveSymlinks(parent), filepath.Base(path))
+}
+
 // adjustPath adjusts a single path based on the relationship between source and target directories
 func (p *PathAdjuster) adjustPath(path, sourceDir, targetDir string) (string, error) {
 	// Convert to absolute paths for calculation
@@ -172,6 +505,11 @@ func (p *PathAdjuster) adjustPath(path, sourceDir, targetDir string) (string, er
 		return "", fmt.Errorf("failed to get absolute path for target directory: %w", err)
 	}
 
+	if p.ResolveSymlinks {
+		absSourceDir = resolveSymlinks(absSourceDir)
+		absTargetDir = resolveSymlinks(absTargetDir)
+	}
+
 	// Resolve the original path relative to the source directory
 	originalAbsPath := filepath.Join(absSourceDir, path)
 
@@ -191,12 +529,11 @@ func (p *PathAdjuster) adjustPath(path, sourceDir, targetDir string) (string, er
 
 // CopyFile copies a file without adjusting paths
 func (p *PathAdjuster) CopyFile(sourceFile, targetFile string) error {
-	// Open the source file
-	src, err := os.Open(sourceFile)
+	// Read the source file
+	content, err := os.ReadFile(sourceFile)
 	if err != nil {
 		return fmt.Errorf("failed to open source file: %w", err)
 	}
-	defer src.Close()
 
 	// Create the target directory if it doesn't exist
 	targetDir := filepath.Dir(targetFile)
@@ -204,15 +541,8 @@ func (p *PathAdjuster) CopyFile(sourceFile, targetFile string) error {
 		return fmt.Errorf("failed to create target directory: %w", err)
 	}
 
-	// Create the target file
-	dst, err := os.Create(targetFile)
-	if err != nil {
-		return fmt.Errorf("failed to create target file: %w", err)
-	}
-	defer dst.Close()
-
-	// Copy the content
-	if _, err := io.Copy(dst, src); err != nil {
+	// Write the content
+	if err := p.writeWithRetry(targetFile, content); err != nil {
 		return fmt.Errorf("failed to copy file content: %w", err)
 	}
 
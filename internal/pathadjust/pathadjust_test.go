@@ -1,10 +1,14 @@
 package pathadjust
 
 import (
+	"bytes"
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"testing"
+	"time"
 )
 
 func TestAdjustPath(t *testing.T) {
@@ -196,6 +200,536 @@ src="./image.png"
 	}
 }
 
+func TestAdjustContentWithSingleQuotedShellPaths(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDir := filepath.Join(tempDir, "target")
+
+	// Shell snippet referencing sibling scripts with single quotes
+	content := `Run the setup script:
+
+source './scripts/env.sh'
+bash '../tools/lint.bash' --fix
+cat <<'END'
+'./scripts/deploy.sh'
+END
+`
+
+	adjuster := NewPathAdjuster(false)
+	adjustments, adjusted, err := adjuster.AdjustContent([]byte(content), sourceDir, targetDir)
+	if err != nil {
+		t.Fatalf("Failed to adjust content: %v", err)
+	}
+
+	expectedPaths := map[string]string{
+		"./scripts/env.sh":    "../source/scripts/env.sh",
+		"./scripts/deploy.sh": "../source/scripts/deploy.sh",
+	}
+
+	for original, expected := range expectedPaths {
+		if !contains(string(adjusted), "'"+expected+"'") {
+			t.Errorf("Expected '%s' to be adjusted to '%s', got:\n%s", original, expected, adjusted)
+		}
+	}
+
+	// Paths resolving to the same location from the target are kept
+	if !contains(string(adjusted), "'../tools/lint.bash'") {
+		t.Errorf("Expected '../tools/lint.bash' to be kept, got:\n%s", adjusted)
+	}
+
+	// The here-doc delimiter is not a path
+	if !contains(string(adjusted), "cat <<'END'") {
+		t.Errorf("Expected here-doc delimiter to be kept, got:\n%s", adjusted)
+	}
+
+	if len(adjustments) != 2 {
+		t.Errorf("Expected 2 adjustments, got %d: %v", len(adjustments), adjustments)
+	}
+}
+
+func TestAdjustContentWithBackslashPaths(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDir := filepath.Join(tempDir, "target")
+
+	// Windows-style relative paths next to a backslash escape that isn't a path
+	content := `import util from '.\lib\util.js'
+See [the guide](..\docs\guide.md) and "./src/main.js".
+printf 'a\tb'
+`
+
+	// Test cases for both separator conventions
+	testCases := []struct {
+		name               string
+		preserveSeparators bool
+		expected           []string
+		adjustments        int
+	}{
+		{
+			name:               "normalize to forward slashes",
+			preserveSeparators: false,
+			expected: []string{
+				`'../source/lib/util.js'`,
+				`(../docs/guide.md)`,
+				`"../source/src/main.js"`,
+			},
+			adjustments: 3,
+		},
+		{
+			name:               "preserve backslashes",
+			preserveSeparators: true,
+			expected: []string{
+				`'..\source\lib\util.js'`,
+				`(..\docs\guide.md)`,
+				`"../source/src/main.js"`,
+			},
+			adjustments: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			adjuster := NewPathAdjuster(false)
+			adjuster.PreserveSeparators = tc.preserveSeparators
+
+			adjustments, adjusted, err := adjuster.AdjustContent([]byte(content), sourceDir, targetDir)
+			if err != nil {
+				t.Fatalf("Failed to adjust content: %v", err)
+			}
+
+			for _, expected := range tc.expected {
+				if !contains(string(adjusted), expected) {
+					t.Errorf("Expected adjusted content to contain %s, got:\n%s", expected, adjusted)
+				}
+			}
+
+			// Backslashes outside of relative paths are kept
+			if !contains(string(adjusted), `printf 'a\tb'`) {
+				t.Errorf("Expected escape sequence to be kept, got:\n%s", adjusted)
+			}
+
+			if len(adjustments) != tc.adjustments {
+				t.Errorf("Expected %d adjustments, got %d: %v", tc.adjustments, len(adjustments), adjustments)
+			}
+		})
+	}
+}
+
+func TestAdjustContentReportsMatcher(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDir := filepath.Join(tempDir, "target")
+
+	// Each line is detected by a different pattern
+	content := `---
+globs: ./src/**/*.ts
+---
+See [the guide](./docs/guide.md).
+<a href="./index.html">Index</a>
+Run './scripts/setup.zsh' first.
+`
+
+	adjuster := NewPathAdjuster(false)
+	adjuster.AdjustGlobs = true
+	adjustments, _, err := adjuster.AdjustContent([]byte(content), sourceDir, targetDir)
+	if err != nil {
+		t.Fatalf("Failed to adjust content: %v", err)
+	}
+
+	expected := map[string]string{
+		"./src/**/*.ts":       "frontmatter-glob",
+		"./docs/guide.md":     "markdown-link",
+		"./index.html":        "html-attribute",
+		"./scripts/setup.zsh": "shell-script",
+	}
+	if len(adjustments) != len(expected) {
+		t.Fatalf("Expected %d adjustments, got %+v", len(expected), adjustments)
+	}
+	for _, adj := range adjustments {
+		if adj.Matcher != expected[adj.OriginalPath] {
+			t.Errorf("Expected adjustment of %s to be tagged %q, got %q", adj.OriginalPath, expected[adj.OriginalPath], adj.Matcher)
+		}
+	}
+}
+
+func TestAdjustContentWithExtensions(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDir := filepath.Join(tempDir, "target")
+
+	content := "Entry point: \"./src/main.rs\"\n"
+
+	// Test cases for extension sets with and without .rs
+	testCases := []struct {
+		name       string
+		extensions []string
+		expected   string
+	}{
+		{
+			name:     "default extensions",
+			expected: "Entry point: \"../source/src/main.rs\"\n",
+		},
+		{
+			name:       "extension in the set",
+			extensions: []string{".md", ".rs"},
+			expected:   "Entry point: \"../source/src/main.rs\"\n",
+		},
+		{
+			name:       "extension not in the set",
+			extensions: []string{"md", "json"},
+			expected:   content,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			adjuster := NewPathAdjuster(false)
+			adjuster.Extensions = tc.extensions
+
+			_, adjusted, err := adjuster.AdjustContent([]byte(content), sourceDir, targetDir)
+			if err != nil {
+				t.Fatalf("Failed to adjust content: %v", err)
+			}
+			if string(adjusted) != tc.expected {
+				t.Errorf("Expected content %q, got %q", tc.expected, adjusted)
+			}
+		})
+	}
+}
+
+func TestAdjustContentWithPathLists(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "rules", "shared")
+	targetDir := filepath.Join(tempDir, "services")
+
+	// Test cases for lists with several paths on one line
+	testCases := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			name:     "front matter globs",
+			content:  "---\nglobs: [\"./src/**\", \"./lib/**\"]\n---\n",
+			expected: "---\nglobs: [\"../rules/shared/src/**\", \"../rules/shared/lib/**\"]\n---\n",
+		},
+		{
+			name:     "front matter value",
+			content:  "---\ninclude: [\"./src/**\", './lib/**']\n---\n",
+			expected: "---\ninclude: [\"../rules/shared/src/**\", '../rules/shared/lib/**']\n---\n",
+		},
+		{
+			name:     "body with mixed elements",
+			content:  "files = [\"./a.md\", \"./b\", \"plain\", \"../c/d.json\"]\n",
+			expected: "files = [\"../rules/shared/a.md\", \"../rules/shared/b\", \"plain\", \"../rules/c/d.json\"]\n",
+		},
+		{
+			name:     "path matched by several patterns",
+			content:  "{\"path\": \"./x.md\", \"extra\": [\"./y.md\"]}\n",
+			expected: "{\"path\": \"../rules/shared/x.md\", \"extra\": [\"../rules/shared/y.md\"]}\n",
+		},
+	}
+
+	// Front matter globs are only adjusted when enabled
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			adjuster := NewPathAdjuster(false)
+			adjuster.AdjustGlobs = true
+			_, adjusted, err := adjuster.AdjustContent([]byte(tc.content), sourceDir, targetDir)
+			if err != nil {
+				t.Fatalf("Failed to adjust content: %v", err)
+			}
+			if string(adjusted) != tc.expected {
+				t.Errorf("Expected content %q, got %q", tc.expected, adjusted)
+			}
+		})
+	}
+}
+
+func TestAdjustContentWithResolveSymlinks(t *testing.T) {
+	// The target is reached through a symlink to a directory elsewhere
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "repo", "source")
+	realDir := filepath.Join(tempDir, "shared", "packages")
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(realDir, "app"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.Symlink(realDir, filepath.Join(tempDir, "repo", "packages")); err != nil {
+		t.Skipf("Symlinks not supported: %v", err)
+	}
+	targetDir := filepath.Join(tempDir, "repo", "packages", "app")
+
+	content := "See [guide](./docs/guide.md)\n"
+
+	// Test cases for the option off and on
+	testCases := []struct {
+		name            string
+		resolveSymlinks bool
+		expected        string
+	}{
+		{
+			name:     "lexical paths",
+			expected: "See [guide](../../source/docs/guide.md)\n",
+		},
+		{
+			name:            "resolved symlinks",
+			resolveSymlinks: true,
+			expected:        "See [guide](../../../repo/source/docs/guide.md)\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			adjuster := NewPathAdjuster(false)
+			adjuster.ResolveSymlinks = tc.resolveSymlinks
+
+			_, adjusted, err := adjuster.AdjustContent([]byte(content), sourceDir, targetDir)
+			if err != nil {
+				t.Fatalf("Failed to adjust content: %v", err)
+			}
+			if string(adjusted) != tc.expected {
+				t.Errorf("Expected content %q, got %q", tc.expected, adjusted)
+			}
+		})
+	}
+}
+
+func TestAdjustContentWithEncodings(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDir := filepath.Join(tempDir, "target")
+
+	// Test cases for content in different encodings
+	testCases := []struct {
+		name     string
+		content  string
+		encoding Encoding
+		expected string
+	}{
+		{
+			name:     "UTF-8",
+			content:  "See [guide](./docs/guide.md)\n",
+			encoding: EncodingUTF8,
+			expected: "See [guide](../source/docs/guide.md)\n",
+		},
+		{
+			name:     "UTF-8 with BOM",
+			content:  "\xEF\xBB\xBFSee [guide](./docs/guide.md)\n",
+			encoding: EncodingUTF8,
+			expected: "\xEF\xBB\xBFSee [guide](../source/docs/guide.md)\n",
+		},
+		{
+			name:     "UTF-16 big endian",
+			content:  "\xFE\xFF\x00[\x00.\x00/\x00a\x00]",
+			encoding: EncodingUTF16BE,
+			expected: "\xFE\xFF\x00[\x00.\x00/\x00a\x00]",
+		},
+		{
+			name:     "Latin-1",
+			content:  "Caf\xe9 [guide](./docs/guide.md)\n",
+			encoding: EncodingUnknown,
+			expected: "Caf\xe9 [guide](./docs/guide.md)\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if encoding := DetectEncoding([]byte(tc.content)); encoding != tc.encoding {
+				t.Errorf("Expected encoding %s, got %s", tc.encoding, encoding)
+			}
+
+			adjuster := NewPathAdjuster(false)
+			_, adjusted, err := adjuster.AdjustContent([]byte(tc.content), sourceDir, targetDir)
+			if err != nil {
+				t.Fatalf("Failed to adjust content: %v", err)
+			}
+			if string(adjusted) != tc.expected {
+				t.Errorf("Expected content %q, got %q", tc.expected, adjusted)
+			}
+		})
+	}
+}
+
+func TestAdjustContentKeepsFrontmatterGlobs(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDir := filepath.Join(tempDir, "target")
+
+	// Cursor rule with match patterns and a file reference in its front matter
+	content := `---
+description: Source rules
+globs: ["./src/**"]
+alwaysApply: false
+template: ./x.md
+---
+See "./docs/guide.md"
+`
+
+	adjuster := NewPathAdjuster(false)
+	_, adjusted, err := adjuster.AdjustContent([]byte(content), sourceDir, targetDir)
+	if err != nil {
+		t.Fatalf("Failed to adjust content: %v", err)
+	}
+
+	expectedLines := []string{
+		`globs: ["./src/**"]`,
+		"template: ../source/x.md",
+		`See "../source/docs/guide.md"`,
+	}
+
+	for _, expected := range expectedLines {
+		if !contains(string(adjusted), expected+"\n") {
+			t.Errorf("Expected adjusted content to contain '%s', got:\n%s", expected, adjusted)
+		}
+	}
+}
+
+func TestAdjustContentFrontmatter(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDir := filepath.Join(tempDir, "target")
+
+	// Cursor rule with relative globs, a description mentioning a literal
+	// path and a file reference in its front matter
+	content := `---
+description: Run ./scripts/check.sh from "./docs/guide.md" before committing
+globs: ["./src/**/*.ts", "*.md"]
+alwaysApply: false
+template: ./x.md
+---
+See "./docs/guide.md"
+`
+
+	testCases := []struct {
+		name     string
+		globs    string
+		expected string
+	}{
+		{
+			name:     "flow sequence",
+			globs:    `globs: ["./src/**/*.ts", "*.md"]`,
+			expected: `globs: ["../source/src/**/*.ts", "*.md"]`,
+		},
+		{
+			name:     "comma-separated",
+			globs:    `globs: ./src/**/*.ts,../shared/*.go, *.md`,
+			expected: `globs: ../source/src/**/*.ts,../shared/*.go, *.md`,
+		},
+		{
+			name:     "block sequence",
+			globs:    "globs:\n  - ./src/**/*.ts\n  - '*.md'",
+			expected: "globs:\n  - ../source/src/**/*.ts\n  - '*.md'",
+		},
+	}
+
+	adjuster := NewPathAdjuster(false)
+	adjuster.AdjustGlobs = true
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			input := strings.Replace(content, `globs: ["./src/**/*.ts", "*.md"]`, tc.globs, 1)
+			_, adjusted, err := adjuster.AdjustContent([]byte(input), sourceDir, targetDir)
+			if err != nil {
+				t.Fatalf("Failed to adjust content: %v", err)
+			}
+
+			expectedLines := []string{
+				`description: Run ./scripts/check.sh from "./docs/guide.md" before committing`,
+				tc.expected,
+				"template: ../source/x.md",
+				`See "../source/docs/guide.md"`,
+			}
+
+			for _, expected := range expectedLines {
+				if !contains(string(adjusted), expected+"\n") {
+					t.Errorf("Expected adjusted content to contain '%s', got:\n%s", expected, adjusted)
+				}
+			}
+		})
+	}
+}
+
+func TestAdjustContentSkipsFencedCodeBlocks(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDir := filepath.Join(tempDir, "target")
+
+	// Rule file with literal paths in code examples
+	content := "Follow [the guide](./guide.md).\n" +
+		"\n" +
+		"```js\n" +
+		"import foo from \"./foo.js\"\n" +
+		"```\n" +
+		"\n" +
+		"~~~~\n" +
+		"```\n" +
+		"\"./nested.md\"\n" +
+		"~~~~\n" +
+		"\n" +
+		"See \"./after.md\" for details.\n"
+
+	adjuster := NewPathAdjuster(false)
+	adjustments, adjusted, err := adjuster.AdjustContent([]byte(content), sourceDir, targetDir)
+	if err != nil {
+		t.Fatalf("Failed to adjust content: %v", err)
+	}
+
+	expectedLines := []string{
+		"Follow [the guide](../source/guide.md).",
+		"import foo from \"./foo.js\"",
+		"\"./nested.md\"",
+		"See \"../source/after.md\" for details.",
+	}
+
+	for _, expected := range expectedLines {
+		if !contains(string(adjusted), expected+"\n") {
+			t.Errorf("Expected adjusted content to contain '%s', got:\n%s", expected, adjusted)
+		}
+	}
+
+	if len(adjustments) != 2 {
+		t.Errorf("Expected 2 adjustments outside code blocks, got %d: %v", len(adjustments), adjustments)
+	}
+}
+
+func TestAdjustPathsCopiesBinaryFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDir := filepath.Join(tempDir, "target")
+
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+
+	// Binary content with NUL bytes, path-like strings and mixed line endings
+	content := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\"./image.png\"\r\n\xff\xfe\x00[x](./a.md)\n\x00")
+	sourceFile := filepath.Join(sourceDir, "logo.png")
+	targetFile := filepath.Join(targetDir, "logo.png")
+	if err := os.WriteFile(sourceFile, content, 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	adjuster := NewPathAdjuster(false)
+	adjustments, err := adjuster.AdjustPaths(sourceFile, targetFile, sourceDir, targetDir)
+	if err != nil {
+		t.Fatalf("Failed to adjust paths: %v", err)
+	}
+
+	if len(adjustments) != 0 {
+		t.Errorf("Expected no adjustments in a binary file, got %v", adjustments)
+	}
+
+	copied, err := os.ReadFile(targetFile)
+	if err != nil {
+		t.Fatalf("Failed to read copied file: %v", err)
+	}
+	if !bytes.Equal(copied, content) {
+		t.Errorf("Expected binary file to be copied byte-identical, got %q", copied)
+	}
+}
+
 func TestCopyFile(t *testing.T) {
 	// Create a temporary directory structure for testing
 	tempDir := t.TempDir()
@@ -246,6 +780,78 @@ func TestCopyFile(t *testing.T) {
 	}
 }
 
+func TestWriteFileRetriesTransientErrors(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testCases := []struct {
+		name          string
+		failures      []error
+		expectedCalls int
+		shouldError   bool
+	}{
+		{
+			name:          "succeeds after transient failures",
+			failures:      []error{syscall.EAGAIN, syscall.EBUSY},
+			expectedCalls: 3,
+		},
+		{
+			name:          "gives up after all attempts",
+			failures:      []error{syscall.EAGAIN, syscall.EAGAIN, syscall.EAGAIN},
+			expectedCalls: 3,
+			shouldError:   true,
+		},
+		{
+			name:          "does not retry permission denied",
+			failures:      []error{os.ErrPermission},
+			expectedCalls: 1,
+			shouldError:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			adjuster := NewPathAdjuster(false)
+			adjuster.RetryDelay = time.Millisecond
+			adjuster.writeFile = func(name string, data []byte, perm os.FileMode) error {
+				calls++
+				if calls <= len(tc.failures) {
+					return &os.PathError{Op: "write", Path: name, Err: tc.failures[calls-1]}
+				}
+				return os.WriteFile(name, data, perm)
+			}
+
+			targetFile := filepath.Join(tempDir, strings.ReplaceAll(tc.name, " ", "-"), "rules.md")
+			err := adjuster.WriteFile(targetFile, []byte("# Rules\n"))
+
+			if calls != tc.expectedCalls {
+				t.Errorf("Expected %d write attempts, got %d", tc.expectedCalls, calls)
+			}
+
+			if tc.shouldError {
+				if err == nil {
+					t.Errorf("Expected error, but got nil")
+				} else if !errors.Is(err, tc.failures[len(tc.failures)-1]) {
+					t.Errorf("Expected final error to wrap %v, got %v", tc.failures[len(tc.failures)-1], err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			content, err := os.ReadFile(targetFile)
+			if err != nil {
+				t.Fatalf("Failed to read target file: %v", err)
+			}
+			if string(content) != "# Rules\n" {
+				t.Errorf("Expected written content '# Rules', got '%s'", content)
+			}
+		})
+	}
+}
+
 func TestIsExternalPath(t *testing.T) {
 	// Create a path adjuster
 	adjuster := NewPathAdjuster(false)
@@ -287,3 +893,132 @@ func TestIsExternalPath(t *testing.T) {
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && strings.Contains(s, substr)
 }
+
+func TestWriteFileIsAtomic(t *testing.T) {
+	// Inject a write error after part of the content was written
+	originalWriteTemp := writeTemp
+	defer func() { writeTemp = originalWriteTemp }()
+	writeTemp = func(f *os.File, content []byte) error {
+		if _, err := f.Write(content[:len(content)/2]); err != nil {
+			return err
+		}
+		return errors.New("disk full")
+	}
+
+	testCases := []struct {
+		name     string
+		existing string
+	}{
+		{
+			name: "new target",
+		},
+		{
+			name:     "existing target",
+			existing: "# Old rules\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			targetDir := t.TempDir()
+			targetFile := filepath.Join(targetDir, "rules.md")
+			if tc.existing != "" {
+				if err := os.WriteFile(targetFile, []byte(tc.existing), 0644); err != nil {
+					t.Fatalf("Failed to write existing target: %v", err)
+				}
+			}
+
+			adjuster := NewPathAdjuster(false)
+			if err := adjuster.WriteFile(targetFile, []byte("# New rules with more content\n")); err == nil {
+				t.Fatalf("Expected error, but got nil")
+			}
+
+			// The target is either missing or untouched
+			content, err := os.ReadFile(targetFile)
+			if tc.existing == "" && !os.IsNotExist(err) {
+				t.Errorf("Expected no target file, got content %q (err=%v)", content, err)
+			}
+			if tc.existing != "" && string(content) != tc.existing {
+				t.Errorf("Expected target to keep %q, got %q (err=%v)", tc.existing, content, err)
+			}
+
+			// The temporary file was removed
+			entries, err := os.ReadDir(targetDir)
+			if err != nil {
+				t.Fatalf("Failed to read target directory: %v", err)
+			}
+			for _, entry := range entries {
+				if entry.Name() != "rules.md" {
+					t.Errorf("Expected temporary file to be removed, found '%s'", entry.Name())
+				}
+			}
+		})
+	}
+}
+
+func TestWriteFileKeepsMode(t *testing.T) {
+	targetDir := t.TempDir()
+	newFile := filepath.Join(targetDir, "new.md")
+	existingFile := filepath.Join(targetDir, "existing.md")
+	if err := os.WriteFile(existingFile, []byte("# Old\n"), 0600); err != nil {
+		t.Fatalf("Failed to write existing target: %v", err)
+	}
+	if err := os.Chmod(existingFile, 0600); err != nil {
+		t.Fatalf("Failed to change mode: %v", err)
+	}
+
+	adjuster := NewPathAdjuster(false)
+	expected := map[string]os.FileMode{
+		newFile:      0644,
+		existingFile: 0600,
+	}
+	for path, mode := range expected {
+		if err := adjuster.WriteFile(path, []byte("# New\n")); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("Failed to stat file: %v", err)
+		}
+		if info.Mode().Perm() != mode {
+			t.Errorf("Expected '%s' to have mode %v, got %v", filepath.Base(path), mode, info.Mode().Perm())
+		}
+	}
+}
+
+func TestWriteFileKeepsSymlink(t *testing.T) {
+	tempDir := t.TempDir()
+	realFile := filepath.Join(tempDir, "shared", "rules.md")
+	if err := os.MkdirAll(filepath.Dir(realFile), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(realFile, []byte("# Old\n"), 0644); err != nil {
+		t.Fatalf("Failed to write real file: %v", err)
+	}
+	link := filepath.Join(tempDir, "rules.md")
+	if err := os.Symlink(realFile, link); err != nil {
+		t.Skipf("Symlinks not supported: %v", err)
+	}
+
+	adjuster := NewPathAdjuster(false)
+	if err := adjuster.WriteFile(link, []byte("# New\n")); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	// The link is kept and the file it points to is updated
+	info, err := os.Lstat(link)
+	if err != nil {
+		t.Fatalf("Failed to stat link: %v", err)
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Expected '%s' to stay a symlink", link)
+	}
+	content, err := os.ReadFile(realFile)
+	if err != nil {
+		t.Fatalf("Failed to read real file: %v", err)
+	}
+	if string(content) != "# New\n" {
+		t.Errorf("Expected real file to contain %q, got %q", "# New\n", content)
+	}
+}
@@ -0,0 +1,50 @@
+package pathadjust
+
+import (
+	"errors"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultWriteAttempts is the number of times a write is attempted before giving up
+	DefaultWriteAttempts = 3
+
+	// DefaultRetryDelay is the delay before the first retry, doubled for each further retry
+	DefaultRetryDelay = 50 * time.Millisecond
+)
+
+// writeWithRetry writes a file, retrying transient failures with exponential backoff
+func (p *PathAdjuster) writeWithRetry(name string, content []byte) error {
+	write := p.writeFile
+	if write == nil {
+		write = writeFileAtomic
+	}
+
+	attempts := p.WriteAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	delay := p.RetryDelay
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = write(name, content, 0644); err == nil || !isTransient(err) {
+			return err
+		}
+		if attempt == attempts {
+			break
+		}
+
+		p.Logger.Debug("Retrying write", "path", name, "attempt", attempt, "error", err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+
+	return err
+}
+
+// isTransient reports whether a write error may succeed when retried
+func isTransient(err error) bool {
+	return errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EBUSY)
+}
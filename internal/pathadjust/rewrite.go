@@ -0,0 +1,30 @@
+package pathadjust
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Rewrite is a regular expression replacement applied to each line of content
+type Rewrite struct {
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// RewriteContent applies rewrites in order to each line of content. The
+// replacement may refer to capture groups of the pattern, such as $1.
+func RewriteContent(content []byte, rewrites []Rewrite) []byte {
+	if len(rewrites) == 0 {
+		return content
+	}
+
+	lines := strings.Split(string(content), "\n")
+	for i, line := range lines {
+		for _, rewrite := range rewrites {
+			line = rewrite.Pattern.ReplaceAllString(line, rewrite.Replacement)
+		}
+		lines[i] = line
+	}
+
+	return []byte(strings.Join(lines, "\n"))
+}
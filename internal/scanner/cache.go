@@ -0,0 +1,230 @@
+package scanner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/upamune/airulesync/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// CacheFile is the name of the cache of source directory scan results
+const CacheFile = ".airulesync.cache"
+
+// cacheHeader is written at the top of cache files
+const cacheHeader = "# Generated by airulesync sync --cache. Do not edit.\n"
+
+// scanCache holds the scan results of source directories by path
+type scanCache struct {
+	SourceDirs map[string]cacheEntry `yaml:"source_dirs"`
+}
+
+// cacheEntry is the scan result of a source directory, valid while its
+// configuration and the modification times of the directories it was
+// scanned from are unchanged
+type cacheEntry struct {
+	Key      string           `yaml:"key"`
+	ModTimes map[string]int64 `yaml:"mod_times"`
+	Matches  [][]fileMatch    `yaml:"matches"`
+}
+
+// loadCache reads the scan cache, starting with an empty one when the file
+// doesn't exist or can't be parsed. A missing file is created right away, so
+// that creating it doesn't change the modification time of a directory
+// scanned afterwards.
+func (s *Scanner) loadCache() error {
+	s.cache = &scanCache{SourceDirs: make(map[string]cacheEntry)}
+
+	data, err := os.ReadFile(s.CachePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return s.saveCache()
+	} else if err != nil {
+		return fmt.Errorf("failed to read cache file: %w", err)
+	}
+
+	var cache scanCache
+	if err := yaml.Unmarshal(data, &cache); err != nil {
+		s.debug("Ignoring unreadable cache file", "path", s.CachePath, "error", err)
+		return nil
+	}
+	if cache.SourceDirs != nil {
+		s.cache = &cache
+	}
+	return nil
+}
+
+// saveCache writes the scan cache
+func (s *Scanner) saveCache() error {
+	data, err := yaml.Marshal(s.cache)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache: %w", err)
+	}
+
+	if err := os.WriteFile(s.CachePath, append([]byte(cacheHeader), data...), 0644); err != nil {
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	return nil
+}
+
+// matchSourceDir finds the files matching each file spec of a source
+// directory, reusing the cached result when nothing it depends on changed
+func (s *Scanner) matchSourceDir(sourceDir config.SourceDir) ([][]fileMatch, error) {
+	var key string
+	if s.cache != nil {
+		key = s.cacheKey(sourceDir)
+		if entry, ok := s.cache.SourceDirs[sourceDir.Path]; ok && entry.Key == key && len(entry.Matches) == len(sourceDir.Files) && modTimesUnchanged(entry.ModTimes) {
+			s.debug("Using cached scan result", "path", sourceDir.Path)
+			return entry.Matches, nil
+		}
+	}
+
+	s.scans++
+	specMatches := make([][]fileMatch, len(sourceDir.Files))
+	for i, fileSpec := range sourceDir.Files {
+		matches, err := s.findSpecMatches(sourceDir, fileSpec)
+		if err != nil {
+			return nil, err
+		}
+		specMatches[i] = matches
+	}
+
+	if s.cache != nil {
+		s.cache.SourceDirs[sourceDir.Path] = cacheEntry{
+			Key:      key,
+			ModTimes: watchedModTimes(sourceDir, specMatches),
+			Matches:  specMatches,
+		}
+	}
+
+	return specMatches, nil
+}
+
+// cacheKey returns a hash of the configuration of a source directory and the
+// scanner options affecting its scan result, including the rules of the
+// repository-level ignore file
+func (s *Scanner) cacheKey(sourceDir config.SourceDir) string {
+	var ignorePatterns []string
+	if s.Config != nil {
+		ignorePatterns = s.Config.Ignore.Patterns()
+	}
+
+	data, _ := json.Marshal(struct {
+		SourceDir       config.SourceDir
+		CaseInsensitive bool
+		Ignore          []string
+	}{sourceDir, s.CaseInsensitive, ignorePatterns})
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// watchedModTimes returns the modification times of the directories a scan
+// result depends on: the glob base, the fixed directory part of each pattern,
+// the directories its wildcard directory segments can match or the tree of a
+// directory pattern, and the directories of the matched files. Missing
+// directories are recorded as 0, so that creating them invalidates the
+// result.
+func watchedModTimes(sourceDir config.SourceDir, specMatches [][]fileMatch) map[string]int64 {
+	globBase := sourceDir.GetGlobBase()
+	dirs := []string{globBase}
+	for _, fileSpec := range sourceDir.Files {
+		if fileSpec.IsNegation() {
+			continue
+		}
+
+		pattern := fileSpec.GetPattern()
+		dir := patternDir(globBase, pattern)
+		dirs = append(dirs, dir)
+
+		// New files in existing directories that matched nothing before
+		// only change the modification time of those directories
+		if info, err := os.Stat(filepath.Join(globBase, pattern)); err == nil && info.IsDir() {
+			dirs = append(dirs, subdirs(filepath.Join(globBase, pattern), -1)...)
+		} else if depth := wildcardDepth(pattern); depth > 0 {
+			dirs = append(dirs, subdirs(dir, depth)...)
+		}
+	}
+	for _, matches := range specMatches {
+		for _, match := range matches {
+			dirs = append(dirs, filepath.Dir(match.Path))
+		}
+	}
+
+	modTimes := make(map[string]int64)
+	for _, dir := range dirs {
+		modTimes[dir] = modTime(dir)
+	}
+	return modTimes
+}
+
+// patternDir returns the directory below base up to the first segment of a
+// pattern containing glob characters, excluding the file name
+func patternDir(base, pattern string) string {
+	segments := strings.Split(filepath.ToSlash(pattern), "/")
+	dir := base
+	for _, segment := range segments[:len(segments)-1] {
+		if strings.ContainsAny(segment, "*?[") {
+			break
+		}
+		dir = filepath.Join(dir, segment)
+	}
+	return dir
+}
+
+// wildcardDepth returns the number of directory segments of a pattern from
+// the first one containing glob characters, excluding the file name
+func wildcardDepth(pattern string) int {
+	segments := strings.Split(filepath.ToSlash(pattern), "/")
+	dirSegments := segments[:len(segments)-1]
+	for i, segment := range dirSegments {
+		if strings.ContainsAny(segment, "*?[") {
+			return len(dirSegments) - i
+		}
+	}
+	return 0
+}
+
+// subdirs returns the directories below dir down to maxDepth levels, or the
+// whole tree if maxDepth is negative
+func subdirs(dir string, maxDepth int) []string {
+	var dirs []string
+	_ = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.IsDir() || path == dir {
+			return nil
+		}
+		dirs = append(dirs, path)
+		if maxDepth >= 0 && dirDepth(dir, path) >= maxDepth {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return dirs
+}
+
+// modTime returns the modification time of a path in nanoseconds, or 0 if it
+// doesn't exist
+func modTime(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.ModTime().UnixNano()
+}
+
+// modTimesUnchanged reports whether the recorded modification times are
+// still current
+func modTimesUnchanged(modTimes map[string]int64) bool {
+	for dir, recorded := range modTimes {
+		if modTime(dir) != recorded {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+package scanner
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// fsPath converts a path to the unrooted, slash-separated form of fs.FS paths
+func fsPath(name string) string {
+	return strings.TrimPrefix(path.Clean(filepath.ToSlash(name)), "/")
+}
+
+// Stat returns the file info of a source file, from FS if set
+func (s *Scanner) Stat(name string) (fs.FileInfo, error) {
+	if s.FS != nil {
+		return fs.Stat(s.FS, fsPath(name))
+	}
+	return os.Stat(name)
+}
+
+// ReadFile reads a source file, from FS if set
+func (s *Scanner) ReadFile(name string) ([]byte, error) {
+	if s.FS != nil {
+		return fs.ReadFile(s.FS, fsPath(name))
+	}
+	return os.ReadFile(name)
+}
+
+// glob returns the source files matching a pattern, from FS if set
+func (s *Scanner) glob(pattern string) ([]string, error) {
+	if s.FS != nil {
+		return fs.Glob(s.FS, fsPath(pattern))
+	}
+	return filepath.Glob(pattern)
+}
+
+// readDir reads the entries of a source directory, from FS if set
+func (s *Scanner) readDir(name string) ([]fs.DirEntry, error) {
+	if s.FS != nil {
+		return fs.ReadDir(s.FS, fsPath(name))
+	}
+	return os.ReadDir(name)
+}
+
+// walkDir walks the tree of a source directory, from FS if set. The root is
+// passed to fn as given, and the paths below it are joined to it.
+func (s *Scanner) walkDir(root string, fn fs.WalkDirFunc) error {
+	if s.FS == nil {
+		return filepath.WalkDir(root, fn)
+	}
+
+	base := fsPath(root)
+	return fs.WalkDir(s.FS, base, func(name string, entry fs.DirEntry, err error) error {
+		rel := strings.TrimPrefix(strings.TrimPrefix(name, base), "/")
+		if base == "." {
+			rel = name
+		}
+		return fn(filepath.Join(root, filepath.FromSlash(rel)), entry, err)
+	})
+}
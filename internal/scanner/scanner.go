@@ -1,42 +1,234 @@
 package scanner
 
 import (
+	"cmp"
+	"context"
 	"fmt"
+	"io/fs"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/upamune/airulesync/internal/config"
+	"github.com/upamune/airulesync/internal/ignore"
 )
 
 // FileInfo represents information about a file to be synchronized
 type FileInfo struct {
-	SourcePath      string
-	SourceDir       string
-	RelativePath    string
-	Pattern         string
-	AdjustPaths     bool
-	Overwrite       bool
-	SourceDirConfig *config.SourceDir
+	SourcePath           string
+	SourceDir            string
+	RelativePath         string
+	Pattern              string
+	AdjustPaths          bool
+	Overwrite            bool
+	PromptOverwrite      bool
+	OverwriteIfNewer     bool
+	SourceDirConfig      *config.SourceDir
+	FrontmatterOverrides map[string]interface{}
+	TargetName           string
+	Header               string
+	HeaderComment        string
+
+	// AdjustDir is the directory relative paths in the file are resolved
+	// against when it differs from SourceDir, as for symlinked files
+	AdjustDir string
+}
+
+// AdjustBase returns the directory relative paths in the file are resolved against
+func (f FileInfo) AdjustBase() string {
+	if f.AdjustDir != "" {
+		return f.AdjustDir
+	}
+	return f.SourceDir
+}
+
+// resolveAdjustDir returns the directory relative paths in a symlinked source
+// file are resolved against: the directory as far above the real file as the
+// source directory is above the link, so paths resolve where the content was
+// written. It returns an empty string for regular files and files of FS.
+func (s *Scanner) resolveAdjustDir(sourceDir, sourcePath string) string {
+	if s.FS != nil {
+		return ""
+	}
+
+	info, err := os.Lstat(sourcePath)
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		return ""
+	}
+
+	realPath, err := filepath.EvalSymlinks(sourcePath)
+	if err != nil {
+		return ""
+	}
+	relDir, err := filepath.Rel(sourceDir, filepath.Dir(sourcePath))
+	if err != nil {
+		return ""
+	}
+
+	dir := filepath.Dir(realPath)
+	if relDir != "." {
+		for range strings.Split(relDir, string(filepath.Separator)) {
+			dir = filepath.Dir(dir)
+		}
+	}
+	return dir
+}
+
+// DefaultRulePatterns are the rule file patterns of common AI coding tools
+var DefaultRulePatterns = []string{
+	".clinerules",
+	".cursor/rules/*.mdc",
+	".roomodes",
+	".rooignore",
+	".cursorignore",
+	".clineignore",
+	".windsurfrules",
+	".aiexclude",
+	".github/copilot-instructions.md",
+}
+
+// DefaultSourceExtensions are the file extensions of source code files that
+// qualify a directory as a potential target directory
+var DefaultSourceExtensions = []string{
+	".go",
+	".js", ".jsx", ".mjs", ".ts", ".tsx",
+	".py",
+	".java", ".kt", ".scala",
+	".c", ".cpp", ".h", ".hpp",
+	".cs",
+	".rs",
+	".rb",
+	".php",
+	".swift",
+}
+
+// RulePatternsFile is the name of the optional file overriding the rule file patterns
+const RulePatternsFile = ".airulesync.patterns"
+
+// LoadRulePatterns reads rule file patterns from a file with one pattern per
+// line. Blank lines and lines starting with # are ignored.
+func LoadRulePatterns(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read patterns file: %w", err)
+	}
+
+	var patterns []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+
+	return patterns, nil
 }
 
 // Scanner is responsible for scanning directories for files to synchronize
 type Scanner struct {
-	Config *config.Config
+	Config           *config.Config
+	RulePatterns     []string
+	ExcludeDirs      []string
+	MaxDepth         int
+	SourceExtensions []string
+	CaseInsensitive  bool
+	Logger           *slog.Logger
+
+	// FS is the file system source files are read from instead of the
+	// operating system's, with source directory paths taken relative to its
+	// root, e.g. an embed.FS or fstest.MapFS. Targets are still written to
+	// the operating system's file system.
+	FS fs.FS
+
+	// CachePath is the file the scan results of source directories are
+	// cached in between runs when set. A cached result is reused while the
+	// modification times of the directories it was scanned from are
+	// unchanged. It is ignored when FS is set.
+	CachePath string
+
+	cache *scanCache
+	scans int
 }
 
 // NewScanner creates a new scanner
 func NewScanner(cfg *config.Config) *Scanner {
 	return &Scanner{
-		Config: cfg,
+		Config:           cfg,
+		RulePatterns:     DefaultRulePatterns,
+		SourceExtensions: DefaultSourceExtensions,
+		CaseInsensitive:  cfg != nil && cfg.IsCaseInsensitive(),
+	}
+}
+
+// MatchPattern reports whether name matches a shell pattern, regardless of
+// case when foldCase is set. A "**" in the pattern matches any number of
+// directories.
+func MatchPattern(pattern, name string, foldCase bool) (bool, error) {
+	if foldCase {
+		pattern, name = strings.ToLower(pattern), strings.ToLower(name)
+	}
+	if !strings.Contains(pattern, "**") {
+		return filepath.Match(pattern, name)
+	}
+
+	re, err := regexp.Compile("^" + ignore.GlobToRegexp(filepath.ToSlash(pattern)) + "$")
+	if err != nil {
+		return false, filepath.ErrBadPattern
 	}
+	return re.MatchString(filepath.ToSlash(name)), nil
+}
+
+// foldCasePattern turns the letters of a glob pattern outside of character
+// classes into classes matching both cases, so that globbing matches file
+// names regardless of case on any file system
+func foldCasePattern(pattern string) string {
+	var b strings.Builder
+	inClass := false
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\' && i+1 < len(pattern):
+			b.WriteByte(c)
+			i++
+			b.WriteByte(pattern[i])
+			continue
+		case c == '[':
+			inClass = true
+		case c == ']':
+			inClass = false
+		}
+
+		lower, upper := strings.ToLower(string(c)), strings.ToUpper(string(c))
+		if !inClass && lower != upper {
+			b.WriteString("[" + lower + upper + "]")
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
 }
 
 // ScanSourceDirs scans all source directories for files to synchronize
 func (s *Scanner) ScanSourceDirs() ([]FileInfo, error) {
-	var files []FileInfo
+	sourceDirs, err := s.SourceDirs()
+	if err != nil {
+		return nil, err
+	}
+	sourceDirs = byPriority(sourceDirs)
+
+	useCache := s.CachePath != "" && s.FS == nil
+	if useCache {
+		if err := s.loadCache(); err != nil {
+			return nil, err
+		}
+	}
 
-	for _, sourceDir := range s.Config.SourceDirs {
+	var files []FileInfo
+	for _, sourceDir := range sourceDirs {
 		dirFiles, err := s.scanSourceDir(sourceDir)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan source directory %s: %w", sourceDir.Path, err)
@@ -44,77 +236,230 @@ func (s *Scanner) ScanSourceDirs() ([]FileInfo, error) {
 		files = append(files, dirFiles...)
 	}
 
+	if useCache {
+		if err := s.saveCache(); err != nil {
+			return nil, err
+		}
+	}
+
 	return files, nil
 }
 
+// byPriority returns the source directories ordered by descending priority,
+// keeping the configured order of equal priorities. As the first file written
+// to a target path is kept, files of higher priority sources win collisions.
+func byPriority(sourceDirs []config.SourceDir) []config.SourceDir {
+	sorted := slices.Clone(sourceDirs)
+	slices.SortStableFunc(sorted, func(a, b config.SourceDir) int {
+		return cmp.Compare(b.Priority, a.Priority)
+	})
+	return sorted
+}
+
 // scanSourceDir scans a single source directory for files to synchronize
 func (s *Scanner) scanSourceDir(sourceDir config.SourceDir) ([]FileInfo, error) {
+	specMatches, err := s.matchSourceDir(sourceDir)
+	if err != nil {
+		return nil, err
+	}
+
 	var files []FileInfo
-	dirOverwrite := sourceDir.GetDirectoryOverwrite()
+	dirOverwrite := sourceDir.GetDirectoryOverwriteMode()
+
+	for i, fileSpec := range sourceDir.Files {
+		// Remove the files matched so far that a negation matches
+		if fileSpec.IsNegation() {
+			files = s.removeNegated(files, fileSpec.NegatedPattern())
+			continue
+		}
+
+		overwrite := fileSpec.GetOverwriteMode(dirOverwrite)
+		for _, match := range specMatches[i] {
+			files = append(files, FileInfo{
+				SourcePath:           match.Path,
+				SourceDir:            sourceDir.Path,
+				RelativePath:         match.RelativePath,
+				Pattern:              fileSpec.GetPattern(),
+				AdjustPaths:          fileSpec.ShouldAdjustPaths(),
+				Overwrite:            overwrite != config.OverwriteNever,
+				PromptOverwrite:      overwrite == config.OverwritePrompt,
+				OverwriteIfNewer:     overwrite == config.OverwriteIfNewer,
+				SourceDirConfig:      &sourceDir,
+				FrontmatterOverrides: fileSpec.FrontmatterOverrides,
+				TargetName:           fileSpec.TargetName,
+				Header:               fileSpec.Header,
+				HeaderComment:        fileSpec.HeaderComment,
+				AdjustDir:            s.resolveAdjustDir(sourceDir.Path, match.Path),
+			})
+		}
+	}
+
+	if s.Logger != nil && s.Logger.Enabled(context.Background(), slog.LevelDebug) {
+		s.logUnmatchedFiles(sourceDir, files)
+	}
+
+	return files, nil
+}
+
+// removeNegated returns the files whose relative path doesn't match the
+// pattern of a negation
+func (s *Scanner) removeNegated(files []FileInfo, pattern string) []FileInfo {
+	var kept []FileInfo
+	for _, file := range files {
+		if ok, _ := MatchPattern(pattern, file.RelativePath, s.CaseInsensitive); ok {
+			s.debug("Excluded candidate", "path", file.SourcePath, "reason", "matched negated pattern !"+pattern)
+			continue
+		}
+		kept = append(kept, file)
+	}
+	return kept
+}
 
+// IsNegated reports whether a path relative to a source directory matches
+// any of its negation file specs
+func (s *Scanner) IsNegated(sourceDir config.SourceDir, relPath string) bool {
 	for _, fileSpec := range sourceDir.Files {
-		pattern := fileSpec.GetPattern()
-		adjustPaths := fileSpec.ShouldAdjustPaths()
-		overwrite := fileSpec.ShouldOverwrite(dirOverwrite)
-
-		// Check if the pattern is a glob pattern
-		if strings.ContainsAny(pattern, "*?[") {
-			// Handle glob pattern
-			matches, err := s.findGlobMatches(sourceDir.Path, pattern, sourceDir.IgnoreFiles)
-			if err != nil {
-				return nil, fmt.Errorf("failed to find glob matches for pattern %s: %w", pattern, err)
-			}
+		if !fileSpec.IsNegation() {
+			continue
+		}
+		if ok, _ := MatchPattern(fileSpec.NegatedPattern(), relPath, s.CaseInsensitive); ok {
+			return true
+		}
+	}
+	return false
+}
 
-			for _, match := range matches {
-				relPath, err := filepath.Rel(sourceDir.Path, match)
-				if err != nil {
-					return nil, fmt.Errorf("failed to get relative path for %s: %w", match, err)
-				}
+// fileMatch is a source file matching a file spec
+type fileMatch struct {
+	Path         string `yaml:"path"`
+	RelativePath string `yaml:"relative_path"`
+}
+
+// findSpecMatches finds the files matching a file spec of a source directory
+func (s *Scanner) findSpecMatches(sourceDir config.SourceDir, fileSpec config.FileSpec) ([]fileMatch, error) {
+	if fileSpec.IsNegation() {
+		return nil, nil
+	}
+
+	pattern := fileSpec.GetPattern()
+	globBase := sourceDir.GetGlobBase()
+
+	// Reject patterns reaching outside of the source directory, such as
+	// ../../etc/passwd in a configuration from an untrusted source
+	if !filepath.IsLocal(pattern) {
+		return nil, fmt.Errorf("file pattern %s resolves outside of source directory %s", pattern, sourceDir.Path)
+	}
 
-				files = append(files, FileInfo{
-					SourcePath:      match,
-					SourceDir:       sourceDir.Path,
-					RelativePath:    relPath,
-					Pattern:         pattern,
-					AdjustPaths:     adjustPaths,
-					Overwrite:       overwrite,
-					SourceDirConfig: &sourceDir,
-				})
+	// Find the files matching the pattern, relative to the glob base
+	var matches []string
+	if strings.ContainsAny(pattern, "*?[") {
+		// Handle glob pattern
+		globMatches, err := s.findGlobMatches(globBase, pattern, sourceDir.IgnoreFiles)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find glob matches for pattern %s: %w", pattern, err)
+		}
+		matches = globMatches
+	} else {
+		// Handle simple file pattern, finding the file by any case of
+		// its name when matching is case-insensitive
+		fullPath := filepath.Join(globBase, pattern)
+		if s.CaseInsensitive {
+			if _, err := s.Stat(fullPath); os.IsNotExist(err) {
+				if matches, _ := s.glob(filepath.Join(globBase, foldCasePattern(pattern))); len(matches) > 0 {
+					fullPath = matches[0]
+				}
 			}
-		} else {
-			// Handle simple file pattern
-			fullPath := filepath.Join(sourceDir.Path, pattern)
-			if s.shouldIgnoreFile(fullPath, sourceDir.IgnoreFiles) {
+		}
+		if ignorePattern, ok := s.matchIgnorePattern(fullPath, sourceDir.IgnoreFiles); ok {
+			s.debug("Excluded candidate", "path", fullPath, "reason", "matched ignore pattern "+ignorePattern)
+			return nil, nil
+		}
+
+		// Check if the file exists
+		info, err := s.Stat(fullPath)
+		if os.IsNotExist(err) {
+			// Skip non-existent files
+			s.debug("Excluded candidate", "path", fullPath, "reason", "file does not exist")
+			return nil, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to stat file %s: %w", fullPath, err)
+		}
+
+		if !info.IsDir() {
+			return []fileMatch{{Path: fullPath, RelativePath: pattern}}, nil
+		}
+
+		// Handle directory pattern, synchronizing the whole tree
+		dirFiles, err := s.findDirFiles(fullPath, sourceDir.IgnoreFiles)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find files in directory %s: %w", pattern, err)
+		}
+		matches = dirFiles
+	}
+
+	var fileMatches []fileMatch
+	for _, match := range matches {
+		relPath, err := filepath.Rel(globBase, match)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get relative path for %s: %w", match, err)
+		}
+		fileMatches = append(fileMatches, fileMatch{Path: match, RelativePath: relPath})
+	}
+
+	return fileMatches, nil
+}
+
+// logUnmatchedFiles logs the files in the directories referenced by the file
+// patterns of a source directory that didn't match any pattern
+func (s *Scanner) logUnmatchedFiles(sourceDir config.SourceDir, files []FileInfo) {
+	matched := make(map[string]bool)
+	for _, file := range files {
+		matched[file.SourcePath] = true
+	}
+
+	visited := make(map[string]bool)
+	for _, fileSpec := range sourceDir.Files {
+		if fileSpec.IsNegation() {
+			continue
+		}
+		dir := filepath.Dir(filepath.Join(sourceDir.GetGlobBase(), fileSpec.GetPattern()))
+		if visited[dir] || strings.ContainsAny(dir, "*?[") {
+			continue
+		}
+		visited[dir] = true
+
+		entries, err := s.readDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			if entry.IsDir() || matched[path] {
 				continue
 			}
-
-			// Check if the file exists
-			if _, err := os.Stat(fullPath); os.IsNotExist(err) {
-				// Skip non-existent files
+			if _, ok := s.matchIgnorePattern(path, sourceDir.IgnoreFiles); ok {
 				continue
-			} else if err != nil {
-				return nil, fmt.Errorf("failed to stat file %s: %w", fullPath, err)
 			}
-
-			files = append(files, FileInfo{
-				SourcePath:      fullPath,
-				SourceDir:       sourceDir.Path,
-				RelativePath:    pattern,
-				Pattern:         pattern,
-				AdjustPaths:     adjustPaths,
-				Overwrite:       overwrite,
-				SourceDirConfig: &sourceDir,
-			})
+			s.debug("Excluded candidate", "path", path, "reason", "did not match any pattern")
 		}
 	}
+}
 
-	return files, nil
+// debug logs a debug message when a logger is configured
+func (s *Scanner) debug(msg string, args ...any) {
+	if s.Logger != nil {
+		s.Logger.Debug(msg, args...)
+	}
 }
 
 // findGlobMatches finds all files matching a glob pattern
 func (s *Scanner) findGlobMatches(basePath, pattern string, ignorePatterns []string) ([]string, error) {
 	fullPattern := filepath.Join(basePath, pattern)
-	matches, err := filepath.Glob(fullPattern)
+	if s.CaseInsensitive {
+		fullPattern = filepath.Join(basePath, foldCasePattern(pattern))
+	}
+	matches, err := s.glob(fullPattern)
 	if err != nil {
 		return nil, fmt.Errorf("failed to glob pattern %s: %w", fullPattern, err)
 	}
@@ -122,9 +467,11 @@ func (s *Scanner) findGlobMatches(basePath, pattern string, ignorePatterns []str
 	// Filter out ignored files
 	var filteredMatches []string
 	for _, match := range matches {
-		if !s.shouldIgnoreFile(match, ignorePatterns) {
+		if ignorePattern, ok := s.matchIgnorePattern(match, ignorePatterns); ok {
+			s.debug("Excluded candidate", "path", match, "reason", "matched ignore pattern "+ignorePattern)
+		} else {
 			// Check if it's a file (not a directory)
-			info, err := os.Stat(match)
+			info, err := s.Stat(match)
 			if err != nil {
 				return nil, fmt.Errorf("failed to stat file %s: %w", match, err)
 			}
@@ -138,53 +485,110 @@ func (s *Scanner) findGlobMatches(basePath, pattern string, ignorePatterns []str
 	return filteredMatches, nil
 }
 
-// shouldIgnoreFile checks if a file should be ignored
-func (s *Scanner) shouldIgnoreFile(filePath string, ignorePatterns []string) bool {
+// findDirFiles finds all files in a directory tree
+func (s *Scanner) findDirFiles(dir string, ignorePatterns []string) ([]string, error) {
+	var files []string
+
+	err := s.walkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if ignorePattern, ok := s.matchIgnorePattern(path, ignorePatterns); ok && path != dir {
+			s.debug("Excluded candidate", "path", path, "reason", "matched ignore pattern "+ignorePattern)
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !entry.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk directory %s: %w", dir, err)
+	}
+
+	return files, nil
+}
+
+// ShouldIgnoreFile checks if a file should be ignored
+func (s *Scanner) ShouldIgnoreFile(filePath string, ignorePatterns []string) bool {
+	_, ok := s.matchIgnorePattern(filePath, ignorePatterns)
+	return ok
+}
+
+// matchIgnorePattern returns the first ignore pattern matching a file,
+// including the rules of the repository-level ignore file
+func (s *Scanner) matchIgnorePattern(filePath string, ignorePatterns []string) (string, bool) {
+	if ignorePattern, ok := s.MatchIgnore(filePath, ignorePatterns); ok {
+		return ignorePattern, true
+	}
+
+	// Check the repository-level ignore file
+	if s.Config != nil {
+		if ignorePattern, ok := s.Config.Ignore.Match(filePath, false); ok {
+			return ignorePattern, true
+		}
+	}
+
+	return "", false
+}
+
+// MatchIgnore returns the first of the ignore patterns matching a file path,
+// either by its base name or as a whole
+func (s *Scanner) MatchIgnore(filePath string, ignorePatterns []string) (string, bool) {
 	for _, ignorePattern := range ignorePatterns {
 		// Check if the ignore pattern is a glob pattern
 		if strings.ContainsAny(ignorePattern, "*?[") {
-			matches, err := filepath.Match(ignorePattern, filepath.Base(filePath))
+			matches, err := MatchPattern(ignorePattern, filepath.Base(filePath), s.CaseInsensitive)
 			if err == nil && matches {
-				return true
+				return ignorePattern, true
+			}
+
+			// Try matching the whole path, e.g. for patterns with "**"
+			matches, err = MatchPattern(ignorePattern, filePath, s.CaseInsensitive)
+			if err == nil && matches {
+				return ignorePattern, true
 			}
 
 			// Try matching against the full path
 			fullIgnorePattern := filepath.Join(filepath.Dir(filePath), ignorePattern)
-			matches, err = filepath.Match(fullIgnorePattern, filePath)
+			matches, err = MatchPattern(fullIgnorePattern, filePath, s.CaseInsensitive)
 			if err == nil && matches {
-				return true
+				return ignorePattern, true
 			}
 		} else {
 			// Simple pattern matching
-			if filepath.Base(filePath) == ignorePattern {
-				return true
+			if s.equalName(filepath.Base(filePath), ignorePattern) {
+				return ignorePattern, true
 			}
 
 			// Check if the full path matches
-			if filePath == ignorePattern {
-				return true
+			if s.equalName(filePath, ignorePattern) {
+				return ignorePattern, true
 			}
 		}
 	}
 
-	return false
+	return "", false
+}
+
+// equalName compares file names, regardless of case if matching is case-insensitive
+func (s *Scanner) equalName(a, b string) bool {
+	if s.CaseInsensitive {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
 }
 
 // ScanDirectory scans a directory for rule files (used by the init command)
 func (s *Scanner) ScanDirectory(dir string) ([]string, error) {
 	var ruleFiles []string
 
-	// Common rule file patterns
-	patterns := []string{
-		".clinerules",
-		".cursor/rules/*.mdc",
-		".roomodes",
-		".rooignore",
-		".cursorignore",
-		".clineignore",
-	}
-
-	for _, pattern := range patterns {
+	for _, pattern := range s.RulePatterns {
 		fullPattern := filepath.Join(dir, pattern)
 		matches, err := filepath.Glob(fullPattern)
 		if err != nil {
@@ -211,10 +615,24 @@ func (s *Scanner) ScanDirectory(dir string) ([]string, error) {
 	return ruleFiles, nil
 }
 
+// dirDepth returns the number of path components of path below baseDir
+func dirDepth(baseDir, path string) int {
+	relPath, err := filepath.Rel(baseDir, path)
+	if err != nil || relPath == "." {
+		return 0
+	}
+	return strings.Count(relPath, string(filepath.Separator)) + 1
+}
+
 // FindPotentialTargetDirs finds potential target directories for rule files
 func (s *Scanner) FindPotentialTargetDirs(baseDir string) ([]string, error) {
 	var targetDirs []string
 
+	sourceExtensions := make(map[string]bool)
+	for _, ext := range s.SourceExtensions {
+		sourceExtensions["."+strings.TrimPrefix(strings.ToLower(ext), ".")] = true
+	}
+
 	err := filepath.Walk(baseDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -230,6 +648,12 @@ func (s *Scanner) FindPotentialTargetDirs(baseDir string) ([]string, error) {
 			return nil
 		}
 
+		// Stop descending beyond the maximum depth, where immediate children
+		// of the base directory are at depth 1
+		if s.MaxDepth > 0 && dirDepth(baseDir, path) > s.MaxDepth {
+			return filepath.SkipDir
+		}
+
 		// Skip hidden directories (except .cursor)
 		if strings.HasPrefix(filepath.Base(path), ".") && filepath.Base(path) != ".cursor" {
 			return filepath.SkipDir
@@ -240,10 +664,15 @@ func (s *Scanner) FindPotentialTargetDirs(baseDir string) ([]string, error) {
 			return filepath.SkipDir
 		}
 
+		// Skip user excluded directories
+		if s.isExcludedDir(baseDir, path) {
+			return filepath.SkipDir
+		}
+
 		// Check if this is a potential target directory
 		// We're looking for directories that:
 		// 1. Are not the base directory
-		// 2. Contain source code files (Go, JavaScript, TypeScript, etc.)
+		// 2. Contain source code files with one of the source extensions
 		// 3. Don't already have rule files
 
 		// Check for source code files
@@ -258,8 +687,7 @@ func (s *Scanner) FindPotentialTargetDirs(baseDir string) ([]string, error) {
 				continue
 			}
 
-			ext := strings.ToLower(filepath.Ext(entry.Name()))
-			if ext == ".go" || ext == ".js" || ext == ".ts" || ext == ".py" || ext == ".java" || ext == ".c" || ext == ".cpp" {
+			if sourceExtensions[strings.ToLower(filepath.Ext(entry.Name()))] {
 				hasSourceFiles = true
 				break
 			}
@@ -268,9 +696,8 @@ func (s *Scanner) FindPotentialTargetDirs(baseDir string) ([]string, error) {
 		if hasSourceFiles {
 			// Check if it already has rule files
 			hasRuleFiles := false
-			rulePatterns := []string{".clinerules", ".cursor/rules", ".roomodes", ".rooignore", ".cursorignore", ".clineignore"}
-			for _, pattern := range rulePatterns {
-				if _, err := os.Stat(filepath.Join(path, pattern)); err == nil {
+			for _, pattern := range s.RulePatterns {
+				if matches, err := filepath.Glob(filepath.Join(path, pattern)); err == nil && len(matches) > 0 {
 					hasRuleFiles = true
 					break
 				}
@@ -294,3 +721,24 @@ func (s *Scanner) FindPotentialTargetDirs(baseDir string) ([]string, error) {
 
 	return targetDirs, nil
 }
+
+// isExcludedDir checks if a directory matches one of the excluded directory
+// names or globs, either by its name or by its path relative to the base directory
+func (s *Scanner) isExcludedDir(baseDir, path string) bool {
+	relPath, err := filepath.Rel(baseDir, path)
+	if err != nil {
+		return false
+	}
+
+	for _, pattern := range s.ExcludeDirs {
+		pattern = filepath.Clean(pattern)
+		if match, _ := filepath.Match(pattern, filepath.Base(path)); match {
+			return true
+		}
+		if match, _ := filepath.Match(pattern, relPath); match {
+			return true
+		}
+	}
+
+	return false
+}
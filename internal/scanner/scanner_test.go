@@ -1,11 +1,18 @@
 package scanner
 
 import (
+	"bytes"
+	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/upamune/airulesync/internal/config"
+	"github.com/upamune/airulesync/internal/ignore"
 )
 
 func TestScanSourceDir(t *testing.T) {
@@ -298,3 +305,811 @@ func TestFindPotentialTargetDirs(t *testing.T) {
 		t.Errorf("Expected 1 directory, got %d", len(targetDirs))
 	}
 }
+
+func TestScanDirectoryWithExtendedRulePatterns(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	baseDir := filepath.Join(tempDir, "base")
+	githubDir := filepath.Join(baseDir, ".github")
+	subDirA := filepath.Join(baseDir, "sub-a")
+	subDirB := filepath.Join(baseDir, "sub-b")
+
+	for _, dir := range []string{baseDir, githubDir, subDirA, subDirB} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+	}
+
+	// Create rule files of newer tools
+	files := map[string]string{
+		filepath.Join(baseDir, ".windsurfrules"):            "# Test windsurfrules file",
+		filepath.Join(baseDir, ".aiexclude"):                "secrets/",
+		filepath.Join(githubDir, "copilot-instructions.md"): "# Test copilot instructions",
+		filepath.Join(subDirA, "main.go"):                   "package main",
+		filepath.Join(subDirB, "main.go"):                   "package main",
+		filepath.Join(subDirB, ".windsurfrules"):            "# Test windsurfrules file in sub-b",
+	}
+
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	s := NewScanner(nil)
+
+	// Scan the directory
+	ruleFiles, err := s.ScanDirectory(baseDir)
+	if err != nil {
+		t.Fatalf("Failed to scan directory: %v", err)
+	}
+
+	for _, expected := range []string{".windsurfrules", ".aiexclude", filepath.Join(".github", "copilot-instructions.md")} {
+		found := false
+		for _, file := range ruleFiles {
+			if file == expected {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("Expected file '%s' to be found, but it wasn't", expected)
+		}
+	}
+
+	// Directories with rule files of newer tools are not suggested as targets
+	targetDirs, err := s.FindPotentialTargetDirs(baseDir)
+	if err != nil {
+		t.Fatalf("Failed to find potential target directories: %v", err)
+	}
+
+	if len(targetDirs) != 1 || targetDirs[0] != "sub-a" {
+		t.Errorf("Expected only 'sub-a' to be suggested, got %v", targetDirs)
+	}
+}
+
+func TestFindPotentialTargetDirsWithExcludeDirs(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	baseDir := filepath.Join(tempDir, "base")
+	files := map[string]string{
+		filepath.Join(baseDir, "sub-a", "main.go"):                 "package main",
+		filepath.Join(baseDir, "third_party", "lib", "lib.go"):     "package lib",
+		filepath.Join(baseDir, "third_party", "main.go"):           "package main",
+		filepath.Join(baseDir, "gen", "proto", "api.pb.go"):        "package proto",
+		filepath.Join(baseDir, "internal", "gen", "generated.go"):  "package gen",
+		filepath.Join(baseDir, "internal", "handler", "server.go"): "package handler",
+	}
+
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	// Exclude by name and by relative path glob
+	s := NewScanner(nil)
+	s.ExcludeDirs = []string{"third_party", "gen/*"}
+
+	targetDirs, err := s.FindPotentialTargetDirs(baseDir)
+	if err != nil {
+		t.Fatalf("Failed to find potential target directories: %v", err)
+	}
+
+	expectedDirs := map[string]bool{
+		"sub-a":                              true,
+		filepath.Join("internal", "gen"):     true,
+		filepath.Join("internal", "handler"): true,
+		"third_party":                        false,
+		filepath.Join("third_party", "lib"):  false,
+		filepath.Join("gen", "proto"):        false,
+	}
+
+	for relPath, expected := range expectedDirs {
+		found := false
+		for _, dir := range targetDirs {
+			if dir == relPath {
+				found = true
+				break
+			}
+		}
+		if found != expected {
+			t.Errorf("Expected directory '%s' found=%v, got found=%v", relPath, expected, found)
+		}
+	}
+}
+
+func TestFindPotentialTargetDirsWithMaxDepth(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	baseDir := filepath.Join(tempDir, "base")
+	files := map[string]string{
+		filepath.Join(baseDir, "cmd", "main.go"):                    "package main",
+		filepath.Join(baseDir, "internal", "handler", "server.go"):  "package handler",
+		filepath.Join(baseDir, "internal", "handler", "v1", "a.go"): "package v1",
+	}
+
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	testCases := []struct {
+		maxDepth int
+		expected []string
+	}{
+		{
+			maxDepth: 1,
+			expected: []string{"cmd"},
+		},
+		{
+			maxDepth: 2,
+			expected: []string{"cmd", filepath.Join("internal", "handler")},
+		},
+		{
+			maxDepth: 0,
+			expected: []string{"cmd", filepath.Join("internal", "handler"), filepath.Join("internal", "handler", "v1")},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("max depth %d", tc.maxDepth), func(t *testing.T) {
+			s := NewScanner(nil)
+			s.MaxDepth = tc.maxDepth
+
+			targetDirs, err := s.FindPotentialTargetDirs(baseDir)
+			if err != nil {
+				t.Fatalf("Failed to find potential target directories: %v", err)
+			}
+
+			if strings.Join(targetDirs, ",") != strings.Join(tc.expected, ",") {
+				t.Errorf("Expected target directories %v, got %v", tc.expected, targetDirs)
+			}
+		})
+	}
+}
+
+func TestFindPotentialTargetDirsWithSourceExtensions(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	baseDir := filepath.Join(tempDir, "base")
+	files := map[string]string{
+		filepath.Join(baseDir, "crates", "core", "lib.rs"): "pub fn core() {}",
+		filepath.Join(baseDir, "app", "Program.CS"):        "class Program {}",
+		filepath.Join(baseDir, "tools", "main.go"):         "package main",
+	}
+
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	// Extensions are accepted with or without the leading dot
+	s := NewScanner(nil)
+	s.SourceExtensions = []string{".rs", "cs"}
+
+	targetDirs, err := s.FindPotentialTargetDirs(baseDir)
+	if err != nil {
+		t.Fatalf("Failed to find potential target directories: %v", err)
+	}
+
+	expected := []string{"app", filepath.Join("crates", "core")}
+	if strings.Join(targetDirs, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected target directories %v, got %v", expected, targetDirs)
+	}
+}
+
+func TestScanSourceDirCaseInsensitive(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	sourceDir := filepath.Join(tempDir, "source")
+	files := map[string]string{
+		filepath.Join(sourceDir, ".CLINERULES"):                 "# Cline rules",
+		filepath.Join(sourceDir, ".Cursor", "Rules", "Go.MDC"):  "# Go rules",
+		filepath.Join(sourceDir, ".Cursor", "Rules", "WIP.mdc"): "# Draft rules",
+	}
+
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	sourceDirConfig := config.SourceDir{
+		Path: sourceDir,
+		Files: []config.FileSpec{
+			{Pattern: ".clinerules"},
+			{Pattern: ".cursor/rules/*.mdc"},
+		},
+		IgnoreFiles: []string{"wip.*"},
+	}
+
+	testCases := []struct {
+		name            string
+		caseInsensitive bool
+		expected        []string
+	}{
+		{
+			name:            "case-insensitive",
+			caseInsensitive: true,
+			expected: []string{
+				filepath.Join(sourceDir, ".CLINERULES"),
+				filepath.Join(sourceDir, ".Cursor", "Rules", "Go.MDC"),
+			},
+		},
+		{
+			name:            "case-sensitive",
+			caseInsensitive: false,
+			expected:        nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewScanner(nil)
+			s.CaseInsensitive = tc.caseInsensitive
+
+			files, err := s.scanSourceDir(sourceDirConfig)
+			if err != nil {
+				t.Fatalf("Failed to scan source directory: %v", err)
+			}
+
+			var sourcePaths []string
+			for _, file := range files {
+				sourcePaths = append(sourcePaths, file.SourcePath)
+			}
+
+			if strings.Join(sourcePaths, ",") != strings.Join(tc.expected, ",") {
+				t.Errorf("Expected files %v, got %v", tc.expected, sourcePaths)
+			}
+		})
+	}
+}
+
+func TestFoldCasePattern(t *testing.T) {
+	testCases := []struct {
+		pattern  string
+		expected string
+	}{
+		{pattern: ".md", expected: ".[mM][dD]"},
+		{pattern: "*_1.go", expected: "*_1.[gG][oO]"},
+		{pattern: "[ab]x", expected: "[ab][xX]"},
+		{pattern: `\*a`, expected: `\*[aA]`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.pattern, func(t *testing.T) {
+			if got := foldCasePattern(tc.pattern); got != tc.expected {
+				t.Errorf("Expected foldCasePattern('%s') to be '%s', got '%s'", tc.pattern, tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	testCases := []struct {
+		pattern  string
+		name     string
+		foldCase bool
+		expected bool
+	}{
+		{pattern: "*.mdc", name: "general.mdc", expected: true},
+		{pattern: "*.mdc", name: "rules/general.mdc", expected: false},
+		{pattern: "**/*.mdc", name: "general.mdc", expected: true},
+		{pattern: "**/*.mdc", name: ".cursor/rules/lang/go.mdc", expected: true},
+		{pattern: ".cursor/**/go.mdc", name: ".cursor/rules/lang/go.mdc", expected: true},
+		{pattern: ".cursor/**/go.mdc", name: "other/rules/go.mdc", expected: false},
+		{pattern: "**/*.MDC", name: "rules/general.mdc", foldCase: true, expected: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.pattern+" "+tc.name, func(t *testing.T) {
+			match, err := MatchPattern(tc.pattern, tc.name, tc.foldCase)
+			if err != nil {
+				t.Fatalf("Failed to match pattern: %v", err)
+			}
+			if match != tc.expected {
+				t.Errorf("Expected MatchPattern('%s', '%s') to be %v, got %v", tc.pattern, tc.name, tc.expected, match)
+			}
+		})
+	}
+}
+
+func TestScanSourceDirWithFS(t *testing.T) {
+	// Source files live in an in-memory file system only
+	fsys := fstest.MapFS{
+		"rules/.clinerules":                    {Data: []byte("# Rules\n")},
+		"rules/.cursor/rules/general.mdc":      {Data: []byte("# General\n")},
+		"rules/.cursor/rules/private.mdc":      {Data: []byte("# Private\n")},
+		"rules/docs/guide.md":                  {Data: []byte("# Guide\n")},
+		"rules/docs/drafts/draft.md":           {Data: []byte("# Draft\n")},
+		"rules/unmatched.txt":                  {Data: []byte("Unmatched\n")},
+		"other/.clinerules":                    {Data: []byte("# Other\n")},
+		"rules/.cursor/rules/nested/extra.mdc": {Data: []byte("# Extra\n")},
+	}
+
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{
+				Path: "./rules",
+				Files: []config.FileSpec{
+					{Pattern: ".clinerules"},
+					{Pattern: ".cursor/rules/*.mdc"},
+					{Pattern: "docs"},
+					{Pattern: ".missing"},
+				},
+				IgnoreFiles: []string{"private.mdc", "drafts"},
+			},
+		},
+	}
+
+	s := NewScanner(cfg)
+	s.FS = fsys
+	files, err := s.ScanSourceDirs()
+	if err != nil {
+		t.Fatalf("Failed to scan source directories: %v", err)
+	}
+
+	var relPaths []string
+	for _, file := range files {
+		relPaths = append(relPaths, file.RelativePath)
+	}
+
+	expected := []string{".clinerules", ".cursor/rules/general.mdc", "docs/guide.md"}
+	if strings.Join(relPaths, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected files %v, got %v", expected, relPaths)
+	}
+
+	// Source files are read from the file system too
+	content, err := s.ReadFile(files[0].SourcePath)
+	if err != nil {
+		t.Fatalf("Failed to read source file: %v", err)
+	}
+	if string(content) != "# Rules\n" {
+		t.Errorf("Expected content of 'rules/.clinerules', got %q", content)
+	}
+}
+
+func TestScanSourceDirLogsExcludedCandidates(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	sourceDir := filepath.Join(tempDir, "source")
+	rulesDir := filepath.Join(sourceDir, ".cursor", "rules")
+	if err := os.MkdirAll(rulesDir, 0755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+
+	files := map[string]string{
+		filepath.Join(rulesDir, "general.mdc"): "# General rules",
+		filepath.Join(rulesDir, "private.mdc"): "# Private rules",
+		filepath.Join(rulesDir, "notes.txt"):   "Notes",
+	}
+
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{
+				Path: sourceDir,
+				Files: []config.FileSpec{
+					{Pattern: ".cursor/rules/*.mdc"},
+					{Pattern: ".clinerules"},
+				},
+				IgnoreFiles: []string{"private.mdc"},
+			},
+		},
+	}
+
+	// Capture the verbose output
+	var buf bytes.Buffer
+	s := NewScanner(cfg)
+	s.Logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	scannedFiles, err := s.scanSourceDir(cfg.SourceDirs[0])
+	if err != nil {
+		t.Fatalf("Failed to scan source directory: %v", err)
+	}
+
+	if len(scannedFiles) != 1 {
+		t.Errorf("Expected 1 file, got %d", len(scannedFiles))
+	}
+
+	output := buf.String()
+	expectedLines := []string{
+		fmt.Sprintf("path=%s reason=\"matched ignore pattern private.mdc\"", filepath.Join(rulesDir, "private.mdc")),
+		fmt.Sprintf("path=%s reason=\"file does not exist\"", filepath.Join(sourceDir, ".clinerules")),
+		fmt.Sprintf("path=%s reason=\"did not match any pattern\"", filepath.Join(rulesDir, "notes.txt")),
+	}
+
+	for _, expected := range expectedLines {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected verbose output to contain '%s', got:\n%s", expected, output)
+		}
+	}
+
+	if strings.Contains(output, "general.mdc") {
+		t.Errorf("Expected matched file not to be reported, got:\n%s", output)
+	}
+}
+
+func TestScanSourceDirsWithSourceGlob(t *testing.T) {
+	// Create a monorepo with per-package rules
+	tempDir := t.TempDir()
+	for path, content := range map[string]string{
+		"packages/api/.clinerules":          "# API rules\n",
+		"packages/web/.clinerules":          "# Web rules\n",
+		"packages/cli/internal/.clinerules": "# CLI rules\n",
+		"packages/docs/README.md":           "# Docs\n",
+		"node_modules/dep/.clinerules":      "# Dependency rules\n",
+		".clinerules":                       "# Root rules\n",
+	} {
+		fullPath := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	// The root is configured explicitly and the packages are discovered
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{Path: tempDir, Files: []config.FileSpec{{Pattern: ".clinerules"}}},
+		},
+		SourceGlob: filepath.Join(tempDir, "**", ".clinerules"),
+	}
+
+	s := NewScanner(cfg)
+	s.CaseInsensitive = false
+	files, err := s.ScanSourceDirs()
+	if err != nil {
+		t.Fatalf("Failed to scan source directories: %v", err)
+	}
+
+	var sourceDirs []string
+	for _, file := range files {
+		if file.RelativePath != ".clinerules" {
+			t.Errorf("Expected only .clinerules files, got %s", file.RelativePath)
+		}
+		relDir, err := filepath.Rel(tempDir, file.SourceDir)
+		if err != nil {
+			t.Fatalf("Failed to get relative path: %v", err)
+		}
+		sourceDirs = append(sourceDirs, filepath.ToSlash(relDir))
+	}
+
+	expected := []string{".", "packages/api", "packages/cli/internal", "packages/web"}
+	if strings.Join(sourceDirs, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected source directories %v, got %v", expected, sourceDirs)
+	}
+}
+
+func TestGlobRoot(t *testing.T) {
+	tests := []struct {
+		glob     string
+		expected string
+	}{
+		{"**/.clinerules", "."},
+		{"packages/*/.clinerules", "packages"},
+		{"packages/api/.clinerules", "packages/api"},
+		{".clinerules", "."},
+		{"/repo/**/.clinerules", "/repo"},
+	}
+
+	for _, tt := range tests {
+		if got := globRoot(filepath.FromSlash(tt.glob)); got != filepath.FromSlash(tt.expected) {
+			t.Errorf("globRoot(%q) = %q, expected %q", tt.glob, got, tt.expected)
+		}
+	}
+}
+
+func TestScanSourceDirsWithCache(t *testing.T) {
+	// Create a source directory with rule files
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "src")
+	for path, content := range map[string]string{
+		".clinerules":         "# Rules\n",
+		".cursor/rules/a.mdc": "# A\n",
+	} {
+		fullPath := filepath.Join(sourceDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{{
+			Path:  sourceDir,
+			Files: []config.FileSpec{{Pattern: ".clinerules"}, {Pattern: ".cursor/rules/*.mdc"}},
+		}},
+	}
+	cachePath := filepath.Join(tempDir, CacheFile)
+
+	// Each run uses a new scanner, like separate invocations
+	scan := func() (*Scanner, []string) {
+		t.Helper()
+		s := NewScanner(cfg)
+		s.CachePath = cachePath
+		files, err := s.ScanSourceDirs()
+		if err != nil {
+			t.Fatalf("Failed to scan source directories: %v", err)
+		}
+		var paths []string
+		for _, file := range files {
+			paths = append(paths, filepath.ToSlash(file.RelativePath))
+		}
+		return s, paths
+	}
+
+	s, first := scan()
+	if s.scans != 1 {
+		t.Errorf("Expected the first run to scan once, got %d scans", s.scans)
+	}
+
+	// An unchanged source directory is not scanned again
+	s, second := scan()
+	if s.scans != 0 {
+		t.Errorf("Expected the second run to use the cache, got %d scans", s.scans)
+	}
+	if strings.Join(first, ",") != strings.Join(second, ",") {
+		t.Errorf("Expected cached files %v, got %v", first, second)
+	}
+
+	// Adding a file changes the directory's modification time
+	if err := os.WriteFile(filepath.Join(sourceDir, ".cursor", "rules", "b.mdc"), []byte("# B\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	s, third := scan()
+	if s.scans != 1 {
+		t.Errorf("Expected a changed directory to be scanned again, got %d scans", s.scans)
+	}
+	expected := []string{".clinerules", ".cursor/rules/a.mdc", ".cursor/rules/b.mdc"}
+	if strings.Join(third, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected files %v, got %v", expected, third)
+	}
+}
+
+func TestScanSourceDirsWithCacheFindsFilesInUnmatchedSubdirs(t *testing.T) {
+	// Create rules in subdirectories, some of which match nothing yet
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "src")
+	for path, content := range map[string]string{
+		"rules/go/rule.mdc":     "# Go\n",
+		"rules/web/notes.txt":   "# Notes\n",
+		"docs/guides/intro.md":  "# Intro\n",
+		"docs/guides/api/.keep": "",
+	} {
+		fullPath := filepath.Join(sourceDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{{
+			Path:        sourceDir,
+			Files:       []config.FileSpec{{Pattern: "rules/*/rule.mdc"}, {Pattern: "docs"}},
+			IgnoreFiles: []string{"**/.keep"},
+		}},
+	}
+	cachePath := filepath.Join(tempDir, CacheFile)
+
+	// Each run uses a new scanner, like separate invocations
+	scan := func() (*Scanner, []string) {
+		t.Helper()
+		s := NewScanner(cfg)
+		s.CachePath = cachePath
+		files, err := s.ScanSourceDirs()
+		if err != nil {
+			t.Fatalf("Failed to scan source directories: %v", err)
+		}
+		var paths []string
+		for _, file := range files {
+			paths = append(paths, filepath.ToSlash(file.RelativePath))
+		}
+		sort.Strings(paths)
+		return s, paths
+	}
+
+	scan()
+	if s, _ := scan(); s.scans != 0 {
+		t.Errorf("Expected the second run to use the cache, got %d scans", s.scans)
+	}
+
+	// New files in existing directories that matched nothing before
+	for _, path := range []string{"rules/web/rule.mdc", "docs/guides/api/reference.md"} {
+		if err := os.WriteFile(filepath.Join(sourceDir, path), []byte("# New\n"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	s, paths := scan()
+	if s.scans != 1 {
+		t.Errorf("Expected a changed subdirectory to be scanned again, got %d scans", s.scans)
+	}
+	expected := []string{"docs/guides/api/reference.md", "docs/guides/intro.md", "rules/go/rule.mdc", "rules/web/rule.mdc"}
+	if strings.Join(paths, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected files %v, got %v", expected, paths)
+	}
+}
+
+func TestScanSourceDirsWithCacheAndChangedIgnoreFile(t *testing.T) {
+	// Create rules and an ignore file outside the source directory, so that
+	// editing it leaves the directory's modification time alone
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "src")
+	for _, path := range []string{".cursor/rules/go.mdc", ".cursor/rules/draft.mdc"} {
+		fullPath := filepath.Join(sourceDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte("# Rule\n"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	ignorePath := filepath.Join(tempDir, ignore.File)
+	if err := os.WriteFile(ignorePath, []byte("# Nothing ignored yet\n"), 0644); err != nil {
+		t.Fatalf("Failed to write ignore file: %v", err)
+	}
+	cachePath := filepath.Join(tempDir, CacheFile)
+
+	// Each run loads the ignore file and uses a new scanner, like separate
+	// invocations
+	scan := func() (*Scanner, []string) {
+		t.Helper()
+		matcher, err := ignore.Load(ignorePath)
+		if err != nil {
+			t.Fatalf("Failed to load ignore file: %v", err)
+		}
+		s := NewScanner(&config.Config{
+			SourceDirs: []config.SourceDir{{
+				Path:  sourceDir,
+				Files: []config.FileSpec{{Pattern: ".cursor/rules/*.mdc"}},
+			}},
+			Ignore: matcher,
+		})
+		s.CachePath = cachePath
+		files, err := s.ScanSourceDirs()
+		if err != nil {
+			t.Fatalf("Failed to scan source directories: %v", err)
+		}
+		var paths []string
+		for _, file := range files {
+			paths = append(paths, filepath.ToSlash(file.RelativePath))
+		}
+		sort.Strings(paths)
+		return s, paths
+	}
+
+	scan()
+	if s, _ := scan(); s.scans != 0 {
+		t.Errorf("Expected the second run to use the cache, got %d scans", s.scans)
+	}
+
+	// Newly ignored files are dropped from the cached result
+	if err := os.WriteFile(ignorePath, []byte("draft.mdc\n"), 0644); err != nil {
+		t.Fatalf("Failed to write ignore file: %v", err)
+	}
+	s, paths := scan()
+	if s.scans != 1 {
+		t.Errorf("Expected a changed ignore file to scan again, got %d scans", s.scans)
+	}
+	expected := []string{".cursor/rules/go.mdc"}
+	if strings.Join(paths, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected files %v, got %v", expected, paths)
+	}
+}
+
+func TestScanSourceDirWithNegation(t *testing.T) {
+	// Create rules with a folder of drafts
+	tempDir := t.TempDir()
+	for _, path := range []string{
+		".cursor/rules/go.mdc",
+		".cursor/rules/style.mdc",
+		".cursor/rules/drafts/wip.mdc",
+		".cursor/rules/drafts/idea.mdc",
+		".cursor/rules/drafts/notes.txt",
+	} {
+		fullPath := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte("# Rule\n"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	// The negation only removes the files matched by the specs before it
+	sourceDir := config.SourceDir{
+		Path: tempDir,
+		Files: []config.FileSpec{
+			{Pattern: ".cursor/rules"},
+			{Pattern: "!.cursor/rules/drafts/*.mdc"},
+		},
+	}
+
+	s := NewScanner(nil)
+	files, err := s.scanSourceDir(sourceDir)
+	if err != nil {
+		t.Fatalf("Failed to scan source directory: %v", err)
+	}
+
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, filepath.ToSlash(file.RelativePath))
+	}
+	sort.Strings(paths)
+
+	expected := []string{".cursor/rules/drafts/notes.txt", ".cursor/rules/go.mdc", ".cursor/rules/style.mdc"}
+	if strings.Join(paths, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected files %v, got %v", expected, paths)
+	}
+
+	// Files matched by a spec after the negation are kept
+	sourceDir.Files = append(sourceDir.Files, config.FileSpec{Pattern: ".cursor/rules/drafts/wip.mdc"})
+	files, err = s.scanSourceDir(sourceDir)
+	if err != nil {
+		t.Fatalf("Failed to scan source directory: %v", err)
+	}
+	if len(files) != 4 || files[3].RelativePath != ".cursor/rules/drafts/wip.mdc" {
+		t.Errorf("Expected the draft to be added back, got %+v", files)
+	}
+}
+
+func TestScanSourceDirRejectsEscapingPatterns(t *testing.T) {
+	// Create a source directory next to a file that must not be synchronized
+	tempDir := t.TempDir()
+	sourcePath := filepath.Join(tempDir, "source")
+	if err := os.MkdirAll(sourcePath, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "secret"), []byte("secret\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	s := NewScanner(nil)
+	for _, pattern := range []string{"../secret", "rules/../../secret", "../*", filepath.Join(tempDir, "secret")} {
+		t.Run(pattern, func(t *testing.T) {
+			sourceDir := config.SourceDir{
+				Path:  sourcePath,
+				Files: []config.FileSpec{{Pattern: pattern}},
+			}
+
+			_, err := s.scanSourceDir(sourceDir)
+			if err == nil || !strings.Contains(err.Error(), "resolves outside of source directory") {
+				t.Errorf("Expected pattern %s to be rejected, got %v", pattern, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/upamune/airulesync/internal/config"
+)
+
+// SourceDirs returns the configured source directories followed by the
+// directories discovered with the source glob of the configuration
+func (s *Scanner) SourceDirs() ([]config.SourceDir, error) {
+	if s.Config.SourceGlob == "" {
+		return s.Config.SourceDirs, nil
+	}
+
+	discovered, err := s.discoverSourceDirs(s.Config.SourceGlob)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover source directories for %s: %w", s.Config.SourceGlob, err)
+	}
+	return append(slices.Clone(s.Config.SourceDirs), discovered...), nil
+}
+
+// discoverSourceDirs finds the directories containing a file matching a
+// source glob, such as **/.clinerules, and returns them as source directories
+// synchronizing the files matching the last element of the glob. Directories
+// that are already configured as source directories are left out, and target
+// directories are not searched, so synchronized copies are not rediscovered.
+func (s *Scanner) discoverSourceDirs(sourceGlob string) ([]config.SourceDir, error) {
+	sourceGlob = filepath.Clean(sourceGlob)
+	filePattern := filepath.Base(sourceGlob)
+	root := globRoot(sourceGlob)
+
+	configured := make(map[string]bool)
+	for _, sourceDir := range s.Config.SourceDirs {
+		configured[filepath.Clean(sourceDir.Path)] = true
+	}
+
+	targets := make(map[string]bool)
+	for _, targetDir := range s.Config.TargetDirs {
+		targets[absPath(targetDir.Path)] = true
+	}
+
+	var sourceDirs []config.SourceDir
+	err := s.walkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// Nothing is discovered below a missing root
+			if path == root && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipAll
+			}
+			return err
+		}
+
+		if entry.IsDir() {
+			if path != root && (entry.Name() == ".git" || entry.Name() == "node_modules" || targets[absPath(path)]) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		dir := filepath.Dir(path)
+		if configured[dir] {
+			return nil
+		}
+
+		matched, err := MatchPattern(sourceGlob, path, s.CaseInsensitive)
+		if err != nil {
+			return fmt.Errorf("invalid source glob %s: %w", sourceGlob, err)
+		}
+		if !matched {
+			return nil
+		}
+
+		s.debug("Discovered source directory", "path", dir, "glob", sourceGlob)
+		configured[dir] = true
+		sourceDirs = append(sourceDirs, config.SourceDir{
+			Path:  dir,
+			Files: []config.FileSpec{{Pattern: filePattern}},
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sourceDirs, nil
+}
+
+// absPath returns the absolute form of a path, or the cleaned path if it
+// can't be resolved
+func absPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	return abs
+}
+
+// globRoot returns the directory of a glob up to its first element with
+// wildcards, below which all its matches are found
+func globRoot(glob string) string {
+	dir := filepath.Dir(glob)
+	elems := strings.Split(dir, string(filepath.Separator))
+	for i, elem := range elems {
+		if strings.ContainsAny(elem, "*?[") {
+			if i == 0 {
+				return "."
+			}
+			if root := strings.Join(elems[:i], string(filepath.Separator)); root != "" {
+				return root
+			}
+			return string(filepath.Separator)
+		}
+	}
+	return dir
+}
@@ -0,0 +1,30 @@
+package sync
+
+// ANSI escape codes of the colors used in reports
+const (
+	ansiReset  = "\033[0m"
+	ansiRed    = "\033[31m"
+	ansiGreen  = "\033[32m"
+	ansiYellow = "\033[33m"
+)
+
+// colorize wraps text in an ANSI color when color output is enabled
+func (s *Syncer) colorize(color, text string) string {
+	if !s.Color {
+		return text
+	}
+	return color + text + ansiReset
+}
+
+// statusMarker returns the list marker of a result in the report, colored red
+// for errors, yellow for skipped files and green for synchronized files
+func (s *Syncer) statusMarker(result SyncResult) string {
+	switch {
+	case result.Error != nil:
+		return s.colorize(ansiRed, "-")
+	case result.Skipped:
+		return s.colorize(ansiYellow, "-")
+	default:
+		return s.colorize(ansiGreen, "-")
+	}
+}
@@ -0,0 +1,83 @@
+package sync
+
+import (
+	"bytes"
+
+	"github.com/upamune/airulesync/internal/scanner"
+)
+
+// Conflict represents a target file that distinct source files, typically
+// from different source directories, would write with differing content.
+// Only the first source file is written; the others are reported as
+// collisions, which strict mode promotes to errors.
+type Conflict struct {
+	TargetFile string
+	Sources    []string
+}
+
+// findConflicts analyzes, before anything is written, which target files would
+// be written by several source files with differing content. Source files
+// rendering to the same content are not conflicts, and neither are source
+// files overridden by a source directory with a higher priority.
+func (s *Syncer) findConflicts(files []scanner.FileInfo) []Conflict {
+	var conflicts []Conflict
+	for _, targetDir := range s.Config.TargetDirs {
+		// Group the source files by the target path they would write
+		var targetOrder []string
+		sources := make(map[string][]scanner.FileInfo)
+		for _, file := range files {
+			if _, ok := s.matchTargetIgnore(targetRelPath(file, targetDir), targetDir); ok {
+				continue
+			}
+
+			key := s.pathKey(s.targetPath(file, targetDir))
+			if _, ok := sources[key]; !ok {
+				targetOrder = append(targetOrder, key)
+			}
+			sources[key] = append(sources[key], file)
+		}
+
+		for _, key := range targetOrder {
+			// Only distinct source files sharing the highest priority compete.
+			// Files are ordered by priority, so the first one has the highest.
+			var candidates []scanner.FileInfo
+			seen := make(map[string]bool)
+			for _, file := range sources[key] {
+				if seen[file.SourcePath] || filePriority(file) < filePriority(sources[key][0]) {
+					continue
+				}
+				seen[file.SourcePath] = true
+				candidates = append(candidates, file)
+			}
+
+			// Only render the target paths several source files compete for
+			if len(candidates) < 2 {
+				continue
+			}
+
+			conflict := Conflict{TargetFile: s.targetPath(sources[key][0], targetDir)}
+			var first []byte
+			differing := false
+			for _, file := range candidates {
+				// Unreadable files are reported when synchronizing them
+				content, _, err := s.renderContent(file, targetDir)
+				if err != nil {
+					continue
+				}
+
+				if len(conflict.Sources) > 0 && !bytes.Equal(first, content) {
+					differing = true
+				} else if len(conflict.Sources) == 0 {
+					first = content
+				}
+				conflict.Sources = append(conflict.Sources, file.SourcePath)
+			}
+
+			if differing {
+				conflicts = append(conflicts, conflict)
+			}
+		}
+	}
+
+	return conflicts
+}
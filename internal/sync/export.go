@@ -0,0 +1,108 @@
+package sync
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+)
+
+// Export renders the files of every target directory, adjusting paths as a
+// sync would, and writes them to a tar archive instead of the target
+// directories. Entries are named after the target path, with target
+// directories mirrored as with an output directory.
+func (s *Syncer) Export(w io.Writer) (*SyncReport, error) {
+	// Scan source directories for files to export
+	files, _, err := s.scanFiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan source directories: %w", err)
+	}
+
+	tw := tar.NewWriter(w)
+	var results []SyncResult
+	written := make(map[string]string)
+	for _, file := range files {
+		for _, targetDir := range s.Config.TargetDirs {
+			relPath := targetRelPath(file, targetDir)
+			name := path.Join(filepath.ToSlash(mirrorPath(targetDir.Path)), filepath.ToSlash(relPath))
+			result := SyncResult{
+				SourceFile: file.SourcePath,
+				TargetFile: name,
+			}
+
+			if marker, ok := s.missingMarker(targetDir); ok {
+				result.Skipped = true
+				result.SkipReason = fmt.Sprintf("target directory has no marker file %s", marker)
+				result.SkipKind = SkipNoMarker
+				results = append(results, result)
+				continue
+			}
+
+			if ignorePattern, ok := s.matchTargetIgnore(relPath, targetDir); ok {
+				result.Skipped = true
+				result.SkipReason = fmt.Sprintf("file matches ignore pattern %s in target directory", ignorePattern)
+				result.SkipKind = SkipIgnored
+				results = append(results, result)
+				continue
+			}
+
+			if owner, ok := written[name]; ok {
+				if owner != file.SourcePath {
+					results = append(results, s.collisionResult(file, name, owner, false))
+				}
+				continue
+			}
+
+			content, adjustments, err := s.renderContent(file, targetDir)
+			if err != nil {
+				result.Error = err
+				results = append(results, result)
+				continue
+			}
+			result.PathAdjustments = adjustments
+
+			info, err := s.Scanner.Stat(file.SourcePath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to stat source file: %w", err)
+			}
+			if err := writeTarEntry(tw, name, info, content); err != nil {
+				return nil, err
+			}
+			written[name] = file.SourcePath
+
+			result.Success = true
+			result.Changed = true
+			s.logResult(result)
+			results = append(results, result)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish archive: %w", err)
+	}
+
+	return &SyncReport{
+		Results: results,
+	}, nil
+}
+
+// writeTarEntry writes the content of a file to a tar archive, keeping the
+// permissions and modification time of the source file
+func writeTarEntry(tw *tar.Writer, name string, info fs.FileInfo, content []byte) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    int64(info.Mode().Perm()),
+		Size:    int64(len(content)),
+		ModTime: info.ModTime(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write archive entry %s: %w", name, err)
+	}
+	if _, err := tw.Write(content); err != nil {
+		return fmt.Errorf("failed to write archive entry %s: %w", name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package sync
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+
+	"github.com/upamune/airulesync/internal/frontmatter"
+	"github.com/upamune/airulesync/internal/scanner"
+)
+
+// commentPrefixes are the comment prefixes of file extensions, used for
+// headers when no comment prefix is configured
+var commentPrefixes = map[string]string{
+	"md": "<!--", "mdc": "<!--", "markdown": "<!--", "html": "<!--", "htm": "<!--", "xml": "<!--",
+	"yaml": "#", "yml": "#", "toml": "#", "sh": "#", "bash": "#", "zsh": "#", "py": "#", "rb": "#",
+	"js": "//", "jsx": "//", "ts": "//", "tsx": "//", "go": "//", "rs": "//", "java": "//", "kt": "//",
+	"swift": "//", "c": "//", "cpp": "//", "h": "//", "hpp": "//", "cs": "//", "php": "//",
+	"css": "/*",
+}
+
+// ruleFileCommentPrefixes are the comment prefixes of extensionless rule
+// files that don't hold Markdown
+var ruleFileCommentPrefixes = map[string]string{
+	".roomodes": "#",
+}
+
+// commentSuffixes close the comments of prefixes that need it
+var commentSuffixes = map[string]string{
+	"<!--": " -->",
+	"/*":   " */",
+}
+
+// addHeader prepends the configured header to the content of a file written
+// to targetPath, after its front matter, as a comment in the syntax of the
+// file. Files whose comment syntax is unknown, such as JSON files, are left
+// alone.
+func (s *Syncer) addHeader(file scanner.FileInfo, targetPath string, content []byte) []byte {
+	template := file.Header
+	if template == "" {
+		template = s.Config.Header
+	}
+	if template == "" {
+		return content
+	}
+
+	prefix := file.HeaderComment
+	if prefix == "" {
+		prefix = s.Config.HeaderComment
+	}
+	if prefix == "" {
+		prefix = inferCommentPrefix(filepath.Base(targetPath), content)
+	}
+	if prefix == "" {
+		s.Logger.Debug("Not adding header to file with unknown comment syntax", "path", targetPath)
+		return content
+	}
+
+	text := strings.NewReplacer(
+		"{source}", filepath.ToSlash(file.SourcePath),
+		"{target}", filepath.ToSlash(targetPath),
+	).Replace(template)
+
+	var header bytes.Buffer
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		header.WriteString(prefix + " " + line + commentSuffixes[prefix] + "\n")
+	}
+
+	// Keep front matter first, as tools only recognize it at the start
+	if lines, body, ok := frontmatter.Split(content); ok {
+		return frontmatter.Join(lines, append(header.Bytes(), body...))
+	}
+	return append(header.Bytes(), content...)
+}
+
+// inferCommentPrefix returns the comment prefix of a file by its extension.
+// Extensionless rule files such as .clinerules hold Markdown unless known
+// otherwise, ignore files such as .cursorignore take # comments and JSON
+// content takes none.
+func inferCommentPrefix(name string, content []byte) string {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(name), "."))
+	if ext != "" && "."+ext != strings.ToLower(name) {
+		return commentPrefixes[ext]
+	}
+
+	trimmed := bytes.TrimSpace(content)
+	if bytes.HasPrefix(trimmed, []byte("{")) || bytes.HasPrefix(trimmed, []byte("[")) {
+		return ""
+	}
+	if strings.HasSuffix(strings.ToLower(name), "ignore") {
+		return "#"
+	}
+	if prefix, ok := ruleFileCommentPrefixes[strings.ToLower(name)]; ok {
+		return prefix
+	}
+	return "<!--"
+}
@@ -0,0 +1,117 @@
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// jsonReport is the JSON representation of a sync report
+type jsonReport struct {
+	Config    *jsonConfig    `json:"config,omitempty"`
+	DryRun    bool           `json:"dryRun"`
+	Results   []jsonResult   `json:"results"`
+	Conflicts []jsonConflict `json:"conflicts,omitempty"`
+	Warnings  []string       `json:"warnings,omitempty"`
+
+	TargetDirsWritten int `json:"targetDirsWritten"`
+}
+
+// jsonConfig identifies the configuration that produced a report
+type jsonConfig struct {
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// jsonResult is the JSON representation of a single sync result
+type jsonResult struct {
+	Source          string           `json:"source"`
+	Target          string           `json:"target"`
+	Status          string           `json:"status"`
+	Reason          string           `json:"reason,omitempty"`
+	Error           string           `json:"error,omitempty"`
+	Bytes           int              `json:"bytes,omitempty"`
+	PathAdjustments []jsonAdjustment `json:"pathAdjustments,omitempty"`
+}
+
+// jsonAdjustment is the JSON representation of a path adjustment
+type jsonAdjustment struct {
+	Line     int    `json:"line"`
+	Original string `json:"original"`
+	Adjusted string `json:"adjusted"`
+	Matcher  string `json:"matcher"`
+}
+
+// jsonConflict is the JSON representation of a conflicting target file
+type jsonConflict struct {
+	Target  string   `json:"target"`
+	Sources []string `json:"sources"`
+}
+
+// resultStatus returns a single word describing the outcome of a result
+func resultStatus(result SyncResult) string {
+	switch {
+	case result.Error != nil:
+		return "error"
+	case result.Pruned:
+		return "pruned"
+	case result.Skipped:
+		return "skipped"
+	case result.Changed:
+		return "changed"
+	default:
+		return "unchanged"
+	}
+}
+
+// PrintJSONReport prints the report of the synchronization operations as JSON
+func (s *Syncer) PrintJSONReport(report *SyncReport, dryRun bool) error {
+	out := jsonReport{
+		DryRun:   dryRun,
+		Results:  []jsonResult{},
+		Warnings: report.Warnings,
+
+		TargetDirsWritten: report.TargetDirsWritten(),
+	}
+	if s.Config.Name != "" || s.Config.Description != "" {
+		out.Config = &jsonConfig{
+			Name:        s.Config.Name,
+			Description: s.Config.Description,
+		}
+	}
+
+	for _, result := range report.Results {
+		entry := jsonResult{
+			Source: result.SourceFile,
+			Target: result.TargetFile,
+			Status: resultStatus(result),
+			Reason: result.SkipReason,
+			Bytes:  result.Bytes,
+		}
+		if result.Error != nil {
+			entry.Error = result.Error.Error()
+		}
+		for _, adj := range result.PathAdjustments {
+			entry.PathAdjustments = append(entry.PathAdjustments, jsonAdjustment{
+				Line:     adj.LineNumber,
+				Original: adj.OriginalPath,
+				Adjusted: adj.AdjustedPath,
+				Matcher:  adj.Matcher,
+			})
+		}
+		out.Results = append(out.Results, entry)
+	}
+
+	for _, conflict := range report.Conflicts {
+		out.Conflicts = append(out.Conflicts, jsonConflict{
+			Target:  conflict.TargetFile,
+			Sources: conflict.Sources,
+		})
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+	fmt.Fprintln(s.Out, string(data))
+	return nil
+}
@@ -0,0 +1,183 @@
+package sync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestFile is the name of the manifest of synchronized target files
+const ManifestFile = ".airulesync.lock"
+
+// manifestHeader is written at the top of manifest files
+const manifestHeader = "# Generated by airulesync sync. Do not edit.\n"
+
+// Manifest lists synchronized target files and the hashes of their content
+type Manifest struct {
+	Files []ManifestEntry `yaml:"files"`
+
+	// dir is the directory of the manifest file, which entry paths are
+	// relative to
+	dir string
+}
+
+// ManifestEntry is a synchronized target file, relative to the directory of
+// the manifest, and the hash of its content
+type ManifestEntry struct {
+	Path   string `yaml:"path"`
+	SHA256 string `yaml:"sha256"`
+}
+
+// LoadManifest loads a manifest file
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest file: %w", err)
+	}
+
+	var manifest Manifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest file: %w", err)
+	}
+	manifest.dir = filepath.Dir(path)
+
+	return &manifest, nil
+}
+
+// WriteManifest records the hashes of the target files synchronized in a
+// report in a manifest file. Entries of target files not synchronized in this
+// run are kept, so runs restricted to some sources or targets update the
+// manifest instead of replacing it. Paths are recorded relative to the
+// directory of the manifest, so it stays valid from any working directory.
+func (s *Syncer) WriteManifest(report *SyncReport, path string) error {
+	hashes := make(map[string]string)
+	dir := filepath.Dir(path)
+
+	existing, err := LoadManifest(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if existing != nil {
+		for _, entry := range existing.Files {
+			hashes[entry.Path] = entry.SHA256
+		}
+	}
+
+	for _, result := range report.Results {
+		if !result.Pruned && !result.Success {
+			continue
+		}
+
+		entryPath, err := manifestEntryPath(dir, result.TargetFile)
+		if err != nil {
+			return err
+		}
+		if result.Pruned {
+			delete(hashes, entryPath)
+			continue
+		}
+
+		hash, err := HashFile(result.TargetFile)
+		if err != nil {
+			return err
+		}
+		hashes[entryPath] = hash
+	}
+
+	// Sort the entries by path for a deterministic manifest
+	manifest := Manifest{Files: make([]ManifestEntry, 0, len(hashes))}
+	for targetPath, hash := range hashes {
+		manifest.Files = append(manifest.Files, ManifestEntry{Path: targetPath, SHA256: hash})
+	}
+	sort.Slice(manifest.Files, func(i, j int) bool {
+		return manifest.Files[i].Path < manifest.Files[j].Path
+	})
+
+	data, err := yaml.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	if err := os.WriteFile(path, append([]byte(manifestHeader), data...), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest file: %w", err)
+	}
+
+	return nil
+}
+
+// manifestEntryPath returns the path of a target file relative to the
+// directory of the manifest, with forward slashes
+func manifestEntryPath(dir, targetPath string) (string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve manifest directory: %w", err)
+	}
+	absTarget, err := filepath.Abs(targetPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve target file %s: %w", targetPath, err)
+	}
+
+	rel, err := filepath.Rel(absDir, absTarget)
+	if err != nil {
+		return "", fmt.Errorf("failed to make target file %s relative to manifest: %w", targetPath, err)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// HashFile returns the hex encoded SHA-256 hash of the content of a file
+func HashFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+
+	return hashContent(data), nil
+}
+
+// hashContent returns the hex encoded SHA-256 hash of content
+func hashContent(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// ManifestMismatch is a target file no longer matching its manifest entry
+type ManifestMismatch struct {
+	Path    string
+	Deleted bool
+}
+
+// Path returns the path of a manifest entry, resolved against the directory
+// of the manifest
+func (m *Manifest) Path(entry ManifestEntry) string {
+	return filepath.Join(m.dir, filepath.FromSlash(entry.Path))
+}
+
+// Verify compares the target files listed in the manifest against their
+// recorded hashes and returns the files that were modified or deleted
+func (m *Manifest) Verify() ([]ManifestMismatch, error) {
+	var mismatches []ManifestMismatch
+
+	for _, entry := range m.Files {
+		path := m.Path(entry)
+		hash, err := HashFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			mismatches = append(mismatches, ManifestMismatch{Path: path, Deleted: true})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if hash != entry.SHA256 {
+			mismatches = append(mismatches, ManifestMismatch{Path: path})
+		}
+	}
+
+	return mismatches, nil
+}
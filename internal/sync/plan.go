@@ -0,0 +1,98 @@
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// PlanVersion is the version of the plan schema, increased on incompatible changes
+const PlanVersion = 1
+
+// PlanAction is what applying a plan does to a target file
+type PlanAction string
+
+// Supported plan actions
+const (
+	// PlanCreate writes a target file that doesn't exist yet
+	PlanCreate PlanAction = "create"
+	// PlanOverwrite replaces the content of an existing target file
+	PlanOverwrite PlanAction = "overwrite"
+	// PlanDelete removes a stale target file, as found by --prune
+	PlanDelete PlanAction = "delete"
+	// PlanSkip leaves a target file alone
+	PlanSkip PlanAction = "skip"
+)
+
+// Plan lists the actions a sync would apply to target files, so that another
+// tool can apply them. Unlike the report, its schema is kept stable.
+type Plan struct {
+	Version int         `json:"version"`
+	Actions []PlanEntry `json:"actions"`
+}
+
+// PlanEntry is the action a sync would apply to a single target file
+type PlanEntry struct {
+	Action PlanAction `json:"action"`
+	Source string     `json:"source,omitempty"`
+	Target string     `json:"target"`
+	// Changed is whether the action changes the content of the target file
+	Changed bool `json:"changed"`
+	// SHA256 is the hex encoded hash of the content the target file has
+	// after the action, for created, overwritten and unchanged files
+	SHA256 string `json:"sha256,omitempty"`
+	// Reason tells why a target file is skipped
+	Reason string `json:"reason,omitempty"`
+}
+
+// NewPlan creates the plan of the results of a report, typically of a dry-run
+func NewPlan(report *SyncReport) *Plan {
+	plan := &Plan{Version: PlanVersion, Actions: []PlanEntry{}}
+
+	for _, result := range report.Results {
+		entry := PlanEntry{
+			Source: result.SourceFile,
+			Target: result.TargetFile,
+			SHA256: result.ContentHash,
+		}
+
+		switch {
+		case result.Error != nil:
+			entry.Action = PlanSkip
+			entry.Reason = result.Error.Error()
+			entry.SHA256 = ""
+		case result.Pruned:
+			entry.Action = PlanDelete
+			entry.Changed = true
+		case result.Skipped:
+			entry.Action = PlanSkip
+			entry.Reason = result.SkipReason
+		case !result.Changed:
+			entry.Action = PlanSkip
+			entry.Reason = string(SkipUnchanged)
+		case result.Overwritten:
+			entry.Action = PlanOverwrite
+			entry.Changed = true
+		default:
+			entry.Action = PlanCreate
+			entry.Changed = true
+		}
+
+		plan.Actions = append(plan.Actions, entry)
+	}
+
+	return plan
+}
+
+// WritePlan writes the plan of the results of a report to a file as JSON
+func WritePlan(report *SyncReport, path string) error {
+	data, err := json.MarshalIndent(NewPlan(report), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal plan: %w", err)
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write plan file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package sync
+
+import (
+	"fmt"
+	"io"
+	gosync "sync"
+
+	"github.com/upamune/airulesync/internal/config"
+	"github.com/upamune/airulesync/internal/scanner"
+)
+
+// progress reports the completion of each pair of file and target directory
+// of a run. It is safe for concurrent use.
+type progress struct {
+	mu    gosync.Mutex
+	out   io.Writer
+	done  int
+	total int
+}
+
+// step reports that a file was processed for a target directory
+func (p *progress) step(file scanner.FileInfo, targetDir config.TargetDir) {
+	if p.out == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.done++
+	fmt.Fprintf(p.out, "[%d/%d] syncing %s -> %s\n", p.done, p.total, file.RelativePath, targetDir.Path)
+}
@@ -0,0 +1,190 @@
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/upamune/airulesync/internal/config"
+	"github.com/upamune/airulesync/internal/scanner"
+)
+
+// pruneStaleFiles removes target files matching the configured file patterns
+// that no longer have a corresponding source file. Only files within the scope
+// of a pattern that the manifest records as synchronized are considered, so
+// files written by hand are never removed, and files ignored by the source or
+// target directory are kept.
+func (s *Syncer) pruneStaleFiles(files []scanner.FileInfo) []SyncResult {
+	recorded, err := s.recordedFiles()
+	if err != nil {
+		return []SyncResult{{Error: fmt.Errorf("failed to find stale files: %w", err)}}
+	}
+
+	// Collect the target paths of all source files, synchronized or not
+	expected := make(map[string]bool)
+	for _, file := range files {
+		for _, targetDir := range s.Config.TargetDirs {
+			expected[s.targetPath(file, targetDir)] = true
+		}
+	}
+
+	sourceDirs, err := s.Scanner.SourceDirs()
+	if err != nil {
+		return []SyncResult{{Error: fmt.Errorf("failed to find stale files: %w", err)}}
+	}
+
+	var results []SyncResult
+	visited := make(map[string]bool)
+	for _, sourceDir := range sourceDirs {
+		for _, fileSpec := range sourceDir.Files {
+			if fileSpec.IsNegation() {
+				continue
+			}
+			for _, targetDir := range s.Config.TargetDirs {
+				// Targets that didn't opt in are left alone
+				if _, ok := s.missingMarker(targetDir); ok {
+					continue
+				}
+
+				// Files of the source directory are below its target prefix
+				root := filepath.Join(s.targetRoot(targetDir), sourceDir.GetTargetPrefix())
+				candidates, err := findPruneCandidates(root, fileSpec.GetPattern())
+				if err != nil {
+					results = append(results, SyncResult{
+						TargetFile: filepath.Join(root, fileSpec.GetPattern()),
+						Error:      fmt.Errorf("failed to find stale files: %w", err),
+					})
+					continue
+				}
+
+				for _, candidate := range candidates {
+					if expected[candidate] || visited[candidate] || !recorded[absPath(candidate)] ||
+						s.keepStaleFile(candidate, sourceDir, targetDir) {
+						continue
+					}
+					visited[candidate] = true
+					results = append(results, s.pruneFile(candidate))
+				}
+			}
+		}
+	}
+
+	return results
+}
+
+// recordedFiles returns the absolute paths of the target files recorded in
+// the manifest by previous runs. Without a manifest no file is recorded.
+func (s *Syncer) recordedFiles() (map[string]bool, error) {
+	recorded := make(map[string]bool)
+	if s.ManifestPath == "" {
+		return recorded, nil
+	}
+
+	manifest, err := LoadManifest(s.ManifestPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return recorded, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range manifest.Files {
+		recorded[absPath(manifest.Path(entry))] = true
+	}
+	return recorded, nil
+}
+
+// absPath returns the absolute form of a path, or the cleaned path if it
+// can't be resolved
+func absPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	return abs
+}
+
+// keepStaleFile checks if a stale target file is ignored by the source or
+// target directory and must therefore be kept
+func (s *Syncer) keepStaleFile(targetFile string, sourceDir config.SourceDir, targetDir config.TargetDir) bool {
+	relPath, err := filepath.Rel(s.targetRoot(targetDir), targetFile)
+	if err != nil {
+		return true
+	}
+
+	if _, ok := s.matchTargetIgnore(relPath, targetDir); ok {
+		return true
+	}
+
+	// Source patterns match the path below the target prefix
+	relPath, err = filepath.Rel(filepath.Join(s.targetRoot(targetDir), sourceDir.GetTargetPrefix()), targetFile)
+	if err != nil {
+		return true
+	}
+
+	// Files removed by a negation file spec were never synchronized
+	if s.Scanner.IsNegated(sourceDir, relPath) {
+		return true
+	}
+
+	// Match the source ignore patterns as if the file was in the source directory
+	return s.Scanner.ShouldIgnoreFile(filepath.Join(sourceDir.GetGlobBase(), relPath), sourceDir.IgnoreFiles)
+}
+
+// pruneFile removes a stale target file, unless this is a dry run
+func (s *Syncer) pruneFile(targetFile string) SyncResult {
+	result := SyncResult{
+		TargetFile: targetFile,
+		Pruned:     true,
+	}
+
+	if !s.DryRun {
+		if err := os.Remove(targetFile); err != nil {
+			result.Error = fmt.Errorf("failed to remove stale file: %w", err)
+			return result
+		}
+	}
+
+	result.Success = true
+	result.Changed = true
+	return result
+}
+
+// findPruneCandidates finds the files in a target directory within the scope
+// of a file pattern: the files matching a glob pattern, the file of a simple
+// pattern, or all files in the tree of a directory pattern
+func findPruneCandidates(targetDir, pattern string) ([]string, error) {
+	var matches []string
+	fullPattern := filepath.Join(targetDir, pattern)
+	if strings.ContainsAny(pattern, "*?[") {
+		globMatches, err := filepath.Glob(fullPattern)
+		if err != nil {
+			return nil, fmt.Errorf("failed to glob pattern %s: %w", fullPattern, err)
+		}
+		matches = globMatches
+	} else if _, err := os.Lstat(fullPattern); err == nil {
+		matches = []string{fullPattern}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	var candidates []string
+	for _, match := range matches {
+		err := filepath.WalkDir(match, func(path string, entry os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.IsDir() {
+				candidates = append(candidates, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return candidates, nil
+}
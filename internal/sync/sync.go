@@ -1,11 +1,20 @@
 package sync
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/upamune/airulesync/internal/config"
+	"github.com/upamune/airulesync/internal/frontmatter"
+	"github.com/upamune/airulesync/internal/logging"
 	"github.com/upamune/airulesync/internal/pathadjust"
 	"github.com/upamune/airulesync/internal/scanner"
 )
@@ -14,81 +23,571 @@ import (
 type SyncResult struct {
 	SourceFile      string
 	TargetFile      string
+	TargetDir       string
 	Success         bool
 	Error           error
 	PathAdjustments []pathadjust.AdjustmentResult
 	Skipped         bool
 	SkipReason      string
+	SkipKind        SkipKind
+	Changed         bool
+	ConflictsWith   string
+	Pruned          bool
+	External        bool
+	Bytes           int
+	Overwritten     bool
+	// ContentHash is the hex encoded SHA-256 hash of the rendered target
+	// content, when it was produced
+	ContentHash string
+}
+
+// SkipKind categorizes why a file was not written to a target
+type SkipKind string
+
+const (
+	// SkipUnchanged is the category of files whose target already has their content
+	SkipUnchanged SkipKind = "unchanged"
+	// SkipOverwrite is the category of files not overwriting existing targets
+	SkipOverwrite SkipKind = "overwrite=false"
+	// SkipIgnored is the category of files matching an ignore pattern of the target
+	SkipIgnored SkipKind = "ignored"
+	// SkipNoMarker is the category of files whose target lacks its required marker file
+	SkipNoMarker SkipKind = "no marker"
+	// SkipMissingDir is the category of files whose target directory doesn't
+	// exist and may not be created
+	SkipMissingDir SkipKind = "missing directory"
+	// SkipOverridden is the category of files whose target is written by a
+	// source directory with a higher priority
+	SkipOverridden SkipKind = "overridden"
+	// SkipOther is the category of other skipped files, such as collisions
+	SkipOther SkipKind = "other"
+)
+
+// skipKinds are the skip categories in the order they are reported
+var skipKinds = []SkipKind{SkipUnchanged, SkipOverwrite, SkipIgnored, SkipNoMarker, SkipMissingDir, SkipOverridden, SkipOther}
+
+// SkipCategory returns why the file of a result was not written, or an empty
+// string if it was. Unchanged files count as synchronized, as their target
+// is up to date, but are not written again.
+func (r SyncResult) SkipCategory() SkipKind {
+	switch {
+	case r.Skipped && r.SkipKind != "":
+		return r.SkipKind
+	case r.Skipped:
+		return SkipOther
+	case r.Success && !r.Changed && !r.Pruned:
+		return SkipUnchanged
+	default:
+		return ""
+	}
 }
 
 // SyncReport represents a report of all synchronization operations
 type SyncReport struct {
-	Results []SyncResult
+	Results   []SyncResult
+	Conflicts []Conflict
+	// Warnings describe problems that don't fail the run, such as external
+	// target directories and target collisions, unless strict mode is set
+	Warnings []string
+}
+
+// HasChanges returns whether any target file was changed or any error occurred
+func (r *SyncReport) HasChanges() bool {
+	for _, result := range r.Results {
+		if result.Changed || result.Error != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// SyncError is the failure to synchronize a source file to a target file
+type SyncError struct {
+	SourceFile string
+	TargetFile string
+	Err        error
+}
+
+// Error describes the failure with the files involved
+func (e *SyncError) Error() string {
+	return fmt.Sprintf("failed to synchronize %s to %s: %v", e.SourceFile, e.TargetFile, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *SyncError) Unwrap() error {
+	return e.Err
+}
+
+// Err returns the per-file failures of the report joined into one error, each
+// a *SyncError, or nil if no file failed
+func (r *SyncReport) Err() error {
+	var errs []error
+	for _, result := range r.Results {
+		if result.Error != nil {
+			errs = append(errs, &SyncError{SourceFile: result.SourceFile, TargetFile: result.TargetFile, Err: result.Error})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Counts returns the number of synchronized, skipped and failed files
+func (r *SyncReport) Counts() (synchronized, skipped, failed int) {
+	for _, result := range r.Results {
+		switch {
+		case result.Error != nil:
+			failed++
+		case result.Skipped:
+			skipped++
+		case result.Success && !result.Pruned:
+			synchronized++
+		}
+	}
+	return synchronized, skipped, failed
+}
+
+// TargetDirsWritten returns the number of distinct target directories at
+// least one file was written to, or would be in a dry run
+func (r *SyncReport) TargetDirsWritten() int {
+	written := make(map[string]bool)
+	for _, result := range r.Results {
+		if result.Changed && !result.Pruned && result.Error == nil {
+			written[result.TargetDir] = true
+		}
+	}
+	return len(written)
 }
 
 // Syncer is responsible for synchronizing files between directories
 type Syncer struct {
-	Config       *config.Config
-	Scanner      *scanner.Scanner
-	PathAdjuster *pathadjust.PathAdjuster
-	DryRun       bool
-	Verbose      bool
+	Config         *config.Config
+	Scanner        *scanner.Scanner
+	PathAdjuster   *pathadjust.PathAdjuster
+	DryRun         bool
+	Verbose        bool
+	Strict         bool
+	Stdout         bool
+	Limit          int
+	FailOnExternal bool
+	NoDereference  bool
+	Prune          bool
+	ManifestPath   string
+	OutputDir      string
+	NoAdjust       bool
+	ForceAdjust    bool
+	Progress       io.Writer
+	OnlyChanged    bool
+	Color          bool
+	SkipPatterns   []string
+	StopOnError    bool
+	NoCreateDirs   bool
+	Out            io.Writer
+	In             io.Reader
+	Prompt         io.Writer
+	Interactive    bool
+	Logger         *slog.Logger
+
+	// writable caches the writability check of directories during dry-runs
+	writable map[string]error
+	// dirErrors holds the target directories that couldn't be created
+	dirErrors map[string]error
+	// warned holds the warnings logged about source files, which are
+	// rendered once per target and when looking for conflicts
+	warned map[string]bool
+	// answers reads the answers to overwrite prompts from In
+	answers *bufio.Reader
+	// rewrites caches the compiled rewrites of the configuration
+	rewrites []pathadjust.Rewrite
 }
 
 // NewSyncer creates a new syncer
 func NewSyncer(cfg *config.Config, dryRun, verbose bool) *Syncer {
+	adjuster := pathadjust.NewPathAdjuster(verbose)
+	if cfg != nil {
+		adjuster.PreserveSeparators = cfg.PreserveSeparators
+		adjuster.Extensions = cfg.AdjustExtensions
+		adjuster.AdjustGlobs = cfg.AdjustFrontmatterGlobs
+	}
+
 	return &Syncer{
 		Config:       cfg,
 		Scanner:      scanner.NewScanner(cfg),
-		PathAdjuster: pathadjust.NewPathAdjuster(verbose),
+		PathAdjuster: adjuster,
 		DryRun:       dryRun,
 		Verbose:      verbose,
+		Out:          os.Stdout,
+		In:           os.Stdin,
+		Prompt:       os.Stderr,
+		Interactive:  isTerminal(os.Stdin),
+		Logger:       logging.Discard(),
+	}
+}
+
+// isTerminal checks if a file is a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// SetLogger sets the logger of the syncer and its scanner and path adjuster
+func (s *Syncer) SetLogger(logger *slog.Logger) {
+	s.Logger = logger
+	s.Scanner.Logger = logger
+	s.PathAdjuster.Logger = logger
+}
+
+// scanFiles scans the source directories for files to synchronize, leaving
+// out the files matching SkipPatterns, which are returned separately
+func (s *Syncer) scanFiles() ([]scanner.FileInfo, []scanner.FileInfo, error) {
+	files, err := s.Scanner.ScanSourceDirs()
+	if err != nil || len(s.SkipPatterns) == 0 {
+		return files, nil, err
+	}
+
+	var kept, skipped []scanner.FileInfo
+	for _, file := range files {
+		if pattern, ok := s.matchSkipPattern(file); ok {
+			s.Logger.Debug("Skipped file matching skip pattern", "path", file.SourcePath, "pattern", pattern)
+			skipped = append(skipped, file)
+			continue
+		}
+		kept = append(kept, file)
+	}
+	return kept, skipped, nil
+}
+
+// matchSkipPattern returns the skip pattern equal to the file spec pattern of
+// a file or matching its relative path
+func (s *Syncer) matchSkipPattern(file scanner.FileInfo) (string, bool) {
+	for _, pattern := range s.SkipPatterns {
+		if filepath.ToSlash(pattern) == filepath.ToSlash(file.Pattern) {
+			return pattern, true
+		}
+		if ok, _ := scanner.MatchPattern(pattern, file.RelativePath, s.Scanner.CaseInsensitive); ok {
+			return pattern, true
+		}
 	}
+	return "", false
 }
 
 // Sync synchronizes files between directories
 func (s *Syncer) Sync() (*SyncReport, error) {
+	s.warned = nil
+
+	if s.FailOnExternal {
+		if err := s.checkExternalTargets(); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.NoDereference {
+		if err := s.checkSymlinkTargets(); err != nil {
+			return nil, err
+		}
+	}
+
 	// Scan source directories for files to synchronize
-	files, err := s.Scanner.ScanSourceDirs()
+	files, skipped, err := s.scanFiles()
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan source directories: %w", err)
 	}
 
-	// Synchronize each file to each target directory
+	// Create the target directories before writing any file
+	var createdDirs []string
+	if !s.DryRun && !s.Stdout {
+		createdDirs = s.createTargetDirs(files)
+	}
+
+	// Find target files that distinct sources would write with differing content
+	conflicts := s.findConflicts(files)
+	conflicted := make(map[string]bool)
+	for _, conflict := range conflicts {
+		conflicted[s.pathKey(conflict.TargetFile)] = true
+	}
+
+	// Synchronize each file to each target directory, remembering which
+	// source file wrote each target path so collisions can be reported
 	var results []SyncResult
+	var warnings []string
+	warnedExternal := make(map[string]bool)
+	written := make(map[string]scanner.FileInfo)
+	changed := 0
+	progress := &progress{out: s.Progress, total: len(files) * len(s.Config.TargetDirs)}
+	stopped := false
+files:
 	for _, file := range files {
 		for _, targetDir := range s.Config.TargetDirs {
-			result := s.syncFile(file, targetDir)
+			targetPath := s.targetPath(file, targetDir)
+
+			var result SyncResult
+			switch owner, ok := written[s.pathKey(targetPath)]; {
+			// Sources of higher priority override the others on purpose
+			case ok && owner.SourcePath != file.SourcePath && filePriority(owner) > filePriority(file):
+				result = SyncResult{
+					SourceFile: file.SourcePath,
+					TargetFile: targetPath,
+					Skipped:    true,
+					SkipReason: fmt.Sprintf("overridden by %s with a higher priority", owner.SourcePath),
+					SkipKind:   SkipOverridden,
+				}
+
+			case ok && owner.SourcePath != file.SourcePath:
+				result = s.collisionResult(file, targetPath, owner.SourcePath, conflicted[s.pathKey(targetPath)])
+
+			// Defer the remaining files once the limit of changed files is
+			// reached. Unchanged files don't count, so the next run resumes
+			// where this one stopped.
+			case s.Limit > 0 && changed >= s.Limit:
+				result = SyncResult{
+					SourceFile: file.SourcePath,
+					TargetFile: targetPath,
+					Skipped:    true,
+					SkipReason: fmt.Sprintf("deferred after reaching the limit of %d files", s.Limit),
+				}
+
+			default:
+				result = s.syncFile(file, targetDir)
+				if result.Success {
+					written[s.pathKey(targetPath)] = file
+				}
+				s.logResult(result)
+				if result.Changed {
+					changed++
+				}
+			}
+
+			// Record warnings once per target directory or collision
+			switch {
+			case result.External && result.Success && !warnedExternal[targetDir.Path]:
+				warnedExternal[targetDir.Path] = true
+				warnings = append(warnings, fmt.Sprintf("target directory %s is external to the repository: cross-repository paths may require manual verification", targetDir.Path))
+			case result.ConflictsWith != "" && result.Error == nil:
+				warnings = append(warnings, fmt.Sprintf("'%s' -> '%s': %s", result.SourceFile, result.TargetFile, result.SkipReason))
+			}
+
 			results = append(results, result)
+			progress.step(file, targetDir)
+
+			// Leave the remaining files alone after the first failure
+			if result.Error != nil && s.StopOnError {
+				stopped = true
+				break files
+			}
 		}
 	}
 
+	if stopped {
+		if remaining := progress.total - len(results); remaining > 0 {
+			warnings = append(warnings, fmt.Sprintf("stopped after the first error, %d files were not attempted", remaining))
+		}
+	}
+
+	// Don't leave behind directories of files that weren't written
+	removeEmptyDirs(createdDirs)
+
+	// Remove target files whose source file no longer exists. Files left
+	// out by skip patterns still exist and keep their target files.
+	if s.Prune && !s.Stdout && !stopped {
+		results = append(results, s.pruneStaleFiles(append(slices.Clip(files), skipped...))...)
+	}
+
 	return &SyncReport{
-		Results: results,
+		Results:   results,
+		Conflicts: conflicts,
+		Warnings:  warnings,
 	}, nil
 }
 
+// checkExternalTargets fails if any target directory is marked as external or
+// lives outside the repository
+func (s *Syncer) checkExternalTargets() error {
+	for _, targetDir := range s.Config.TargetDirs {
+		if s.isExternalTarget(targetDir) {
+			return fmt.Errorf("target directory %s is external to the repository", targetDir.Path)
+		}
+	}
+	return nil
+}
+
+// isExternalTarget checks if a target directory is external. Inside a
+// repository the external flag, detected from the repository root when the
+// configuration was loaded, decides. Outside of one, target directories are
+// external when they lie outside the directory of the configuration file.
+func (s *Syncer) isExternalTarget(targetDir config.TargetDir) bool {
+	if targetDir.External {
+		return true
+	}
+
+	configDir := absPath(s.Config.Dir)
+	if config.FindRepoRoot(configDir) != "" {
+		return false
+	}
+	rel, err := filepath.Rel(configDir, absPath(targetDir.Path))
+	if err != nil {
+		return true
+	}
+	rel = filepath.ToSlash(rel)
+	return rel == ".." || s.PathAdjuster.IsExternalPath(rel)
+}
+
+// checkSymlinkTargets checks that no target directory is a symbolic link
+func (s *Syncer) checkSymlinkTargets() error {
+	for _, targetDir := range s.Config.TargetDirs {
+		info, err := os.Lstat(s.targetRoot(targetDir))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("failed to check target directory %s: %w", targetDir.Path, err)
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("target directory %s is a symbolic link", targetDir.Path)
+		}
+	}
+	return nil
+}
+
+// logResult logs the outcome of synchronizing a single file
+func (s *Syncer) logResult(result SyncResult) {
+	switch {
+	case result.Error != nil:
+		s.Logger.Debug("Failed to synchronize file", "source", result.SourceFile, "target", result.TargetFile, "error", result.Error)
+	case result.Skipped:
+		s.Logger.Debug("Skipped file", "source", result.SourceFile, "target", result.TargetFile, "reason", result.SkipReason)
+	default:
+		s.Logger.Debug("Synchronized file", "source", result.SourceFile, "target", result.TargetFile, "changed", result.Changed)
+	}
+}
+
+// collisionResult creates the result for a file whose target path was already
+// written by another source file in this run. The earlier write is kept; the
+// collision is a warning unless strict mode promotes it to an error.
+// Conflicting collisions are those where the sources differ in content.
+func (s *Syncer) collisionResult(file scanner.FileInfo, targetPath, owner string, conflicting bool) SyncResult {
+	result := SyncResult{
+		SourceFile:    file.SourcePath,
+		TargetFile:    targetPath,
+		ConflictsWith: owner,
+	}
+
+	reason := fmt.Sprintf("target file collides with %s written earlier in this run", owner)
+	if conflicting {
+		reason = fmt.Sprintf("target file conflicts with differing content of %s written earlier in this run", owner)
+	}
+	if s.Strict {
+		result.Error = fmt.Errorf("%s", reason)
+	} else {
+		result.Skipped = true
+		result.SkipReason = reason
+	}
+
+	return result
+}
+
+// filePriority returns the priority of the source directory of a file
+func filePriority(file scanner.FileInfo) int {
+	if file.SourceDirConfig == nil {
+		return 0
+	}
+	return file.SourceDirConfig.Priority
+}
+
+// targetPath calculates the path a file is written to in a target directory
+func (s *Syncer) targetPath(file scanner.FileInfo, targetDir config.TargetDir) string {
+	return filepath.Join(s.targetRoot(targetDir), targetRelPath(file, targetDir))
+}
+
+// pathKey returns the key identifying a target path, which is the same for
+// every case of the path when matching is case-insensitive
+func (s *Syncer) pathKey(path string) string {
+	if s.Scanner.CaseInsensitive {
+		return strings.ToLower(path)
+	}
+	return path
+}
+
+// targetRelPath calculates the path of a file relative to a target directory,
+// renaming it as configured by the target directory or the file spec and
+// placing it below the target prefix of its source directory
+func targetRelPath(file scanner.FileInfo, targetDir config.TargetDir) string {
+	relPath := file.RelativePath
+	name := file.TargetName
+	if rename, ok := targetDir.Rename[filepath.ToSlash(file.RelativePath)]; ok {
+		name = rename
+	}
+	if name != "" {
+		relPath = filepath.Join(filepath.Dir(file.RelativePath), name)
+	}
+	return filepath.Join(file.SourceDirConfig.GetTargetPrefix(), relPath)
+}
+
+// targetRoot returns the directory files of a target directory are written to.
+// With an output directory, the target directory path is mirrored below it.
+func (s *Syncer) targetRoot(targetDir config.TargetDir) string {
+	if s.OutputDir == "" {
+		return targetDir.Path
+	}
+	return filepath.Join(s.OutputDir, mirrorPath(targetDir.Path))
+}
+
+// mirrorPath returns the relative path a directory is mirrored to below another
+// directory: paths within the working directory keep their relative layout,
+// and paths outside of it are mirrored by their absolute path
+func mirrorPath(dir string) string {
+	if !filepath.IsAbs(dir) {
+		rel := filepath.Clean(dir)
+		if rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return rel
+		}
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		abs = dir
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(abs, filepath.VolumeName(abs)), string(filepath.Separator))
+}
+
 // syncFile synchronizes a single file to a target directory
 func (s *Syncer) syncFile(file scanner.FileInfo, targetDir config.TargetDir) SyncResult {
 	// Calculate the target file path
-	relPath := file.RelativePath
-	targetPath := filepath.Join(targetDir.Path, relPath)
+	relPath := targetRelPath(file, targetDir)
+	targetPath := s.targetPath(file, targetDir)
 
 	// Create a result object
 	result := SyncResult{
 		SourceFile: file.SourcePath,
 		TargetFile: targetPath,
+		TargetDir:  targetDir.Path,
 		Success:    false,
 		Skipped:    false,
+		External:   s.isExternalTarget(targetDir),
+	}
+
+	// Check if the target directory opted in with its marker file
+	if marker, ok := s.missingMarker(targetDir); ok {
+		result.Skipped = true
+		result.SkipReason = fmt.Sprintf("target directory has no marker file %s", marker)
+		result.SkipKind = SkipNoMarker
+		return result
+	}
+
+	// Check if the target directory exists when it may not be created
+	if s.missingTargetDir(targetDir) {
+		result.Skipped = true
+		result.SkipReason = fmt.Sprintf("target directory %s does not exist", s.targetRoot(targetDir))
+		result.SkipKind = SkipMissingDir
+		return result
 	}
 
 	// Check if the file should be ignored
-	for _, ignorePattern := range targetDir.IgnoreFiles {
-		if match, _ := filepath.Match(ignorePattern, relPath); match {
-			result.Skipped = true
-			result.SkipReason = fmt.Sprintf("file matches ignore pattern %s in target directory", ignorePattern)
-			return result
-		}
+	if ignorePattern, ok := s.matchTargetIgnore(relPath, targetDir); ok {
+		result.Skipped = true
+		result.SkipReason = fmt.Sprintf("file matches ignore pattern %s in target directory", ignorePattern)
+		result.SkipKind = SkipIgnored
+		return result
 	}
 
 	// Check if the target file exists and should be overwritten
@@ -96,49 +595,380 @@ func (s *Syncer) syncFile(file scanner.FileInfo, targetDir config.TargetDir) Syn
 		if _, err := os.Stat(targetPath); err == nil {
 			result.Skipped = true
 			result.SkipReason = "file exists and overwrite=false"
+			result.SkipKind = SkipOverwrite
+			return result
+		}
+	}
+
+	// Produce the target content
+	content, adjustments, err := s.renderContent(file, targetDir)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	result.PathAdjustments = adjustments
+
+	// Keep target files modified since their source file with
+	// overwrite=if-newer, unless they already have the content
+	if file.OverwriteIfNewer {
+		newer, err := s.targetIsNewer(file.SourcePath, targetPath, content)
+		if err != nil {
+			result.Error = err
+			return result
+		}
+		if newer {
+			result.Skipped = true
+			result.SkipReason = "target is newer"
+			result.SkipKind = SkipOverwrite
 			return result
 		}
 	}
 
-	// If this is a dry run, just return the result
+	// Print the target content instead of writing it
+	if s.Stdout {
+		fmt.Fprintf(s.Out, "==> '%s' -> '%s' <==\n", file.SourcePath, targetPath)
+		s.Out.Write(content)
+		result.Success = true
+		return result
+	}
+
+	// If this is a dry run, check that the file could be written and produce
+	// its content to estimate the size of the sync, without writing it
 	if s.DryRun {
+		if err := s.checkWritable(filepath.Dir(targetPath)); err != nil {
+			result.Error = fmt.Errorf("target directory is not writable: %w", err)
+			return result
+		}
+
+		existing, existingErr := os.ReadFile(targetPath)
+
+		result.ContentHash = hashContent(content)
+		result.Bytes = len(content)
+		result.Overwritten = existingErr == nil
 		result.Success = true
+		result.Changed = existingErr != nil || !bytes.Equal(existing, content)
 		return result
 	}
 
+	// Remember the current target content to detect whether the sync changes it
+	previous, previousErr := os.ReadFile(targetPath)
+
 	// Ensure the target directory exists
 	targetDirPath := filepath.Dir(targetPath)
+	if err, ok := s.dirErrors[targetDirPath]; ok {
+		result.Error = err
+		return result
+	}
 	if err := os.MkdirAll(targetDirPath, 0755); err != nil {
 		result.Error = fmt.Errorf("failed to create target directory: %w", err)
 		return result
 	}
 
-	// Synchronize the file
-	if file.AdjustPaths {
-		// Adjust paths in the file
-		adjustments, err := s.PathAdjuster.AdjustPaths(
-			file.SourcePath,
-			targetPath,
-			file.SourceDir,
-			targetDir.Path,
-		)
+	result.ContentHash = hashContent(content)
+
+	// Ask before overwriting an existing, differing target file
+	if file.PromptOverwrite && previousErr == nil && !bytes.Equal(previous, content) {
+		if !s.Interactive {
+			result.Skipped = true
+			result.SkipReason = "file exists and overwrite=prompt without a terminal"
+			return result
+		}
+
+		overwrite, err := s.confirmOverwrite(file.SourcePath, targetPath)
 		if err != nil {
-			result.Error = fmt.Errorf("failed to adjust paths: %w", err)
+			result.Error = err
 			return result
 		}
-		result.PathAdjustments = adjustments
-	} else {
-		// Copy the file without adjusting paths
-		if err := s.PathAdjuster.CopyFile(file.SourcePath, targetPath); err != nil {
-			result.Error = fmt.Errorf("failed to copy file: %w", err)
+		if !overwrite {
+			result.Skipped = true
+			result.SkipReason = "file exists and overwrite was declined"
 			return result
 		}
 	}
 
+	// Leave targets that already have the content alone
+	if previousErr == nil && bytes.Equal(previous, content) {
+		result.Success = true
+		return result
+	}
+
+	// Write the target file
+	if err := s.PathAdjuster.WriteFile(targetPath, content); err != nil {
+		result.Error = fmt.Errorf("failed to write file: %w", err)
+		return result
+	}
+
+	result.Bytes = len(content)
+	result.Overwritten = previousErr == nil
 	result.Success = true
+	result.Changed = true
 	return result
 }
 
+// targetIsNewer reports whether a target file exists with content other than
+// the synchronized content and was modified at the same time as or after its
+// source file
+func (s *Syncer) targetIsNewer(sourcePath, targetPath string, content []byte) (bool, error) {
+	existing, err := os.ReadFile(targetPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to read target file: %w", err)
+	}
+	if bytes.Equal(existing, content) {
+		return false, nil
+	}
+
+	targetInfo, err := os.Stat(targetPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to stat target file: %w", err)
+	}
+
+	sourceInfo, err := s.Scanner.Stat(sourcePath)
+	if err != nil {
+		return false, fmt.Errorf("failed to stat source file: %w", err)
+	}
+	return !targetInfo.ModTime().Before(sourceInfo.ModTime()), nil
+}
+
+// confirmOverwrite asks whether to overwrite a target file with its source
+// file on Prompt and reads the answer from In
+func (s *Syncer) confirmOverwrite(sourcePath, targetPath string) (bool, error) {
+	if s.answers == nil {
+		s.answers = bufio.NewReader(s.In)
+	}
+
+	// Prompts go to stderr, keeping them out of the report on stdout
+	fmt.Fprintf(s.Prompt, "Overwrite '%s' with '%s'? [y/N] ", targetPath, sourcePath)
+	answer, err := s.answers.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, fmt.Errorf("failed to read answer: %w", err)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// matchTargetIgnore returns the first ignore pattern of a target directory, or
+// the pattern of the repository-level ignore file, matching the relative path of a file
+func (s *Syncer) matchTargetIgnore(relPath string, targetDir config.TargetDir) (string, bool) {
+	if ignorePattern, ok := s.Scanner.MatchIgnore(relPath, targetDir.IgnoreFiles); ok {
+		return ignorePattern, true
+	}
+
+	// Check the repository-level ignore file
+	return s.Config.Ignore.Match(filepath.Join(targetDir.Path, relPath), false)
+}
+
+// missingMarker returns the required marker file of a target directory if it
+// doesn't exist there. The marker is looked up in the configured target
+// directory, even when writing below an output directory.
+func (s *Syncer) missingMarker(targetDir config.TargetDir) (string, bool) {
+	if targetDir.RequireMarker == "" {
+		return "", false
+	}
+
+	if _, err := os.Stat(filepath.Join(targetDir.Path, targetDir.RequireMarker)); err != nil {
+		return targetDir.RequireMarker, true
+	}
+	return "", false
+}
+
+// checkWritable checks that files can be created in a directory by creating
+// and removing a temporary file in it, or in its nearest existing ancestor
+// when the directory doesn't exist yet
+func (s *Syncer) checkWritable(dir string) error {
+	if s.writable == nil {
+		s.writable = make(map[string]error)
+	}
+	if err, ok := s.writable[dir]; ok {
+		return err
+	}
+
+	err := probeWritable(dir)
+	s.writable[dir] = err
+	return err
+}
+
+// probeWritable performs the writability check of checkWritable
+func probeWritable(dir string) error {
+	for current := dir; ; current = filepath.Dir(current) {
+		info, err := os.Stat(current)
+		if os.IsNotExist(err) && filepath.Dir(current) != current {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", current)
+		}
+
+		probe, err := os.CreateTemp(current, ".airulesync-probe-*")
+		if err != nil {
+			return err
+		}
+		probe.Close()
+		return os.Remove(probe.Name())
+	}
+}
+
+// warnOnce logs a warning about a source file, unless the same warning was
+// already logged during the run
+func (s *Syncer) warnOnce(msg string, args ...any) {
+	key := fmt.Sprint(append([]any{msg}, args...)...)
+	if s.warned[key] {
+		return
+	}
+	if s.warned == nil {
+		s.warned = make(map[string]bool)
+	}
+	s.warned[key] = true
+	s.Logger.Warn(msg, args...)
+}
+
+// renderContent produces the content of a file as it should be written to a target directory
+func (s *Syncer) renderContent(file scanner.FileInfo, targetDir config.TargetDir) ([]byte, []pathadjust.AdjustmentResult, error) {
+	content, err := s.Scanner.ReadFile(file.SourcePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read source file: %w", err)
+	}
+
+	// Copy files verbatim when adjustment is disabled for the run
+	if s.NoAdjust {
+		return content, nil, nil
+	}
+
+	// Copy binary and non-UTF-8 files, such as UTF-16 files, as they are,
+	// as line processing would corrupt them
+	if encoding := pathadjust.DetectEncoding(content); encoding != pathadjust.EncodingUTF8 {
+		if file.AdjustPaths || s.ForceAdjust || len(s.Config.Rewrites) > 0 || len(targetDir.FrontmatterOverrides) > 0 || len(file.FrontmatterOverrides) > 0 {
+			if encoding == pathadjust.EncodingBinary {
+				s.warnOnce("Copying binary file without adjusting paths", "path", file.SourcePath)
+			} else {
+				s.warnOnce("Copying non-UTF-8 file without adjusting paths", "path", file.SourcePath, "encoding", encoding)
+			}
+		}
+		return content, nil, nil
+	}
+
+	// Copy huge files as they are, as line processing them would be slow
+	// and pointless for rule files
+	if limit := s.Config.GetMaxAdjustSize(); limit >= 0 && int64(len(content)) > limit {
+		s.warnOnce("Copying large file without adjusting paths", "path", file.SourcePath, "size", len(content), "max_adjust_size", limit)
+		return content, nil, nil
+	}
+
+	// Keep a UTF-8 byte order mark out of the way of front matter detection
+	// and put it back in front of the result
+	bom, content := pathadjust.SplitBOM(content)
+
+	// Adjust paths in the file
+	var adjustments []pathadjust.AdjustmentResult
+	if file.AdjustPaths || s.ForceAdjust {
+		adjustments, content, err = s.PathAdjuster.AdjustContent(content, file.AdjustBase(), filepath.Join(targetDir.Path, file.SourceDirConfig.GetTargetPrefix()))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to adjust paths: %w", err)
+		}
+	}
+
+	// Apply the configured rewrites after the standard path adjustment
+	rewrites, err := s.compileRewrites()
+	if err != nil {
+		return nil, nil, err
+	}
+	content = pathadjust.RewriteContent(content, rewrites)
+
+	// Force front matter keys, with file spec overrides taking precedence
+	overrides := make(map[string]interface{})
+	for key, value := range targetDir.FrontmatterOverrides {
+		overrides[key] = value
+	}
+	for key, value := range file.FrontmatterOverrides {
+		overrides[key] = value
+	}
+	content, err = frontmatter.Apply(content, overrides)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to apply front matter overrides: %w", err)
+	}
+
+	// Mark the file as generated with the configured header
+	content = s.addHeader(file, s.targetPath(file, targetDir), content)
+
+	return append(bom, content...), adjustments, nil
+}
+
+// compileRewrites compiles the rewrites of the configuration once
+func (s *Syncer) compileRewrites() ([]pathadjust.Rewrite, error) {
+	if s.rewrites != nil || len(s.Config.Rewrites) == 0 {
+		return s.rewrites, nil
+	}
+
+	for _, rewrite := range s.Config.Rewrites {
+		pattern, err := rewrite.Compile()
+		if err != nil {
+			return nil, err
+		}
+		s.rewrites = append(s.rewrites, pathadjust.Rewrite{Pattern: pattern, Replacement: rewrite.Replacement})
+	}
+
+	return s.rewrites, nil
+}
+
+// printSkipCategories prints the number of files not written for each
+// category of reasons, such as unchanged or ignored files
+func (s *Syncer) printSkipCategories(report *SyncReport, prefix string) {
+	counts := make(map[SkipKind]int)
+	for _, result := range report.Results {
+		if kind := result.SkipCategory(); kind != "" {
+			counts[kind]++
+		}
+	}
+
+	for _, kind := range skipKinds {
+		if (kind == SkipOverridden || kind == SkipOther) && counts[kind] == 0 {
+			continue
+		}
+		fmt.Fprintf(s.Out, "%s  * skipped (%s): %d\n", prefix, kind, counts[kind])
+	}
+}
+
+// PrintAdjustmentReport prints only the files with path adjustments and the
+// original and adjusted paths of each adjustment
+func (s *Syncer) PrintAdjustmentReport(report *SyncReport, dryRun bool) {
+	prefix := ""
+	if dryRun {
+		prefix = "[DRY-RUN] "
+	}
+
+	for _, result := range report.Results {
+		if len(result.PathAdjustments) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(s.Out, "%s'%s' -> '%s'\n", prefix, result.SourceFile, result.TargetFile)
+		for _, adj := range result.PathAdjustments {
+			fmt.Fprintf(s.Out, "%s  - Line %d: '%s' -> '%s'\n", prefix, adj.LineNumber, adj.OriginalPath, adj.AdjustedPath)
+		}
+	}
+}
+
+// PrintAdjustmentLines prints the path adjustments of the adjustment report
+// one per line, as the target file, line number, original path and adjusted
+// path, for tools and reviews
+func (s *Syncer) PrintAdjustmentLines(report *SyncReport) {
+	for _, result := range report.Results {
+		for _, adj := range result.PathAdjustments {
+			fmt.Fprintf(s.Out, "%s:%d: '%s' -> '%s'\n", result.TargetFile, adj.LineNumber, adj.OriginalPath, adj.AdjustedPath)
+		}
+	}
+}
+
 // PrintReport prints a report of the synchronization operations
 func (s *Syncer) PrintReport(report *SyncReport, dryRun bool) {
 	prefix := ""
@@ -146,81 +976,145 @@ func (s *Syncer) PrintReport(report *SyncReport, dryRun bool) {
 		prefix = "[DRY-RUN] "
 	}
 
-	fmt.Printf("%sStarting synchronization process\n", prefix)
-	fmt.Printf("%sScanning source directories for target files...\n", prefix)
+	fmt.Fprintf(s.Out, "%sStarting synchronization process\n", prefix)
+	fmt.Fprintf(s.Out, "%sScanning source directories for target files...\n", prefix)
 
-	// Group results by source file for better readability
+	// Group results by source file for better readability. Results follow
+	// the scan order, sources by descending priority and then in declaration
+	// order, so groups keep that order and targets their declaration order.
+	var sourceOrder []string
 	sourceFiles := make(map[string][]SyncResult)
 	for _, result := range report.Results {
+		if _, ok := sourceFiles[result.SourceFile]; !ok {
+			sourceOrder = append(sourceOrder, result.SourceFile)
+		}
 		sourceFiles[result.SourceFile] = append(sourceFiles[result.SourceFile], result)
 	}
 
 	// Print files to synchronize
-	fmt.Printf("\n%sFiles to synchronize:\n", prefix)
-	syncCount := 0
-	skipCount := 0
+	fmt.Fprintf(s.Out, "\n%sFiles to synchronize:\n", prefix)
+	hiddenCount := 0
 
-	for sourceFile, results := range sourceFiles {
-		for _, result := range results {
-			if !result.Skipped {
-				syncCount++
-				fmt.Printf("%s- '%s' -> '%s'\n", prefix, sourceFile, result.TargetFile)
+	for _, sourceFile := range sourceOrder {
+		for _, result := range sourceFiles[sourceFile] {
+			if !result.Skipped && !result.Pruned {
+				// Hide files whose target already matched the source
+				if s.OnlyChanged && result.Success && !result.Changed {
+					hiddenCount++
+					continue
+				}
+				fmt.Fprintf(s.Out, "%s%s '%s' -> '%s'\n", prefix, s.statusMarker(result), sourceFile, result.TargetFile)
 
 				if result.PathAdjustments != nil && len(result.PathAdjustments) > 0 {
-					fmt.Printf("%s  * Path adjustments: %d locations\n", prefix, len(result.PathAdjustments))
+					fmt.Fprintf(s.Out, "%s  * Path adjustments: %d locations\n", prefix, len(result.PathAdjustments))
 
 					if s.Verbose {
 						for _, adj := range result.PathAdjustments {
-							fmt.Printf("%s    - Line %d: '%s' -> '%s'\n", prefix, adj.LineNumber, adj.OriginalPath, adj.AdjustedPath)
+							fmt.Fprintf(s.Out, "%s    - Line %d: '%s' -> '%s' [%s]\n", prefix, adj.LineNumber, adj.OriginalPath, adj.AdjustedPath, adj.Matcher)
 						}
 					}
 				} else if result.PathAdjustments != nil {
-					fmt.Printf("%s  * Path adjustments: 0 locations\n", prefix)
+					fmt.Fprintf(s.Out, "%s  * Path adjustments: 0 locations\n", prefix)
 				} else {
-					fmt.Printf("%s  * No path adjustment (as configured)\n", prefix)
+					fmt.Fprintf(s.Out, "%s  * No path adjustment (as configured)\n", prefix)
 				}
 
 				// Check if this is a cross-repository sync
-				if s.PathAdjuster.IsExternalPath(filepath.Dir(result.TargetFile)) {
-					fmt.Printf("%s  * Warning: Cross-repository paths may require manual verification\n", prefix)
+				if result.External {
+					fmt.Fprintf(s.Out, "%s  * Warning: Cross-repository paths may require manual verification\n", prefix)
 				}
 			}
 		}
 	}
 
 	// Print files to skip
-	fmt.Printf("\n%sFiles to skip:\n", prefix)
-	for sourceFile, results := range sourceFiles {
-		for _, result := range results {
+	fmt.Fprintf(s.Out, "\n%sFiles to skip:\n", prefix)
+	for _, sourceFile := range sourceOrder {
+		for _, result := range sourceFiles[sourceFile] {
 			if result.Skipped {
-				skipCount++
-				fmt.Printf("%s- '%s' -> '%s' (%s)\n", prefix, sourceFile, result.TargetFile, result.SkipReason)
+				fmt.Fprintf(s.Out, "%s%s '%s' -> '%s' (%s)\n", prefix, s.statusMarker(result), sourceFile, result.TargetFile, result.SkipReason)
 			}
 		}
 	}
 
-	// Print summary
-	fmt.Printf("\n%sSynchronization process completed\n", prefix)
-	fmt.Printf("%s- Files synchronized: %d\n", prefix, syncCount)
-	fmt.Printf("%s- Files skipped: %d\n", prefix, skipCount)
+	// Print target files sources would write with differing content
+	if len(report.Conflicts) > 0 {
+		fmt.Fprintf(s.Out, "\n%sConflicting sources:\n", prefix)
+		for _, conflict := range report.Conflicts {
+			fmt.Fprintf(s.Out, "%s- '%s' <- '%s'\n", prefix, conflict.TargetFile, strings.Join(conflict.Sources, "', '"))
+		}
+	}
 
-	// Print errors if any
-	errorCount := 0
+	// Print stale files to prune
+	pruneCount := 0
 	for _, result := range report.Results {
-		if result.Error != nil {
-			errorCount++
+		if !result.Pruned {
+			continue
+		}
+		if pruneCount == 0 {
+			fmt.Fprintf(s.Out, "\n%sStale files to prune:\n", prefix)
 		}
+		pruneCount++
+		fmt.Fprintf(s.Out, "%s- '%s'\n", prefix, result.TargetFile)
 	}
 
-	if errorCount > 0 {
-		fmt.Printf("%s- Errors encountered: %d\n", prefix, errorCount)
+	// Print summary, counted like the JSON report
+	syncCount, skipCount, failCount := report.Counts()
+	fmt.Fprintf(s.Out, "\n%sSynchronization process completed\n", prefix)
+	fmt.Fprintf(s.Out, "%s- Files synchronized: %d\n", prefix, syncCount)
+	fmt.Fprintf(s.Out, "%s- Files skipped: %d\n", prefix, skipCount)
+	if failCount > 0 {
+		fmt.Fprintf(s.Out, "%s- Files failed: %d\n", prefix, failCount)
+	}
+	fmt.Fprintf(s.Out, "%s- Target directories written: %d\n", prefix, report.TargetDirsWritten())
+	if s.Verbose {
+		s.printSkipCategories(report, prefix)
+	}
+	if hiddenCount > 0 {
+		fmt.Fprintf(s.Out, "%s- Unchanged files not listed: %d\n", prefix, hiddenCount)
+	}
+	if pruneCount > 0 {
+		fmt.Fprintf(s.Out, "%s- Stale files pruned: %d\n", prefix, pruneCount)
+	}
 
-		if s.Verbose {
-			fmt.Printf("\n%sErrors:\n", prefix)
-			for _, result := range report.Results {
-				if result.Error != nil {
-					fmt.Printf("%s- '%s' -> '%s': %v\n", prefix, result.SourceFile, result.TargetFile, result.Error)
-				}
+	// Estimate the size of the sync in dry-run mode
+	if dryRun {
+		totalBytes, newCount, overwriteCount := 0, 0, 0
+		for _, result := range report.Results {
+			if !result.Changed || result.Pruned {
+				continue
+			}
+			totalBytes += result.Bytes
+			if result.Overwritten {
+				overwriteCount++
+			} else {
+				newCount++
+			}
+		}
+		fmt.Fprintf(s.Out, "%s- Bytes to write: %d (%d new files, %d overwritten)\n", prefix, totalBytes, newCount, overwriteCount)
+	}
+
+	// Print collision warnings
+	collisionCount := 0
+	for _, result := range report.Results {
+		if result.ConflictsWith != "" && result.Error == nil {
+			collisionCount++
+		}
+	}
+
+	if collisionCount > 0 {
+		fmt.Fprintf(s.Out, "%s- Warning: Target collisions detected: %d\n", prefix, collisionCount)
+	}
+	if len(report.Conflicts) > 0 {
+		fmt.Fprintf(s.Out, "%s- Conflicting target files: %d\n", prefix, len(report.Conflicts))
+	}
+
+	// Print errors if any
+	if failCount > 0 && s.Verbose {
+		fmt.Fprintf(s.Out, "\n%sErrors:\n", prefix)
+		for _, result := range report.Results {
+			if result.Error != nil {
+				fmt.Fprintf(s.Out, "%s- '%s' -> '%s': %v\n", prefix, result.SourceFile, result.TargetFile, result.Error)
 			}
 		}
 	}
@@ -1,9 +1,20 @@
 package sync
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/upamune/airulesync/internal/config"
 	"github.com/upamune/airulesync/internal/scanner"
@@ -251,6 +262,500 @@ func TestSyncFileWithIgnorePattern(t *testing.T) {
 	}
 }
 
+func TestSyncWithRequireMarker(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	sourceDir := filepath.Join(tempDir, "source")
+	enabledDir := filepath.Join(tempDir, "enabled")
+	disabledDir := filepath.Join(tempDir, "disabled")
+
+	for _, dir := range []string{sourceDir, enabledDir, disabledDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, ".clinerules"), []byte("# Rules\n"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	// Only one of the targets opts in with the marker file
+	if err := os.WriteFile(filepath.Join(enabledDir, ".airulesync-enabled"), nil, 0644); err != nil {
+		t.Fatalf("Failed to write marker file: %v", err)
+	}
+
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{
+				Path:  sourceDir,
+				Files: []config.FileSpec{{Pattern: ".clinerules"}},
+			},
+		},
+		TargetDirs: []config.TargetDir{
+			{Path: enabledDir, RequireMarker: ".airulesync-enabled"},
+			{Path: disabledDir, RequireMarker: ".airulesync-enabled"},
+		},
+	}
+
+	syncer := NewSyncer(cfg, false, false)
+	report, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	if len(report.Results) != 2 {
+		t.Fatalf("Expected 2 results, got %+v", report.Results)
+	}
+
+	// The target with the marker is synchronized
+	if !report.Results[0].Success {
+		t.Errorf("Expected file to be synchronized to the target with the marker, got %+v", report.Results[0])
+	}
+	if _, err := os.Stat(filepath.Join(enabledDir, ".clinerules")); err != nil {
+		t.Errorf("Expected file in the target with the marker: %v", err)
+	}
+
+	// The target without the marker is skipped with a clear reason
+	result := report.Results[1]
+	if !result.Skipped || result.SkipKind != SkipNoMarker {
+		t.Errorf("Expected file to be skipped for the target without the marker, got %+v", result)
+	}
+	if result.SkipReason != "target directory has no marker file .airulesync-enabled" {
+		t.Errorf("Expected skip reason to mention the marker file, got '%s'", result.SkipReason)
+	}
+	if _, err := os.Stat(filepath.Join(disabledDir, ".clinerules")); !os.IsNotExist(err) {
+		t.Errorf("Expected no file in the target without the marker, got err=%v", err)
+	}
+}
+
+func TestSyncWithSkipPatterns(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDir := filepath.Join(tempDir, "target")
+
+	for path, content := range map[string]string{
+		".clinerules":         "# Rules\n",
+		".windsurfrules":      "# Windsurf\n",
+		".cursor/rules/a.mdc": "# A\n",
+		".cursor/rules/b.mdc": "# B\n",
+	} {
+		fullPath := filepath.Join(sourceDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{
+				Path: sourceDir,
+				Files: []config.FileSpec{
+					{Pattern: ".clinerules"},
+					{Pattern: ".windsurfrules"},
+					{Pattern: ".cursor/rules/*.mdc"},
+				},
+			},
+		},
+		TargetDirs: []config.TargetDir{{Path: targetDir}},
+	}
+
+	// Skip one file spec by its pattern and one file by its relative path
+	syncer := NewSyncer(cfg, false, false)
+	syncer.SkipPatterns = []string{".cursor/rules/*.mdc", ".windsurf*"}
+	report, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	if len(report.Results) != 1 || !report.Results[0].Success {
+		t.Fatalf("Expected only .clinerules to be synchronized, got %+v", report.Results)
+	}
+	if _, err := os.Stat(filepath.Join(targetDir, ".clinerules")); err != nil {
+		t.Errorf("Expected .clinerules in the target: %v", err)
+	}
+	for _, skipped := range []string{".windsurfrules", ".cursor/rules/a.mdc", ".cursor/rules/b.mdc"} {
+		if _, err := os.Stat(filepath.Join(targetDir, skipped)); !os.IsNotExist(err) {
+			t.Errorf("Expected no %s in the target, got err=%v", skipped, err)
+		}
+	}
+}
+
+func TestNewPlan(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDir := filepath.Join(tempDir, "target")
+
+	for path, content := range map[string]string{
+		filepath.Join(sourceDir, ".clinerules"):    "# Rules\n",
+		filepath.Join(sourceDir, ".windsurfrules"): "# Windsurf\n",
+		filepath.Join(sourceDir, ".roomodes"):      "# Modes\n",
+		filepath.Join(targetDir, ".windsurfrules"): "# Old windsurf\n",
+		filepath.Join(targetDir, ".roomodes"):      "# Local modes\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	// One file is new, one replaces a differing target and one must not
+	// overwrite its target
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{
+				Path: sourceDir,
+				Files: []config.FileSpec{
+					{Pattern: ".clinerules"},
+					{Pattern: ".windsurfrules"},
+					{Pattern: ".roomodes", Overwrite: config.OverwriteNever},
+				},
+			},
+		},
+		TargetDirs: []config.TargetDir{{Path: targetDir}},
+	}
+
+	syncer := NewSyncer(cfg, true, false)
+	report, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	plan := NewPlan(report)
+	if plan.Version != PlanVersion {
+		t.Errorf("Expected plan version %d, got %d", PlanVersion, plan.Version)
+	}
+
+	hash := func(content string) string {
+		sum := sha256.Sum256([]byte(content))
+		return hex.EncodeToString(sum[:])
+	}
+	expected := []PlanEntry{
+		{Action: PlanCreate, Source: filepath.Join(sourceDir, ".clinerules"), Target: filepath.Join(targetDir, ".clinerules"), Changed: true, SHA256: hash("# Rules\n")},
+		{Action: PlanOverwrite, Source: filepath.Join(sourceDir, ".windsurfrules"), Target: filepath.Join(targetDir, ".windsurfrules"), Changed: true, SHA256: hash("# Windsurf\n")},
+		{Action: PlanSkip, Source: filepath.Join(sourceDir, ".roomodes"), Target: filepath.Join(targetDir, ".roomodes"), Reason: "file exists and overwrite=false"},
+	}
+	if !reflect.DeepEqual(plan.Actions, expected) {
+		t.Errorf("Expected plan actions %+v, got %+v", expected, plan.Actions)
+	}
+
+	// Nothing is written by the dry-run
+	if _, err := os.Stat(filepath.Join(targetDir, ".clinerules")); !os.IsNotExist(err) {
+		t.Errorf("Expected no file to be written, got err=%v", err)
+	}
+}
+
+func TestSyncCreatesTargetDirsUpFront(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	sourceDir := filepath.Join(tempDir, "source")
+	openDir := filepath.Join(tempDir, "open")
+	blockedDir := filepath.Join(tempDir, "blocked", "target")
+
+	if err := os.MkdirAll(filepath.Join(sourceDir, "docs"), 0755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+	for path, content := range map[string]string{
+		filepath.Join(sourceDir, "docs", "rules.md"): "# Rules\n",
+		filepath.Join(tempDir, "blocked"):            "a file where a directory should be",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{
+				Path:  sourceDir,
+				Files: []config.FileSpec{{Pattern: "docs/rules.md"}},
+			},
+		},
+		TargetDirs: []config.TargetDir{{Path: openDir}, {Path: blockedDir}},
+	}
+
+	syncer := NewSyncer(cfg, false, false)
+	report, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	// Files of the blocked directory fail with the error of the pre-pass
+	if len(report.Results) != 2 {
+		t.Fatalf("Expected 2 results, got %+v", report.Results)
+	}
+	if !report.Results[0].Success {
+		t.Errorf("Expected the open target to be synchronized, got error=%v", report.Results[0].Error)
+	}
+	expected := fmt.Sprintf("failed to create target directory %s: %s exists and is not a directory",
+		filepath.Join(blockedDir, "docs"), filepath.Join(tempDir, "blocked"))
+	if err := report.Results[1].Error; err == nil || err.Error() != expected {
+		t.Errorf("Expected error %q, got %v", expected, err)
+	}
+}
+
+func TestSyncRemovesUnusedTargetDirs(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDir := filepath.Join(tempDir, "target")
+
+	for _, name := range []string{"a", "b", "c"} {
+		path := filepath.Join(sourceDir, name, "rules.md")
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("# Rules\n"), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{
+				Path:  sourceDir,
+				Files: []config.FileSpec{{Pattern: "*/rules.md"}},
+			},
+		},
+		TargetDirs: []config.TargetDir{{Path: targetDir}},
+	}
+
+	syncer := NewSyncer(cfg, false, false)
+	syncer.Limit = 1
+	if _, err := syncer.Sync(); err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	// Only the directory of the written file is left
+	entries, err := os.ReadDir(targetDir)
+	if err != nil {
+		t.Fatalf("Failed to read target directory: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if strings.Join(names, ",") != "a" {
+		t.Errorf("Expected only the directory 'a' in the target, got %v", names)
+	}
+}
+
+func TestSyncWithNoCreateDirs(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	existingDir := filepath.Join(tempDir, "existing")
+	missingDir := filepath.Join(tempDir, "missing")
+
+	for _, dir := range []string{sourceDir, existingDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+	}
+	files := map[string]string{
+		filepath.Join(sourceDir, ".clinerules"):          "# Rules\n",
+		filepath.Join(sourceDir, ".cursor/rules/go.mdc"): "# Go\n",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{
+				Path:  sourceDir,
+				Files: []config.FileSpec{{Pattern: ".clinerules"}, {Pattern: ".cursor/rules/*.mdc"}},
+			},
+		},
+		TargetDirs: []config.TargetDir{
+			{Path: existingDir},
+			{Path: missingDir},
+		},
+	}
+
+	syncer := NewSyncer(cfg, false, false)
+	syncer.NoCreateDirs = true
+	report, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	// The missing target directory is reported instead of created
+	if _, err := os.Stat(missingDir); !os.IsNotExist(err) {
+		t.Errorf("Expected missing target directory not to be created, got %v", err)
+	}
+	for _, result := range report.Results {
+		if !strings.HasPrefix(result.TargetFile, missingDir) {
+			continue
+		}
+		if !result.Skipped || result.SkipKind != SkipMissingDir {
+			t.Errorf("Expected '%s' to be skipped for a missing directory, got %+v", result.TargetFile, result)
+		}
+		if expected := "target directory " + missingDir + " does not exist"; result.SkipReason != expected {
+			t.Errorf("Expected skip reason '%s', got '%s'", expected, result.SkipReason)
+		}
+	}
+
+	// Directories below an existing target directory are still created
+	for _, path := range []string{".clinerules", ".cursor/rules/go.mdc"} {
+		if _, err := os.Stat(filepath.Join(existingDir, path)); err != nil {
+			t.Errorf("Expected '%s' to be synchronized to the existing target: %v", path, err)
+		}
+	}
+}
+
+func TestSyncWithTargetPrefix(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDir := filepath.Join(tempDir, "target")
+
+	for _, dir := range []string{sourceDir, targetDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, ".clinerules"), []byte("See [guide](./docs/guide.md)\n"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{
+				Path:         sourceDir,
+				Files:        []config.FileSpec{{Pattern: ".clinerules"}},
+				TargetPrefix: "rules",
+			},
+		},
+		TargetDirs: []config.TargetDir{{Path: targetDir}},
+	}
+
+	syncer := NewSyncer(cfg, false, false)
+	report, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	// The file lands below the prefix in the target directory
+	expectedPath := filepath.Join(targetDir, "rules", ".clinerules")
+	if len(report.Results) != 1 || report.Results[0].TargetFile != expectedPath {
+		t.Fatalf("Expected target file %s, got %+v", expectedPath, report.Results)
+	}
+
+	// Paths are adjusted relative to the prefixed directory
+	content, err := os.ReadFile(expectedPath)
+	if err != nil {
+		t.Fatalf("Failed to read target file: %v", err)
+	}
+	expected := "See [guide](../../source/docs/guide.md)\n"
+	if string(content) != expected {
+		t.Errorf("Expected content %q, got %q", expected, content)
+	}
+}
+
+func TestSyncWithTargetIgnoreGlobs(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	sourceDir := filepath.Join(tempDir, "source")
+	files := []string{
+		".clinerules",
+		".cursor/rules/general.mdc",
+		".cursor/rules/lang/go.mdc",
+		"docs/private.md",
+		"docs/public.md",
+	}
+	for _, name := range files {
+		path := filepath.Join(sourceDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("# Rules\n"), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	// Test cases for target ignore patterns
+	testCases := []struct {
+		name        string
+		ignoreFiles []string
+		ignored     []string
+	}{
+		{
+			name:        "nested glob",
+			ignoreFiles: []string{"**/*.mdc"},
+			ignored:     []string{".cursor/rules/general.mdc", ".cursor/rules/lang/go.mdc"},
+		},
+		{
+			name:        "base name",
+			ignoreFiles: []string{"private.md"},
+			ignored:     []string{"docs/private.md"},
+		},
+		{
+			name:        "base name glob",
+			ignoreFiles: []string{"go.*"},
+			ignored:     []string{".cursor/rules/lang/go.mdc"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			targetDir := filepath.Join(tempDir, strings.ReplaceAll(tc.name, " ", "-"))
+			cfg := &config.Config{
+				SourceDirs: []config.SourceDir{
+					{
+						Path: sourceDir,
+						Files: []config.FileSpec{
+							{Pattern: ".clinerules"},
+							{Pattern: ".cursor"},
+							{Pattern: "docs"},
+						},
+					},
+				},
+				TargetDirs: []config.TargetDir{
+					{Path: targetDir, IgnoreFiles: tc.ignoreFiles},
+				},
+			}
+
+			if _, err := NewSyncer(cfg, false, false).Sync(); err != nil {
+				t.Fatalf("Failed to sync: %v", err)
+			}
+
+			ignored := make(map[string]bool)
+			for _, name := range tc.ignored {
+				ignored[name] = true
+			}
+
+			for _, name := range files {
+				_, err := os.Stat(filepath.Join(targetDir, filepath.FromSlash(name)))
+				if ignored[name] && !os.IsNotExist(err) {
+					t.Errorf("Expected '%s' to be ignored by %v, got err=%v", name, tc.ignoreFiles, err)
+				}
+				if !ignored[name] && err != nil {
+					t.Errorf("Expected '%s' to be synchronized: %v", name, err)
+				}
+			}
+		})
+	}
+}
+
 func TestSyncFileDryRun(t *testing.T) {
 	// Create a temporary directory structure for testing
 	tempDir := t.TempDir()
@@ -320,3 +825,2780 @@ func TestSyncFileDryRun(t *testing.T) {
 		t.Errorf("Target file exists, but it should not in dry-run mode")
 	}
 }
+
+func TestSyncDetectsTargetCollision(t *testing.T) {
+	// Test cases for collision handling
+	testCases := []struct {
+		name   string
+		strict bool
+	}{
+		{
+			name:   "warning",
+			strict: false,
+		},
+		{
+			name:   "strict",
+			strict: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Create a temporary directory structure for testing
+			tempDir := t.TempDir()
+
+			// Create two source directories that both provide a .clinerules file
+			sourceDirA := filepath.Join(tempDir, "source-a")
+			sourceDirB := filepath.Join(tempDir, "source-b")
+			targetDir := filepath.Join(tempDir, "target")
+
+			for _, dir := range []string{sourceDirA, sourceDirB, targetDir} {
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					t.Fatalf("Failed to create test directory: %v", err)
+				}
+			}
+
+			files := map[string]string{
+				filepath.Join(sourceDirA, ".clinerules"): "# Source A\n",
+				filepath.Join(sourceDirB, ".clinerules"): "# Source B\n",
+			}
+			for path, content := range files {
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatalf("Failed to write test file: %v", err)
+				}
+			}
+
+			// Create a test configuration where two file specs produce the same target
+			cfg := &config.Config{
+				SourceDirs: []config.SourceDir{
+					{
+						Path:  sourceDirA,
+						Files: []config.FileSpec{{Pattern: "*"}},
+					},
+					{
+						Path:  sourceDirB,
+						Files: []config.FileSpec{{Pattern: ".clinerules"}},
+					},
+				},
+				TargetDirs: []config.TargetDir{
+					{
+						Path: targetDir,
+					},
+				},
+			}
+
+			// Run the synchronization
+			syncer := NewSyncer(cfg, false, false)
+			syncer.Strict = tc.strict
+			report, err := syncer.Sync()
+			if err != nil {
+				t.Fatalf("Failed to sync: %v", err)
+			}
+
+			if len(report.Results) != 2 {
+				t.Fatalf("Expected 2 results, got %d", len(report.Results))
+			}
+
+			// The first write succeeds
+			if !report.Results[0].Success {
+				t.Errorf("Expected first write to succeed, but it failed: %v", report.Results[0].Error)
+			}
+
+			// The second write is flagged as a collision with the first source
+			collision := report.Results[1]
+			if collision.ConflictsWith != filepath.Join(sourceDirA, ".clinerules") {
+				t.Errorf("Expected collision with the first source, got '%s'", collision.ConflictsWith)
+			}
+
+			if tc.strict {
+				if collision.Error == nil {
+					t.Errorf("Expected collision to be reported as an error in strict mode")
+				}
+			} else {
+				if collision.Error != nil || !collision.Skipped {
+					t.Errorf("Expected collision to be skipped with a warning, got error: %v", collision.Error)
+				}
+				var out strings.Builder
+				syncer.Out = &out
+				syncer.PrintReport(report, false)
+				if !strings.Contains(out.String(), "Warning: Target collisions detected: 1") {
+					t.Errorf("Expected collision warning in report, got:\n%s", out.String())
+				}
+			}
+
+			// The target keeps the content of the first source
+			content, err := os.ReadFile(filepath.Join(targetDir, ".clinerules"))
+			if err != nil {
+				t.Fatalf("Failed to read target file: %v", err)
+			}
+			if string(content) != "# Source A\n" {
+				t.Errorf("Expected target content to come from the first source, got '%s'", string(content))
+			}
+		})
+	}
+}
+
+func TestSyncWithSourcePriority(t *testing.T) {
+	// Test cases for the priorities of a base and an override source
+	testCases := []struct {
+		name             string
+		basePriority     int
+		overridePriority int
+		expected         string
+	}{
+		{
+			name:     "configured order without priorities",
+			expected: "# Base\n",
+		},
+		{
+			name:             "override with higher priority",
+			overridePriority: 10,
+			expected:         "# Override\n",
+		},
+		{
+			name:             "base with higher priority",
+			basePriority:     5,
+			overridePriority: -1,
+			expected:         "# Base\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			baseDir := filepath.Join(tempDir, "base")
+			overrideDir := filepath.Join(tempDir, "override")
+			targetDir := filepath.Join(tempDir, "target")
+
+			files := map[string]string{
+				filepath.Join(baseDir, ".clinerules"):     "# Base\n",
+				filepath.Join(overrideDir, ".clinerules"): "# Override\n",
+			}
+			for path, content := range files {
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatalf("Failed to create test directory: %v", err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatalf("Failed to write test file: %v", err)
+				}
+			}
+
+			// Both sources write the same target file
+			cfg := &config.Config{
+				SourceDirs: []config.SourceDir{
+					{
+						Path:     baseDir,
+						Priority: tc.basePriority,
+						Files:    []config.FileSpec{{Pattern: ".clinerules"}},
+					},
+					{
+						Path:     overrideDir,
+						Priority: tc.overridePriority,
+						Files:    []config.FileSpec{{Pattern: ".clinerules"}},
+					},
+				},
+				TargetDirs: []config.TargetDir{
+					{
+						Path: targetDir,
+					},
+				},
+			}
+
+			// Collisions resolved by priority aren't reported, even in
+			// strict mode
+			resolved := tc.basePriority != tc.overridePriority
+			syncer := NewSyncer(cfg, false, false)
+			syncer.Strict = resolved
+			report, err := syncer.Sync()
+			if err != nil {
+				t.Fatalf("Failed to sync: %v", err)
+			}
+
+			content, err := os.ReadFile(filepath.Join(targetDir, ".clinerules"))
+			if err != nil {
+				t.Fatalf("Failed to read target file: %v", err)
+			}
+			if string(content) != tc.expected {
+				t.Errorf("Expected target content '%s', got '%s'", tc.expected, string(content))
+			}
+
+			if resolved {
+				if len(report.Warnings) > 0 || len(report.Conflicts) > 0 || report.Err() != nil {
+					t.Errorf("Expected no collision to be reported, got warnings=%v conflicts=%v err=%v", report.Warnings, report.Conflicts, report.Err())
+				}
+				if len(report.Results) != 2 || report.Results[1].SkipKind != SkipOverridden {
+					t.Errorf("Expected the lower priority file to be overridden, got %+v", report.Results)
+				}
+			} else if len(report.Warnings) != 1 || len(report.Conflicts) != 1 {
+				t.Errorf("Expected the collision to be reported, got warnings=%v conflicts=%v", report.Warnings, report.Conflicts)
+			}
+
+			// The configuration keeps its declared order
+			if cfg.SourceDirs[0].Path != baseDir {
+				t.Errorf("Expected the configured source directories to be left in order")
+			}
+		})
+	}
+}
+
+func TestSyncFileWithFrontmatterOverrides(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	// Create test directories
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDir := filepath.Join(tempDir, "target")
+
+	for _, dir := range []string{sourceDir, targetDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+	}
+
+	// Create a rule file with front matter
+	sourceFile := filepath.Join(sourceDir, "rule.mdc")
+	content := `---
+description: Go coding rules
+globs: "*.go"
+alwaysApply: false
+---
+# Go rules
+`
+	if err := os.WriteFile(sourceFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	// Create a test configuration forcing alwaysApply in the target
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{
+				Path: sourceDir,
+				Files: []config.FileSpec{
+					{Pattern: "rule.mdc"},
+				},
+			},
+		},
+		TargetDirs: []config.TargetDir{
+			{
+				Path:                 targetDir,
+				FrontmatterOverrides: map[string]interface{}{"alwaysApply": true},
+			},
+		},
+	}
+
+	// Create a file info
+	fileInfo := scanner.FileInfo{
+		SourcePath:   sourceFile,
+		SourceDir:    sourceDir,
+		RelativePath: "rule.mdc",
+		Pattern:      "rule.mdc",
+		AdjustPaths:  true,
+		Overwrite:    true,
+	}
+
+	// Sync the file
+	syncer := NewSyncer(cfg, false, false)
+	result := syncer.syncFile(fileInfo, cfg.TargetDirs[0])
+	if !result.Success {
+		t.Fatalf("Expected sync to succeed, but it failed: %v", result.Error)
+	}
+
+	// Verify that alwaysApply was forced while other keys were preserved
+	syncedContent, err := os.ReadFile(filepath.Join(targetDir, "rule.mdc"))
+	if err != nil {
+		t.Fatalf("Failed to read synced file: %v", err)
+	}
+
+	expectedContent := `---
+description: Go coding rules
+globs: "*.go"
+alwaysApply: true
+---
+# Go rules
+`
+	if string(syncedContent) != expectedContent {
+		t.Errorf("Expected synced content:\n%s\n\nGot:\n%s", expectedContent, string(syncedContent))
+	}
+
+	// Verify that the source file was not modified
+	sourceContent, err := os.ReadFile(sourceFile)
+	if err != nil {
+		t.Fatalf("Failed to read source file: %v", err)
+	}
+	if string(sourceContent) != content {
+		t.Errorf("Expected source content to remain unchanged")
+	}
+}
+
+func TestSyncWithSourceGlobTwice(t *testing.T) {
+	// Create a monorepo where one package holds the rules of all others
+	tempDir := t.TempDir()
+	apiDir := filepath.Join(tempDir, "packages", "api")
+	webDir := filepath.Join(tempDir, "packages", "web")
+
+	if err := os.MkdirAll(apiDir, 0755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(apiDir, ".clinerules"), []byte("# API rules\n"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	cfg := &config.Config{
+		SourceGlob: filepath.Join(tempDir, "**", ".clinerules"),
+		TargetDirs: []config.TargetDir{{Path: webDir}},
+	}
+
+	// The copy written by the first run is not a source of the second
+	for run := 1; run <= 2; run++ {
+		syncer := NewSyncer(cfg, false, false)
+		report, err := syncer.Sync()
+		if err != nil {
+			t.Fatalf("Failed to sync run %d: %v", run, err)
+		}
+
+		if len(report.Results) != 1 || report.Results[0].SourceFile != filepath.Join(apiDir, ".clinerules") {
+			t.Errorf("Expected run %d to only synchronize the api rules, got %+v", run, report.Results)
+		}
+		if len(report.Warnings) > 0 {
+			t.Errorf("Expected no warnings in run %d, got %v", run, report.Warnings)
+		}
+	}
+}
+
+func TestSyncWithGlobBase(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	// Rule files live under the repository root while paths inside them are
+	// relative to the docs directory
+	repoDir := filepath.Join(tempDir, "repo")
+	docsDir := filepath.Join(repoDir, "docs")
+	rulesDir := filepath.Join(repoDir, "rules")
+	targetDir := filepath.Join(repoDir, "sub-project")
+
+	for _, dir := range []string{docsDir, rulesDir, targetDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+	}
+
+	sourceFile := filepath.Join(rulesDir, "go.md")
+	if err := os.WriteFile(sourceFile, []byte("[Guide](./guide.md)\n"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	// Create a test configuration with a glob base differing from the path
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{
+				Path:     docsDir,
+				GlobBase: repoDir,
+				Files:    []config.FileSpec{{Pattern: "rules/*.md"}},
+			},
+		},
+		TargetDirs: []config.TargetDir{
+			{
+				Path: targetDir,
+			},
+		},
+	}
+
+	// Run the synchronization
+	syncer := NewSyncer(cfg, false, false)
+	report, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	if len(report.Results) != 1 || !report.Results[0].Success {
+		t.Fatalf("Expected a single successful result, got %+v", report.Results)
+	}
+
+	// The pattern is matched against the glob base and keeps its layout in the target
+	syncedContent, err := os.ReadFile(filepath.Join(targetDir, "rules", "go.md"))
+	if err != nil {
+		t.Fatalf("Failed to read synced file: %v", err)
+	}
+
+	// Paths are adjusted relative to the source path, not the glob base
+	expectedContent := "[Guide](../docs/guide.md)\n"
+	if string(syncedContent) != expectedContent {
+		t.Errorf("Expected synced content '%s', got '%s'", expectedContent, string(syncedContent))
+	}
+}
+
+func TestSyncFileToStdout(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	// Create test directories
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDir := filepath.Join(tempDir, "target")
+
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+
+	// Create a test file to sync
+	sourceFile := filepath.Join(sourceDir, ".clinerules")
+	content := "import \"./relative/path/file.js\"\n"
+	if err := os.WriteFile(sourceFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	// Create a test configuration
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{
+				Path: sourceDir,
+				Files: []config.FileSpec{
+					{Pattern: ".clinerules"},
+				},
+			},
+		},
+		TargetDirs: []config.TargetDir{
+			{
+				Path: targetDir,
+			},
+		},
+	}
+
+	// Create a file info
+	fileInfo := scanner.FileInfo{
+		SourcePath:   sourceFile,
+		SourceDir:    sourceDir,
+		RelativePath: ".clinerules",
+		Pattern:      ".clinerules",
+		AdjustPaths:  true,
+		Overwrite:    true,
+	}
+
+	// Create a syncer printing to a buffer
+	var out strings.Builder
+	syncer := NewSyncer(cfg, false, false)
+	syncer.Stdout = true
+	syncer.Out = &out
+
+	// Sync the file
+	result := syncer.syncFile(fileInfo, cfg.TargetDirs[0])
+	if !result.Success {
+		t.Fatalf("Expected sync to succeed, but it failed: %v", result.Error)
+	}
+
+	// Verify the printed content
+	targetFile := filepath.Join(targetDir, ".clinerules")
+	expected := "==> '" + sourceFile + "' -> '" + targetFile + "' <==\n" +
+		"import \"../source/relative/path/file.js\"\n"
+	if out.String() != expected {
+		t.Errorf("Expected output:\n%s\n\nGot:\n%s", expected, out.String())
+	}
+
+	// Verify that nothing was written
+	if _, err := os.Stat(targetDir); !os.IsNotExist(err) {
+		t.Errorf("Target directory exists, but it should not in stdout mode")
+	}
+}
+
+func TestPrintAdjustmentReport(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	// Create test directories
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDir := filepath.Join(tempDir, "target")
+
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+
+	// Create one file with a relative path and two without adjustments
+	files := map[string]string{
+		".clinerules":   "[Guide](./guide.md)\n",
+		".roomodes":     "[Guide](./guide.md)\n",
+		".cursorignore": "# No paths here\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(sourceDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	// Create a test configuration where .roomodes is copied verbatim
+	falseValue := false
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{
+				Path: sourceDir,
+				Files: []config.FileSpec{
+					{Pattern: ".clinerules"},
+					{Pattern: ".roomodes", AdjustPaths: &falseValue},
+					{Pattern: ".cursorignore"},
+				},
+			},
+		},
+		TargetDirs: []config.TargetDir{
+			{
+				Path: targetDir,
+			},
+		},
+	}
+
+	// Run the synchronization
+	syncer := NewSyncer(cfg, false, false)
+	report, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	// Print the adjustment report
+	var out strings.Builder
+	syncer.Out = &out
+	syncer.PrintAdjustmentReport(report, false)
+
+	expected := "'" + filepath.Join(sourceDir, ".clinerules") + "' -> '" + filepath.Join(targetDir, ".clinerules") + "'\n" +
+		"  - Line 1: './guide.md' -> '../source/guide.md'\n"
+	if out.String() != expected {
+		t.Errorf("Expected report:\n%s\n\nGot:\n%s", expected, out.String())
+	}
+}
+
+func TestPrintReportFollowsConfigOrder(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	// Declare sources and targets in reverse alphabetical order
+	sourceDirZ := filepath.Join(tempDir, "z-rules")
+	sourceDirA := filepath.Join(tempDir, "a-rules")
+	targetDirY := filepath.Join(tempDir, "y-target")
+	targetDirB := filepath.Join(tempDir, "b-target")
+
+	for _, dir := range []string{sourceDirZ, sourceDirA} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, ".clinerules"), []byte("# Rules\n"), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{Path: sourceDirZ, Files: []config.FileSpec{{Pattern: ".clinerules"}}},
+			{Path: sourceDirA, Files: []config.FileSpec{{Pattern: ".clinerules"}}},
+		},
+		TargetDirs: []config.TargetDir{
+			{Path: targetDirY},
+			{Path: targetDirB},
+		},
+	}
+
+	// Run a dry-run synchronization and print the report
+	syncer := NewSyncer(cfg, true, false)
+	report, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	var out strings.Builder
+	syncer.Out = &out
+	syncer.PrintReport(report, true)
+
+	// The report lists sources, then targets, in config order
+	expectedOrder := []string{
+		"'" + filepath.Join(sourceDirZ, ".clinerules") + "' -> '" + filepath.Join(targetDirY, ".clinerules") + "'",
+		"'" + filepath.Join(sourceDirZ, ".clinerules") + "' -> '" + filepath.Join(targetDirB, ".clinerules") + "'",
+		"'" + filepath.Join(sourceDirA, ".clinerules") + "' -> '" + filepath.Join(targetDirY, ".clinerules") + "'",
+		"'" + filepath.Join(sourceDirA, ".clinerules") + "' -> '" + filepath.Join(targetDirB, ".clinerules") + "'",
+	}
+
+	last := -1
+	for _, line := range expectedOrder {
+		index := strings.Index(out.String(), line)
+		if index < 0 {
+			t.Fatalf("Expected report to contain %s, got:\n%s", line, out.String())
+		}
+		if index < last {
+			t.Errorf("Expected %s to be listed in config order, got:\n%s", line, out.String())
+		}
+		last = index
+	}
+}
+
+func TestSyncDryRunReportsUnwritableTargets(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	sourceDir := filepath.Join(tempDir, "source")
+	readOnlyDir := filepath.Join(tempDir, "read-only")
+	blockedDir := filepath.Join(tempDir, "blocked")
+	writableDir := filepath.Join(tempDir, "writable", "nested")
+
+	for _, dir := range []string{sourceDir, readOnlyDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+	}
+
+	files := map[string]string{
+		filepath.Join(sourceDir, ".clinerules"): "# Test clinerules file\n",
+		blockedDir:                              "a regular file where a directory should be",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	// Make the read-only target read-only; root can write anyway
+	if err := os.Chmod(readOnlyDir, 0555); err != nil {
+		t.Fatalf("Failed to make directory read-only: %v", err)
+	}
+	t.Cleanup(func() { os.Chmod(readOnlyDir, 0755) })
+	readOnlyEnforced := os.Geteuid() != 0
+
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{Path: sourceDir, Files: []config.FileSpec{{Pattern: ".clinerules"}}},
+		},
+		TargetDirs: []config.TargetDir{
+			{Path: readOnlyDir},
+			{Path: blockedDir},
+			{Path: writableDir},
+		},
+	}
+
+	// Run a dry-run synchronization
+	syncer := NewSyncer(cfg, true, false)
+	report, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	if len(report.Results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(report.Results))
+	}
+
+	if readOnlyEnforced && report.Results[0].Error == nil {
+		t.Errorf("Expected read-only target to be reported as not writable")
+	}
+
+	if report.Results[1].Error == nil || !strings.Contains(report.Results[1].Error.Error(), "not writable") {
+		t.Errorf("Expected blocked target to be reported as not writable, got %v", report.Results[1].Error)
+	}
+
+	if report.Results[2].Error != nil {
+		t.Errorf("Expected missing target under a writable directory to pass, got %v", report.Results[2].Error)
+	}
+
+	// The dry-run leaves no probe files behind
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to read directory: %v", err)
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".airulesync-probe-") {
+			t.Errorf("Expected probe file to be removed, found %s", entry.Name())
+		}
+	}
+}
+
+func TestSyncWithLimit(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDir := filepath.Join(tempDir, "target")
+
+	for _, dir := range []string{sourceDir, targetDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+	}
+
+	names := []string{"a.md", "b.md", "c.md"}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(sourceDir, name), []byte("# "+name+"\n"), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{
+				Path:  sourceDir,
+				Files: []config.FileSpec{{Pattern: "*.md"}},
+			},
+		},
+		TargetDirs: []config.TargetDir{
+			{
+				Path: targetDir,
+			},
+		},
+	}
+
+	// Only the first two files are written
+	syncer := NewSyncer(cfg, false, false)
+	syncer.Limit = 2
+	report, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	written := 0
+	deferred := 0
+	for _, result := range report.Results {
+		if result.Changed {
+			written++
+		}
+		if result.Skipped && strings.HasPrefix(result.SkipReason, "deferred") {
+			deferred++
+		}
+	}
+
+	if written != 2 || deferred != 1 {
+		t.Errorf("Expected 2 written and 1 deferred files, got %d written and %d deferred", written, deferred)
+	}
+
+	if _, err := os.Stat(filepath.Join(targetDir, "c.md")); !os.IsNotExist(err) {
+		t.Errorf("Expected deferred file not to be written, got err=%v", err)
+	}
+
+	// The next run resumes with the deferred file, as unchanged files don't count
+	report, err = syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	for _, result := range report.Results {
+		if result.Skipped {
+			t.Errorf("Expected no skipped files on the second run, got '%s' (%s)", result.SourceFile, result.SkipReason)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(targetDir, "c.md")); err != nil {
+		t.Errorf("Expected deferred file to be written on the second run: %v", err)
+	}
+}
+
+func TestPrintTree(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDirA := filepath.Join(tempDir, "target-a")
+	targetDirB := filepath.Join(tempDir, "target-b")
+
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+
+	for _, name := range []string{".clinerules", ".roomodes"} {
+		if err := os.WriteFile(filepath.Join(sourceDir, name), []byte("# Rules\n"), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{
+				Path:  sourceDir,
+				Files: []config.FileSpec{{Pattern: ".clinerules"}, {Pattern: ".roomodes"}},
+			},
+		},
+		TargetDirs: []config.TargetDir{
+			{
+				Path: targetDirA,
+			},
+			{
+				Path:        targetDirB,
+				IgnoreFiles: []string{".roomodes"},
+			},
+		},
+	}
+
+	var out strings.Builder
+	syncer := NewSyncer(cfg, false, false)
+	syncer.Out = &out
+
+	if err := syncer.PrintTree(); err != nil {
+		t.Fatalf("Failed to print tree: %v", err)
+	}
+
+	// Ignored targets are not part of the mapping
+	expected := sourceDir + "\n" +
+		"├── .clinerules\n" +
+		"│   ├── -> " + filepath.Join(targetDirA, ".clinerules") + "\n" +
+		"│   └── -> " + filepath.Join(targetDirB, ".clinerules") + "\n" +
+		"└── .roomodes\n" +
+		"    └── -> " + filepath.Join(targetDirA, ".roomodes") + "\n"
+
+	if out.String() != expected {
+		t.Errorf("Expected tree:\n%s\ngot:\n%s", expected, out.String())
+	}
+
+	// Nothing is written
+	if _, err := os.Stat(targetDirA); !os.IsNotExist(err) {
+		t.Errorf("Expected no target directory to be created, got err=%v", err)
+	}
+}
+
+func TestSyncWithOverwritePrompt(t *testing.T) {
+	// Test cases for prompt answers
+	testCases := []struct {
+		name        string
+		interactive bool
+		answers     string
+		expected    map[string]bool
+	}{
+		{
+			name:        "scripted answers",
+			interactive: true,
+			answers:     "y\nn\n",
+			expected:    map[string]bool{"a.md": true, "b.md": false, "c.md": false},
+		},
+		{
+			name:        "not a terminal",
+			interactive: false,
+			answers:     "y\ny\ny\n",
+			expected:    map[string]bool{"a.md": false, "b.md": false, "c.md": false},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			sourceDir := filepath.Join(tempDir, "source")
+			targetDir := filepath.Join(tempDir, "target")
+
+			for _, dir := range []string{sourceDir, targetDir} {
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					t.Fatalf("Failed to create test directory: %v", err)
+				}
+			}
+
+			// Every target file exists with differing content
+			for name := range tc.expected {
+				if err := os.WriteFile(filepath.Join(sourceDir, name), []byte("# Source\n"), 0644); err != nil {
+					t.Fatalf("Failed to write source file: %v", err)
+				}
+				if err := os.WriteFile(filepath.Join(targetDir, name), []byte("# Target\n"), 0644); err != nil {
+					t.Fatalf("Failed to write target file: %v", err)
+				}
+			}
+
+			cfg := &config.Config{
+				SourceDirs: []config.SourceDir{
+					{
+						Path:      sourceDir,
+						Overwrite: config.OverwritePrompt,
+						Files:     []config.FileSpec{{Pattern: "*.md"}},
+					},
+				},
+				TargetDirs: []config.TargetDir{
+					{
+						Path: targetDir,
+					},
+				},
+			}
+
+			var out, prompt strings.Builder
+			syncer := NewSyncer(cfg, false, false)
+			syncer.Out = &out
+			syncer.Prompt = &prompt
+			syncer.In = strings.NewReader(tc.answers)
+			syncer.Interactive = tc.interactive
+
+			if _, err := syncer.Sync(); err != nil {
+				t.Fatalf("Failed to sync: %v", err)
+			}
+
+			for name, overwritten := range tc.expected {
+				content, err := os.ReadFile(filepath.Join(targetDir, name))
+				if err != nil {
+					t.Fatalf("Failed to read target file: %v", err)
+				}
+
+				expected := "# Target\n"
+				if overwritten {
+					expected = "# Source\n"
+				}
+				if string(content) != expected {
+					t.Errorf("Expected '%s' to contain '%s', got '%s'", name, expected, string(content))
+				}
+			}
+
+			// Prompts are only shown on a terminal, apart from the output
+			prompts := strings.Count(prompt.String(), "[y/N]")
+			if tc.interactive && prompts != 3 {
+				t.Errorf("Expected 3 prompts, got %d:\n%s", prompts, prompt.String())
+			}
+			if !tc.interactive && prompts != 0 {
+				t.Errorf("Expected no prompts, got %d:\n%s", prompts, prompt.String())
+			}
+			if strings.Contains(out.String(), "[y/N]") {
+				t.Errorf("Expected no prompts in the output, got:\n%s", out.String())
+			}
+		})
+	}
+}
+
+func TestSyncWithOverwriteIfNewer(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDir := filepath.Join(tempDir, "target")
+
+	for _, dir := range []string{sourceDir, targetDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+	}
+
+	// The source of a.md and the target of b.md were modified last, and c.md
+	// has no target yet
+	older := time.Now().Add(-time.Hour)
+	newer := time.Now()
+	files := map[string][2]time.Time{
+		"a.md": {newer, older},
+		"b.md": {older, newer},
+	}
+	for name, modTimes := range files {
+		sourcePath := filepath.Join(sourceDir, name)
+		targetPath := filepath.Join(targetDir, name)
+		if err := os.WriteFile(sourcePath, []byte("# Source\n"), 0644); err != nil {
+			t.Fatalf("Failed to write source file: %v", err)
+		}
+		if err := os.WriteFile(targetPath, []byte("# Edited target\n"), 0644); err != nil {
+			t.Fatalf("Failed to write target file: %v", err)
+		}
+		if err := os.Chtimes(sourcePath, modTimes[0], modTimes[0]); err != nil {
+			t.Fatalf("Failed to set source modification time: %v", err)
+		}
+		if err := os.Chtimes(targetPath, modTimes[1], modTimes[1]); err != nil {
+			t.Fatalf("Failed to set target modification time: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, "c.md"), []byte("# Source\n"), 0644); err != nil {
+		t.Fatalf("Failed to write source file: %v", err)
+	}
+
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{
+				Path:      sourceDir,
+				Overwrite: config.OverwriteIfNewer,
+				Files:     []config.FileSpec{{Pattern: "*.md"}},
+			},
+		},
+		TargetDirs: []config.TargetDir{
+			{
+				Path: targetDir,
+			},
+		},
+	}
+
+	syncer := NewSyncer(cfg, false, false)
+	report, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	// Only the target modified after its source is kept
+	expected := map[string]string{
+		"a.md": "# Source\n",
+		"b.md": "# Edited target\n",
+		"c.md": "# Source\n",
+	}
+	for name, want := range expected {
+		content, err := os.ReadFile(filepath.Join(targetDir, name))
+		if err != nil {
+			t.Fatalf("Failed to read target file: %v", err)
+		}
+		if string(content) != want {
+			t.Errorf("Expected '%s' to contain '%s', got '%s'", name, want, string(content))
+		}
+	}
+
+	for _, result := range report.Results {
+		skipped := filepath.Base(result.TargetFile) == "b.md"
+		if result.Skipped != skipped {
+			t.Errorf("Expected '%s' skipped to be %v, got %v", result.TargetFile, skipped, result.Skipped)
+		}
+		if skipped && (result.SkipReason != "target is newer" || result.SkipKind != SkipOverwrite) {
+			t.Errorf("Expected skip reason 'target is newer', got '%s' (%s)", result.SkipReason, result.SkipKind)
+		}
+	}
+
+	// A second run finds the written targets unchanged rather than newer
+	report, err = syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	for _, result := range report.Results {
+		skipped := filepath.Base(result.TargetFile) == "b.md"
+		if result.Skipped != skipped {
+			t.Errorf("Expected '%s' skipped to be %v in the second run, got %v (%s)", result.TargetFile, skipped, result.Skipped, result.SkipReason)
+		}
+		if !skipped && (!result.Success || result.Changed) {
+			t.Errorf("Expected '%s' to be unchanged in the second run, got %+v", result.TargetFile, result)
+		}
+	}
+}
+
+func TestWriteManifest(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDirA := filepath.Join(tempDir, "target-a")
+	targetDirB := filepath.Join(tempDir, "target-b")
+
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+
+	sourceContent := "# Rules\n"
+	if err := os.WriteFile(filepath.Join(sourceDir, ".clinerules"), []byte(sourceContent), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{
+				Path:  sourceDir,
+				Files: []config.FileSpec{{Pattern: ".clinerules"}},
+			},
+		},
+		TargetDirs: []config.TargetDir{
+			{
+				Path: targetDirB,
+			},
+			{
+				Path: targetDirA,
+			},
+		},
+	}
+
+	syncer := NewSyncer(cfg, false, false)
+	report, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	manifestPath := filepath.Join(tempDir, ManifestFile)
+	if err := syncer.WriteManifest(report, manifestPath); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+
+	manifest, err := LoadManifest(manifestPath)
+	if err != nil {
+		t.Fatalf("Failed to load manifest: %v", err)
+	}
+
+	// SHA-256 of the synchronized content
+	expectedHash := "a3af2177809b3c009bfec4d1c507c6ee32757385e856bce9ae5ac3aec41a791b"
+
+	// Entries are sorted by path, relative to the manifest
+	expectedPaths := []string{
+		"target-a/.clinerules",
+		"target-b/.clinerules",
+	}
+
+	if len(manifest.Files) != len(expectedPaths) {
+		t.Fatalf("Expected %d manifest entries, got %+v", len(expectedPaths), manifest.Files)
+	}
+
+	for i, expectedPath := range expectedPaths {
+		entry := manifest.Files[i]
+		if entry.Path != expectedPath {
+			t.Errorf("Expected manifest entry %d to be '%s', got '%s'", i, expectedPath, entry.Path)
+		}
+		if entry.SHA256 != expectedHash {
+			t.Errorf("Expected hash of '%s' to be '%s', got '%s'", entry.Path, expectedHash, entry.SHA256)
+		}
+	}
+
+	// Entries resolve against the directory of the manifest
+	if path := manifest.Path(manifest.Files[0]); path != filepath.Join(targetDirA, ".clinerules") {
+		t.Errorf("Expected manifest entry to resolve to '%s', got '%s'", filepath.Join(targetDirA, ".clinerules"), path)
+	}
+}
+
+func TestSyncWithRewrites(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDir := filepath.Join(tempDir, "target")
+
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+
+	// A made-up include directive the built-in path detection doesn't know
+	sourceContent := "@include(shared/go.md)\n[Guide](./guide.md)\n"
+	if err := os.WriteFile(filepath.Join(sourceDir, ".clinerules"), []byte(sourceContent), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{
+				Path:  sourceDir,
+				Files: []config.FileSpec{{Pattern: ".clinerules"}},
+			},
+		},
+		TargetDirs: []config.TargetDir{
+			{
+				Path: targetDir,
+			},
+		},
+		Rewrites: []config.Rewrite{
+			{
+				Pattern:     `@include\(([^)]+)\)`,
+				Replacement: "@include(../source/$1)",
+			},
+		},
+	}
+
+	syncer := NewSyncer(cfg, false, false)
+	if _, err := syncer.Sync(); err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	syncedContent, err := os.ReadFile(filepath.Join(targetDir, ".clinerules"))
+	if err != nil {
+		t.Fatalf("Failed to read synced file: %v", err)
+	}
+
+	// The rewrite is applied along with the standard path adjustment
+	expectedContent := "@include(../source/shared/go.md)\n[Guide](../source/guide.md)\n"
+	if string(syncedContent) != expectedContent {
+		t.Errorf("Expected synced content '%s', got '%s'", expectedContent, string(syncedContent))
+	}
+}
+
+func TestSyncFailOnExternal(t *testing.T) {
+	// Test cases for internal and external targets
+	testCases := []struct {
+		name      string
+		targetDir config.TargetDir
+		expected  string
+	}{
+		{
+			name:      "internal target",
+			targetDir: config.TargetDir{Path: "sub-project"},
+		},
+		{
+			name:      "target marked external",
+			targetDir: config.TargetDir{Path: "vendored", External: true},
+			expected:  "target directory vendored is external to the repository",
+		},
+		{
+			name:      "target outside the repository",
+			targetDir: config.TargetDir{Path: "../other-repo"},
+			expected:  "target directory ../other-repo is external to the repository",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repoDir := filepath.Join(t.TempDir(), "repo")
+			if err := os.MkdirAll(repoDir, 0755); err != nil {
+				t.Fatalf("Failed to create test directory: %v", err)
+			}
+			if err := os.WriteFile(filepath.Join(repoDir, ".clinerules"), []byte("# Rules\n"), 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+			t.Chdir(repoDir)
+
+			cfg := &config.Config{
+				SourceDirs: []config.SourceDir{
+					{
+						Path:  ".",
+						Files: []config.FileSpec{{Pattern: ".clinerules"}},
+					},
+				},
+				TargetDirs: []config.TargetDir{tc.targetDir},
+			}
+
+			syncer := NewSyncer(cfg, false, false)
+			syncer.FailOnExternal = true
+			_, err := syncer.Sync()
+
+			if tc.expected == "" {
+				if err != nil {
+					t.Fatalf("Expected internal target to sync, got %v", err)
+				}
+				if _, err := os.Stat(filepath.Join(tc.targetDir.Path, ".clinerules")); err != nil {
+					t.Errorf("Expected file to be synced: %v", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Fatalf("Expected error containing '%s', got %v", tc.expected, err)
+			}
+
+			// Nothing is written when a target is forbidden
+			if _, err := os.Stat(filepath.Join(tc.targetDir.Path, ".clinerules")); !os.IsNotExist(err) {
+				t.Errorf("Expected no file to be synced, got err=%v", err)
+			}
+		})
+	}
+}
+
+func TestSyncWithSymlinkedTargetDir(t *testing.T) {
+	// Test cases for following and refusing symlinked target directories
+	testCases := []struct {
+		name          string
+		noDereference bool
+		expected      string
+	}{
+		{
+			name: "dereference",
+		},
+		{
+			name:          "no dereference",
+			noDereference: true,
+			expected:      "is a symbolic link",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			sourceDir := filepath.Join(tempDir, "source")
+			realDir := filepath.Join(tempDir, "real")
+			linkDir := filepath.Join(tempDir, "link")
+
+			for _, dir := range []string{sourceDir, realDir} {
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					t.Fatalf("Failed to create test directory: %v", err)
+				}
+			}
+			if err := os.WriteFile(filepath.Join(sourceDir, ".clinerules"), []byte("# Rules\n"), 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+			if err := os.Symlink(realDir, linkDir); err != nil {
+				t.Skipf("Symlinks not supported: %v", err)
+			}
+
+			cfg := &config.Config{
+				SourceDirs: []config.SourceDir{
+					{
+						Path:  sourceDir,
+						Files: []config.FileSpec{{Pattern: ".clinerules"}},
+					},
+				},
+				TargetDirs: []config.TargetDir{{Path: linkDir}},
+			}
+
+			syncer := NewSyncer(cfg, false, false)
+			syncer.NoDereference = tc.noDereference
+			_, err := syncer.Sync()
+
+			if tc.expected == "" {
+				if err != nil {
+					t.Fatalf("Expected symlinked target to sync, got %v", err)
+				}
+				if _, err := os.Stat(filepath.Join(realDir, ".clinerules")); err != nil {
+					t.Errorf("Expected file to be written through the link: %v", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Fatalf("Expected error containing '%s', got %v", tc.expected, err)
+			}
+			if _, err := os.Stat(filepath.Join(realDir, ".clinerules")); !os.IsNotExist(err) {
+				t.Errorf("Expected no file to be synced, got err=%v", err)
+			}
+		})
+	}
+}
+
+func TestSyncDirectoryPattern(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDir := filepath.Join(tempDir, "target")
+
+	// Rules stored as a two-level directory tree with assets
+	files := map[string]string{
+		filepath.Join(sourceDir, ".cursor", "rules", "general.mdc"):              "[Guide](./docs/guide.md)\n",
+		filepath.Join(sourceDir, ".cursor", "rules", "go", "style.mdc"):          "# Go style\n",
+		filepath.Join(sourceDir, ".cursor", "rules", "go", "assets", "logo.txt"): "logo\n",
+		filepath.Join(sourceDir, ".cursor", "rules", "go", "draft.tmp"):          "draft\n",
+	}
+
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{
+				Path:        sourceDir,
+				Files:       []config.FileSpec{{Pattern: ".cursor/rules"}},
+				IgnoreFiles: []string{"*.tmp"},
+			},
+		},
+		TargetDirs: []config.TargetDir{
+			{
+				Path: targetDir,
+			},
+		},
+	}
+
+	syncer := NewSyncer(cfg, false, false)
+	report, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	if len(report.Results) != 3 {
+		t.Errorf("Expected 3 results, got %d", len(report.Results))
+	}
+
+	// The tree is reproduced in the target with paths adjusted per file
+	expectedFiles := map[string]string{
+		filepath.Join(".cursor", "rules", "general.mdc"):              "[Guide](../source/docs/guide.md)\n",
+		filepath.Join(".cursor", "rules", "go", "style.mdc"):          "# Go style\n",
+		filepath.Join(".cursor", "rules", "go", "assets", "logo.txt"): "logo\n",
+	}
+
+	for relPath, expected := range expectedFiles {
+		content, err := os.ReadFile(filepath.Join(targetDir, relPath))
+		if err != nil {
+			t.Errorf("Expected '%s' to be synced: %v", relPath, err)
+			continue
+		}
+		if string(content) != expected {
+			t.Errorf("Expected '%s' to contain '%s', got '%s'", relPath, expected, string(content))
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(targetDir, ".cursor", "rules", "go", "draft.tmp")); !os.IsNotExist(err) {
+		t.Errorf("Expected ignored file not to be synced, got err=%v", err)
+	}
+}
+
+func TestSyncWithPrune(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDir := filepath.Join(tempDir, "target")
+
+	files := map[string]string{
+		filepath.Join(sourceDir, "rules", "a.mdc"):  "# A\n",
+		filepath.Join(sourceDir, "rules", "b.mdc"):  "# B\n",
+		filepath.Join(targetDir, "rules", "own.md"): "# Not within a pattern scope\n",
+		filepath.Join(targetDir, "rules", "x.mdc"):  "# Kept by the target\n",
+		filepath.Join(targetDir, "rules", "h.mdc"):  "# Written by hand\n",
+	}
+
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{
+				Path:  sourceDir,
+				Files: []config.FileSpec{{Pattern: "rules/*.mdc"}},
+			},
+		},
+		TargetDirs: []config.TargetDir{
+			{
+				Path:        targetDir,
+				IgnoreFiles: []string{"rules/x.mdc"},
+			},
+		},
+	}
+
+	syncer := NewSyncer(cfg, false, false)
+	syncer.Prune = true
+	syncer.ManifestPath = filepath.Join(tempDir, ManifestFile)
+	report, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	// Record the synchronized files, which limits pruning to them
+	if err := syncer.WriteManifest(report, syncer.ManifestPath); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+
+	// Remove a source file and synchronize again
+	if err := os.Remove(filepath.Join(sourceDir, "rules", "b.mdc")); err != nil {
+		t.Fatalf("Failed to remove source file: %v", err)
+	}
+
+	// A dry run only reports the stale file
+	syncer.DryRun = true
+	report, err = syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	stale := filepath.Join(targetDir, "rules", "b.mdc")
+	var pruned []string
+	for _, result := range report.Results {
+		if result.Pruned {
+			pruned = append(pruned, result.TargetFile)
+		}
+	}
+	if len(pruned) != 1 || pruned[0] != stale {
+		t.Errorf("Expected only '%s' to be pruned, got %v", stale, pruned)
+	}
+	if _, err := os.Stat(stale); err != nil {
+		t.Errorf("Expected stale file to be kept in a dry run: %v", err)
+	}
+
+	syncer.DryRun = false
+	if _, err := syncer.Sync(); err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("Expected stale file to be pruned, got err=%v", err)
+	}
+
+	// Files outside the pattern scopes, ignored by the target or never
+	// synchronized are kept
+	for _, kept := range []string{"a.mdc", "own.md", "x.mdc", "h.mdc"} {
+		if _, err := os.Stat(filepath.Join(targetDir, "rules", kept)); err != nil {
+			t.Errorf("Expected '%s' to be kept: %v", kept, err)
+		}
+	}
+}
+
+func TestSyncWithPruneAndSkipPatterns(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDir := filepath.Join(tempDir, "target")
+
+	for path, content := range map[string]string{
+		filepath.Join(sourceDir, "a.md"): "# A\n",
+		filepath.Join(sourceDir, "b.md"): "# B\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{
+				Path:  sourceDir,
+				Files: []config.FileSpec{{Pattern: "*.md"}},
+			},
+		},
+		TargetDirs: []config.TargetDir{{Path: targetDir}},
+	}
+
+	syncer := NewSyncer(cfg, false, false)
+	syncer.Prune = true
+	syncer.ManifestPath = filepath.Join(tempDir, ManifestFile)
+	report, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	if err := syncer.WriteManifest(report, syncer.ManifestPath); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+
+	// Skipping a file for a run doesn't make its target file stale
+	syncer.SkipPatterns = []string{"b.md"}
+	report, err = syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	for _, result := range report.Results {
+		if result.Pruned {
+			t.Errorf("Expected no file to be pruned, got '%s'", result.TargetFile)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(targetDir, "b.md")); err != nil {
+		t.Errorf("Expected the target file of the skipped source to be kept: %v", err)
+	}
+}
+
+func TestSyncWithOutputDir(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
+
+	sourceFile := filepath.Join("source", ".cursor", "rules", "general.mdc")
+	if err := os.MkdirAll(filepath.Dir(sourceFile), 0755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+	if err := os.WriteFile(sourceFile, []byte("[Guide](./docs/guide.md)\n"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{
+				Path:  "./source",
+				Files: []config.FileSpec{{Pattern: ".cursor/rules/*.mdc"}},
+			},
+		},
+		TargetDirs: []config.TargetDir{
+			{Path: "./packages/app"},
+			{Path: "./docs"},
+		},
+	}
+
+	syncer := NewSyncer(cfg, false, false)
+	syncer.OutputDir = "scratch"
+	report, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	for _, result := range report.Results {
+		if !result.Success {
+			t.Errorf("Expected '%s' to be synchronized, got error=%v skip=%q", result.TargetFile, result.Error, result.SkipReason)
+		}
+	}
+
+	// Files land below the output directory, adjusted for the real targets
+	expectedFiles := map[string]string{
+		filepath.Join("scratch", "packages", "app", ".cursor", "rules", "general.mdc"): "[Guide](../../source/docs/guide.md)\n",
+		filepath.Join("scratch", "docs", ".cursor", "rules", "general.mdc"):            "[Guide](../source/docs/guide.md)\n",
+	}
+
+	for path, expected := range expectedFiles {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Errorf("Expected '%s' to be written: %v", path, err)
+			continue
+		}
+		if string(content) != expected {
+			t.Errorf("Expected content of '%s' to be '%s', got '%s'", path, expected, string(content))
+		}
+	}
+
+	// The real targets are untouched
+	for _, targetDir := range []string{"packages", "docs"} {
+		if _, err := os.Stat(targetDir); !os.IsNotExist(err) {
+			t.Errorf("Expected real target '%s' not to exist, got err=%v", targetDir, err)
+		}
+	}
+}
+
+func TestSyncWithOutputDirAndNoCreateDirs(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
+
+	for _, dir := range []string{"source", filepath.Join("packages", "app"), filepath.Join("scratch", "docs")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join("source", ".clinerules"), []byte("# Rules\n"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{
+				Path:  "./source",
+				Files: []config.FileSpec{{Pattern: ".clinerules"}},
+			},
+		},
+		TargetDirs: []config.TargetDir{
+			{Path: "./packages/app"},
+			{Path: "./docs"},
+		},
+	}
+
+	syncer := NewSyncer(cfg, false, false)
+	syncer.OutputDir = "scratch"
+	syncer.NoCreateDirs = true
+	report, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	// Target directories are checked below the output directory
+	for _, result := range report.Results {
+		switch result.TargetFile {
+		case filepath.Join("scratch", "packages", "app", ".clinerules"):
+			if !result.Skipped || result.SkipKind != SkipMissingDir {
+				t.Errorf("Expected '%s' to be skipped for a missing directory, got %+v", result.TargetFile, result)
+			}
+			if expected := "target directory " + filepath.Join("scratch", "packages", "app") + " does not exist"; result.SkipReason != expected {
+				t.Errorf("Expected skip reason '%s', got '%s'", expected, result.SkipReason)
+			}
+		case filepath.Join("scratch", "docs", ".clinerules"):
+			if !result.Success {
+				t.Errorf("Expected '%s' to be synchronized, got error=%v skip=%q", result.TargetFile, result.Error, result.SkipReason)
+			}
+		default:
+			t.Errorf("Unexpected target file '%s'", result.TargetFile)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join("scratch", "packages")); !os.IsNotExist(err) {
+		t.Errorf("Expected missing output directory not to be created, got %v", err)
+	}
+}
+
+func TestSyncWithTargetName(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDirA := filepath.Join(tempDir, "target-a")
+	targetDirB := filepath.Join(tempDir, "target-b")
+	targetDirC := filepath.Join(tempDir, "target-c")
+
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, ".clinerules"), []byte("See [guide](./guide.md)\n"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{
+				Path:  sourceDir,
+				Files: []config.FileSpec{{Pattern: ".clinerules", TargetName: "CLAUDE.md"}},
+			},
+		},
+		TargetDirs: []config.TargetDir{
+			{Path: targetDirA},
+			{Path: targetDirB, Rename: map[string]string{".clinerules": "AGENTS.md"}},
+			{Path: targetDirC, IgnoreFiles: []string{"CLAUDE.md"}},
+		},
+	}
+
+	syncer := NewSyncer(cfg, false, false)
+	report, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	if len(report.Results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(report.Results))
+	}
+
+	// The file spec name applies unless the target renames the file
+	expectedFiles := map[string]string{
+		filepath.Join(targetDirA, "CLAUDE.md"): "See [guide](../source/guide.md)\n",
+		filepath.Join(targetDirB, "AGENTS.md"): "See [guide](../source/guide.md)\n",
+	}
+
+	for path, expected := range expectedFiles {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Errorf("Expected renamed target '%s' to exist: %v", path, err)
+			continue
+		}
+		if string(content) != expected {
+			t.Errorf("Expected content of '%s' to be '%s', got '%s'", path, expected, string(content))
+		}
+	}
+
+	for _, path := range []string{
+		filepath.Join(targetDirA, ".clinerules"),
+		filepath.Join(targetDirB, "CLAUDE.md"),
+		filepath.Join(targetDirC, "CLAUDE.md"),
+	} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected '%s' not to exist, got err=%v", path, err)
+		}
+	}
+
+	// Target ignore patterns match the renamed file
+	if !report.Results[2].Skipped {
+		t.Errorf("Expected renamed file to be ignored by target-c, got %+v", report.Results[2])
+	}
+}
+
+func TestSyncDetectsConflictingSources(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	sourceDirA := filepath.Join(tempDir, "source-a")
+	sourceDirB := filepath.Join(tempDir, "source-b")
+	targetDir := filepath.Join(tempDir, "target")
+
+	// Both source directories define .roomodes with differing content and
+	// .clinerules with the same content
+	files := map[string]string{
+		filepath.Join(sourceDirA, ".roomodes"):   "mode: a\n",
+		filepath.Join(sourceDirB, ".roomodes"):   "mode: b\n",
+		filepath.Join(sourceDirA, ".clinerules"): "# Shared\n",
+		filepath.Join(sourceDirB, ".clinerules"): "# Shared\n",
+	}
+
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{
+				Path:  sourceDirA,
+				Files: []config.FileSpec{{Pattern: ".roomodes"}, {Pattern: ".clinerules"}},
+			},
+			{
+				Path:  sourceDirB,
+				Files: []config.FileSpec{{Pattern: ".roomodes"}, {Pattern: ".clinerules"}},
+			},
+		},
+		TargetDirs: []config.TargetDir{
+			{
+				Path: targetDir,
+			},
+		},
+	}
+
+	syncer := NewSyncer(cfg, true, false)
+	syncer.Strict = true
+	report, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	// Only the target with differing content is a conflict
+	if len(report.Conflicts) != 1 {
+		t.Fatalf("Expected 1 conflict, got %d: %v", len(report.Conflicts), report.Conflicts)
+	}
+
+	conflict := report.Conflicts[0]
+	if conflict.TargetFile != filepath.Join(targetDir, ".roomodes") {
+		t.Errorf("Expected conflict on '.roomodes', got '%s'", conflict.TargetFile)
+	}
+	expectedSources := []string{filepath.Join(sourceDirA, ".roomodes"), filepath.Join(sourceDirB, ".roomodes")}
+	if strings.Join(conflict.Sources, ",") != strings.Join(expectedSources, ",") {
+		t.Errorf("Expected conflicting sources %v, got %v", expectedSources, conflict.Sources)
+	}
+
+	// The second source is an error in strict mode
+	var conflictErr error
+	for _, result := range report.Results {
+		if result.SourceFile == filepath.Join(sourceDirB, ".roomodes") {
+			conflictErr = result.Error
+			if result.ConflictsWith != filepath.Join(sourceDirA, ".roomodes") {
+				t.Errorf("Expected conflict with the first source, got '%s'", result.ConflictsWith)
+			}
+		}
+	}
+	if conflictErr == nil || !strings.Contains(conflictErr.Error(), "differing content") {
+		t.Errorf("Expected conflict error mentioning differing content, got %v", conflictErr)
+	}
+
+	var out strings.Builder
+	syncer.Out = &out
+	syncer.PrintReport(report, true)
+	for _, expected := range []string{
+		"Conflicting sources:",
+		fmt.Sprintf("- '%s' <- '%s', '%s'", conflict.TargetFile, expectedSources[0], expectedSources[1]),
+		"- Conflicting target files: 1",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected report to contain '%s', got:\n%s", expected, out.String())
+		}
+	}
+}
+
+// readCountingFS counts the reads of each file of a MapFS
+type readCountingFS struct {
+	fstest.MapFS
+	reads map[string]int
+}
+
+func (f *readCountingFS) ReadFile(name string) ([]byte, error) {
+	f.reads[name]++
+	return f.MapFS.ReadFile(name)
+}
+
+func TestSyncRendersOnlyCompetingSourcesForConflicts(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
+
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{Path: "a", Files: []config.FileSpec{{Pattern: ".roomodes"}, {Pattern: ".clinerules"}}},
+			{Path: "b", Files: []config.FileSpec{{Pattern: ".roomodes"}}},
+		},
+		TargetDirs: []config.TargetDir{
+			{Path: "sub"},
+		},
+	}
+
+	fsys := &readCountingFS{
+		MapFS: fstest.MapFS{
+			"a/.roomodes":   {Data: []byte("mode: a\n")},
+			"a/.clinerules": {Data: []byte("# Rules\n")},
+			"b/.roomodes":   {Data: []byte("mode: b\n")},
+		},
+		reads: make(map[string]int),
+	}
+	syncer := NewSyncer(cfg, false, false)
+	syncer.Scanner.FS = fsys
+	report, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	if len(report.Conflicts) != 1 {
+		t.Errorf("Expected 1 conflict, got %d: %v", len(report.Conflicts), report.Conflicts)
+	}
+
+	// Files with a target path of their own are only read to be synchronized,
+	// and the colliding source is only read to look for conflicts
+	expected := map[string]int{
+		"a/.roomodes":   2,
+		"b/.roomodes":   1,
+		"a/.clinerules": 1,
+	}
+	for name, reads := range expected {
+		if fsys.reads[name] != reads {
+			t.Errorf("Expected '%s' to be read %d times, got %d", name, reads, fsys.reads[name])
+		}
+	}
+}
+
+func TestSyncWithNoAdjust(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDir := filepath.Join(tempDir, "target")
+
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+
+	// A file that would normally be rewritten
+	content := "---\ndescription: Rules\n---\nSee [guide](./docs/guide.md) and \"./config.json\"\n"
+	if err := os.WriteFile(filepath.Join(sourceDir, ".clinerules"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{
+				Path:  sourceDir,
+				Files: []config.FileSpec{{Pattern: ".clinerules"}},
+			},
+		},
+		TargetDirs: []config.TargetDir{
+			{
+				Path:                 targetDir,
+				FrontmatterOverrides: map[string]interface{}{"alwaysApply": true},
+			},
+		},
+		Rewrites: []config.Rewrite{{Pattern: "Rules", Replacement: "Shared rules"}},
+	}
+
+	syncer := NewSyncer(cfg, false, false)
+	syncer.NoAdjust = true
+	report, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	if len(report.Results) != 1 || !report.Results[0].Success {
+		t.Fatalf("Expected file to be synchronized, got %+v", report.Results)
+	}
+	if len(report.Results[0].PathAdjustments) != 0 {
+		t.Errorf("Expected no path adjustments, got %v", report.Results[0].PathAdjustments)
+	}
+
+	synced, err := os.ReadFile(filepath.Join(targetDir, ".clinerules"))
+	if err != nil {
+		t.Fatalf("Failed to read target file: %v", err)
+	}
+	if string(synced) != content {
+		t.Errorf("Expected file to be copied byte-for-byte, got:\n%s", synced)
+	}
+}
+
+func TestSyncCopiesFilesOverMaxAdjustSize(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDir := filepath.Join(tempDir, "target")
+
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+
+	// A small file is adjusted while a file over the limit is copied as is
+	small := "See [guide](./docs/guide.md)\n"
+	large := "See [guide](./docs/guide.md)\n" + strings.Repeat("Padding line without paths\n", 10)
+	for name, content := range map[string]string{"small.md": small, "large.md": large} {
+		if err := os.WriteFile(filepath.Join(sourceDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{
+				Path:  sourceDir,
+				Files: []config.FileSpec{{Pattern: "small.md"}, {Pattern: "large.md"}},
+			},
+		},
+		TargetDirs:    []config.TargetDir{{Path: targetDir}},
+		MaxAdjustSize: 64,
+	}
+
+	var logs bytes.Buffer
+	syncer := NewSyncer(cfg, false, false)
+	syncer.SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+	report, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	if len(report.Results) != 2 || !report.Results[0].Success || !report.Results[1].Success {
+		t.Fatalf("Expected both files to be synchronized, got %+v", report.Results)
+	}
+
+	synced, err := os.ReadFile(filepath.Join(targetDir, "large.md"))
+	if err != nil {
+		t.Fatalf("Failed to read target file: %v", err)
+	}
+	if string(synced) != large {
+		t.Errorf("Expected large file to be copied unchanged, got:\n%s", synced)
+	}
+
+	synced, err = os.ReadFile(filepath.Join(targetDir, "small.md"))
+	if err != nil {
+		t.Fatalf("Failed to read target file: %v", err)
+	}
+	if string(synced) == small {
+		t.Errorf("Expected small file to have its paths adjusted, got:\n%s", synced)
+	}
+
+	// The verbatim copy is reported as a warning
+	output := logs.String()
+	if !strings.Contains(output, "level=WARN") || !strings.Contains(output, "Copying large file without adjusting paths") || !strings.Contains(output, filepath.Join(sourceDir, "large.md")) {
+		t.Errorf("Expected a warning about the large file, got:\n%s", output)
+	}
+	if count := strings.Count(output, "Copying large file without adjusting paths"); count != 1 {
+		t.Errorf("Expected the warning to be logged once, got %d times:\n%s", count, output)
+	}
+	if strings.Contains(output, "small.md") {
+		t.Errorf("Expected no warning about the small file, got:\n%s", output)
+	}
+}
+
+func TestSyncPreservesEncodings(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDir := filepath.Join(tempDir, "target")
+
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+
+	// A UTF-8 file with a byte order mark and front matter, and a UTF-16
+	// file, as written by some Windows editors
+	bom := "\xEF\xBB\xBF"
+	withBOM := bom + "---\ndescription: Go rules\n---\nSee [guide](./docs/guide.md)\n"
+	utf16 := []byte{0xFF, 0xFE}
+	for _, r := range "See [guide](./docs/guide.md)\n" {
+		utf16 = append(utf16, byte(r), 0)
+	}
+	for name, content := range map[string][]byte{"bom.mdc": []byte(withBOM), "utf16.md": utf16} {
+		if err := os.WriteFile(filepath.Join(sourceDir, name), content, 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{
+				Path: sourceDir,
+				Files: []config.FileSpec{
+					{Pattern: "bom.mdc", FrontmatterOverrides: map[string]interface{}{"alwaysApply": true}},
+					{Pattern: "utf16.md"},
+				},
+			},
+		},
+		TargetDirs: []config.TargetDir{{Path: targetDir}},
+	}
+
+	var logs bytes.Buffer
+	syncer := NewSyncer(cfg, false, false)
+	syncer.SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+	report, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	if len(report.Results) != 2 || !report.Results[0].Success || !report.Results[1].Success {
+		t.Fatalf("Expected both files to be synchronized, got %+v", report.Results)
+	}
+
+	// The byte order mark is kept once, with the front matter and paths
+	// adjusted behind it
+	synced, err := os.ReadFile(filepath.Join(targetDir, "bom.mdc"))
+	if err != nil {
+		t.Fatalf("Failed to read target file: %v", err)
+	}
+	expected := bom + "---\ndescription: Go rules\nalwaysApply: true\n---\nSee [guide](../source/docs/guide.md)\n"
+	if string(synced) != expected {
+		t.Errorf("Expected content %q, got %q", expected, synced)
+	}
+
+	// The UTF-16 file is copied verbatim with a warning
+	synced, err = os.ReadFile(filepath.Join(targetDir, "utf16.md"))
+	if err != nil {
+		t.Fatalf("Failed to read target file: %v", err)
+	}
+	if !bytes.Equal(synced, utf16) {
+		t.Errorf("Expected UTF-16 file to be copied unchanged, got %q", synced)
+	}
+	output := logs.String()
+	if !strings.Contains(output, "Copying non-UTF-8 file without adjusting paths") || !strings.Contains(output, "encoding=UTF-16LE") {
+		t.Errorf("Expected a warning about the UTF-16 file, got:\n%s", output)
+	}
+}
+
+func TestSyncWithForceAdjust(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDir := filepath.Join(tempDir, "target")
+
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+
+	// A file normally copied as it is
+	content := "{\"path\": \"./modes/review.md\"}\n"
+	if err := os.WriteFile(filepath.Join(sourceDir, ".roomodes"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	adjustPaths := false
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{
+				Path:  sourceDir,
+				Files: []config.FileSpec{{Pattern: ".roomodes", AdjustPaths: &adjustPaths}},
+			},
+		},
+		TargetDirs: []config.TargetDir{
+			{
+				Path: targetDir,
+			},
+		},
+	}
+
+	syncer := NewSyncer(cfg, false, false)
+	syncer.ForceAdjust = true
+	report, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	if len(report.Results) != 1 || len(report.Results[0].PathAdjustments) != 1 {
+		t.Errorf("Expected 1 path adjustment, got %+v", report.Results)
+	}
+
+	synced, err := os.ReadFile(filepath.Join(targetDir, ".roomodes"))
+	if err != nil {
+		t.Fatalf("Failed to read target file: %v", err)
+	}
+	expected := "{\"path\": \"../source/modes/review.md\"}\n"
+	if string(synced) != expected {
+		t.Errorf("Expected adjusted content '%s', got '%s'", expected, synced)
+	}
+}
+
+func TestSyncWithProgress(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	sourceDir := filepath.Join(tempDir, "source")
+	files := map[string]string{
+		filepath.Join(sourceDir, ".clinerules"):                   "# Cline\n",
+		filepath.Join(sourceDir, ".cursor", "rules", "go.mdc"):    "# Go\n",
+		filepath.Join(sourceDir, ".cursor", "rules", "draft.mdc"): "# Draft\n",
+	}
+
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{
+				Path:  sourceDir,
+				Files: []config.FileSpec{{Pattern: ".clinerules"}, {Pattern: ".cursor/rules/*.mdc"}},
+			},
+		},
+		TargetDirs: []config.TargetDir{
+			{Path: filepath.Join(tempDir, "sub-a")},
+			{Path: filepath.Join(tempDir, "sub-b"), IgnoreFiles: []string{".cursor/rules/draft.mdc"}},
+		},
+	}
+
+	var progress strings.Builder
+	syncer := NewSyncer(cfg, false, false)
+	syncer.Progress = &progress
+	report, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	// One line is printed for every file and target directory pair
+	lines := strings.Split(strings.TrimSuffix(progress.String(), "\n"), "\n")
+	if len(lines) != len(report.Results) || len(lines) != 6 {
+		t.Fatalf("Expected 6 progress lines for %d results, got:\n%s", len(report.Results), progress.String())
+	}
+
+	for i, line := range lines {
+		if !strings.HasPrefix(line, fmt.Sprintf("[%d/6] syncing ", i+1)) {
+			t.Errorf("Expected progress line %d to count up to 6, got '%s'", i+1, line)
+		}
+	}
+
+	expected := fmt.Sprintf("[1/6] syncing .clinerules -> %s", filepath.Join(tempDir, "sub-a"))
+	if lines[0] != expected {
+		t.Errorf("Expected first progress line '%s', got '%s'", expected, lines[0])
+	}
+}
+
+func TestDryRunReportsBytesToWrite(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDir := filepath.Join(tempDir, "target")
+
+	files := map[string]string{
+		filepath.Join(sourceDir, ".clinerules"): "See [guide](./docs/guide.md)\n",
+		filepath.Join(sourceDir, ".roomodes"):   "{}\n",
+		filepath.Join(targetDir, ".roomodes"):   "{\"old\": true}\n",
+	}
+
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{
+				Path:  sourceDir,
+				Files: []config.FileSpec{{Pattern: ".clinerules"}, {Pattern: ".roomodes"}},
+			},
+		},
+		TargetDirs: []config.TargetDir{
+			{
+				Path: targetDir,
+			},
+		},
+	}
+
+	syncer := NewSyncer(cfg, true, false)
+	report, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	// The estimate matches the size of the adjusted content
+	expectedBytes := len("See [guide](../source/docs/guide.md)\n") + len("{}\n")
+	total := 0
+	for _, result := range report.Results {
+		total += result.Bytes
+	}
+	if total != expectedBytes {
+		t.Errorf("Expected %d bytes to write, got %d", expectedBytes, total)
+	}
+
+	var out strings.Builder
+	syncer.Out = &out
+	syncer.PrintReport(report, true)
+	expected := fmt.Sprintf("[DRY-RUN] - Bytes to write: %d (1 new files, 1 overwritten)", expectedBytes)
+	if !strings.Contains(out.String(), expected) {
+		t.Errorf("Expected report to contain '%s', got:\n%s", expected, out.String())
+	}
+
+	// Nothing is written
+	if _, err := os.Stat(filepath.Join(targetDir, ".clinerules")); !os.IsNotExist(err) {
+		t.Errorf("Expected dry run not to write files, got err=%v", err)
+	}
+}
+
+func TestSyncWithIgnoreFile(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDir := filepath.Join(tempDir, "target")
+
+	files := map[string]string{
+		filepath.Join(sourceDir, "rules", "general.md"): "# General\n",
+		filepath.Join(sourceDir, "rules", "old.bak"):    "# Old\n",
+		filepath.Join(sourceDir, "rules", "keep.bak"):   "# Kept backup\n",
+		filepath.Join(sourceDir, "rules", "local.md"):   "# Local\n",
+		filepath.Join(tempDir, ".airulesyncignore"):     "# Backups\n*.bak\n!keep.bak\n\n/target/rules/local.md\n",
+		filepath.Join(tempDir, ".airulesync.yaml"): fmt.Sprintf(`
+source_dirs:
+  - path: %q
+    files:
+      - "rules/*"
+target_dirs:
+  - path: %q
+`, sourceDir, targetDir),
+	}
+
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	cfg, err := config.LoadConfig(filepath.Join(tempDir, ".airulesync.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	syncer := NewSyncer(cfg, false, false)
+	if _, err := syncer.Sync(); err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	// Source and target paths are both matched against the ignore file
+	expected := map[string]bool{
+		"general.md": true,
+		"old.bak":    false,
+		"keep.bak":   true,
+		"local.md":   false,
+	}
+
+	for name, synced := range expected {
+		_, err := os.Stat(filepath.Join(targetDir, "rules", name))
+		if synced && err != nil {
+			t.Errorf("Expected '%s' to be synchronized: %v", name, err)
+		}
+		if !synced && !os.IsNotExist(err) {
+			t.Errorf("Expected '%s' to be ignored, got err=%v", name, err)
+		}
+	}
+}
+
+func TestPrintReportOnlyChanged(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDir := filepath.Join(tempDir, "target")
+
+	for name, content := range map[string]string{
+		"stable.md":  "# Stable\n",
+		"changed.md": "# Before\n",
+		"ignored.md": "# Ignored\n",
+	} {
+		if err := os.MkdirAll(sourceDir, 0755); err != nil {
+			t.Fatalf("Failed to create source directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(sourceDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{Path: sourceDir, Files: []config.FileSpec{{Pattern: "*.md"}}},
+		},
+		TargetDirs: []config.TargetDir{
+			{Path: targetDir, IgnoreFiles: []string{"ignored.md"}},
+		},
+	}
+
+	// Synchronize once, then change one of the source files
+	if _, err := NewSyncer(cfg, false, false).Sync(); err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, "changed.md"), []byte("# After\n"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	syncer := NewSyncer(cfg, false, false)
+	syncer.OnlyChanged = true
+	report, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	var out strings.Builder
+	syncer.Out = &out
+	syncer.PrintReport(report, false)
+
+	// The unchanged file is suppressed, while changed and skipped files remain
+	if strings.Contains(out.String(), filepath.Join(targetDir, "stable.md")) {
+		t.Errorf("Expected unchanged file to be omitted from the report, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), filepath.Join(targetDir, "changed.md")) {
+		t.Errorf("Expected changed file to be listed in the report, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), filepath.Join(targetDir, "ignored.md")) {
+		t.Errorf("Expected skipped file to be listed in the report, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "Unchanged files not listed: 1") {
+		t.Errorf("Expected summary to count the omitted file, got:\n%s", out.String())
+	}
+}
+
+func TestPrintReportCountsFailedFiles(t *testing.T) {
+	report := &SyncReport{
+		Results: []SyncResult{
+			{SourceFile: "a.md", TargetFile: "sub/a.md", Success: true, Changed: true},
+			{SourceFile: "b.md", TargetFile: "sub/b.md", Skipped: true, SkipReason: "file matches ignore pattern b.md in target directory"},
+			{SourceFile: "c.md", TargetFile: "sub/c.md", Error: errors.New("permission denied")},
+		},
+	}
+
+	var out strings.Builder
+	syncer := NewSyncer(&config.Config{}, false, false)
+	syncer.Out = &out
+	syncer.PrintReport(report, false)
+
+	// The summary counts files like the JSON report does
+	synchronized, skipped, failed := report.Counts()
+	for _, expected := range []string{
+		fmt.Sprintf("- Files synchronized: %d\n", synchronized),
+		fmt.Sprintf("- Files skipped: %d\n", skipped),
+		fmt.Sprintf("- Files failed: %d\n", failed),
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected report to contain '%s', got:\n%s", strings.TrimSpace(expected), out.String())
+		}
+	}
+	if synchronized != 1 || failed != 1 {
+		t.Errorf("Expected 1 synchronized and 1 failed file, got %d and %d", synchronized, failed)
+	}
+}
+
+func TestPrintJSONReport(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDir := filepath.Join(tempDir, "target")
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatalf("Failed to create source directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, ".clinerules"), []byte("# Rules\n"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	cfg := &config.Config{
+		Name:        "shared-rules",
+		Description: "Shares the rules with the target",
+		SourceDirs: []config.SourceDir{
+			{Path: sourceDir, Files: []config.FileSpec{{Pattern: ".clinerules"}}},
+		},
+		TargetDirs: []config.TargetDir{
+			{Path: targetDir},
+		},
+	}
+
+	syncer := NewSyncer(cfg, false, false)
+	report, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	var out strings.Builder
+	syncer.Out = &out
+	if err := syncer.PrintJSONReport(report, false); err != nil {
+		t.Fatalf("Failed to print JSON report: %v", err)
+	}
+
+	var parsed struct {
+		Config struct {
+			Name        string `json:"name"`
+			Description string `json:"description"`
+		} `json:"config"`
+		Results []struct {
+			Target string `json:"target"`
+			Status string `json:"status"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal([]byte(out.String()), &parsed); err != nil {
+		t.Fatalf("Failed to parse JSON report: %v\n%s", err, out.String())
+	}
+
+	// The report names the configuration that produced it
+	if parsed.Config.Name != cfg.Name || parsed.Config.Description != cfg.Description {
+		t.Errorf("Expected config name and description in report, got: %+v", parsed.Config)
+	}
+
+	if len(parsed.Results) != 1 || parsed.Results[0].Status != "changed" {
+		t.Errorf("Expected a single changed result, got: %+v", parsed.Results)
+	}
+}
+
+func TestSyncWithSymlinkedSourceFile(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	sharedDir := filepath.Join(tempDir, "shared")
+	sourceDir := filepath.Join(tempDir, "project")
+	targetDir := filepath.Join(sourceDir, "sub")
+
+	for _, dir := range []string{sharedDir, targetDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+	}
+
+	// The real rule file refers to a path next to itself
+	if err := os.WriteFile(filepath.Join(sharedDir, ".clinerules"), []byte("See [guide](./docs/guide.md)\n"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := os.Symlink(filepath.Join("..", "shared", ".clinerules"), filepath.Join(sourceDir, ".clinerules")); err != nil {
+		t.Skipf("Symlinks are not supported: %v", err)
+	}
+
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{Path: sourceDir, Files: []config.FileSpec{{Pattern: ".clinerules"}}},
+		},
+		TargetDirs: []config.TargetDir{
+			{Path: targetDir},
+		},
+	}
+
+	if _, err := NewSyncer(cfg, false, false).Sync(); err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	// The target keeps the configured layout, with paths adjusted from the
+	// location of the real file
+	targetFile := filepath.Join(targetDir, ".clinerules")
+	info, err := os.Lstat(targetFile)
+	if err != nil {
+		t.Fatalf("Failed to stat target file: %v", err)
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		t.Errorf("Expected target to be a regular file, got a symlink")
+	}
+
+	content, err := os.ReadFile(targetFile)
+	if err != nil {
+		t.Fatalf("Failed to read target file: %v", err)
+	}
+	expected := "See [guide](../../shared/docs/guide.md)\n"
+	if string(content) != expected {
+		t.Errorf("Expected target content %q, got %q", expected, content)
+	}
+}
+
+func TestPrintReportSkipCategories(t *testing.T) {
+	// Create a temporary directory structure for testing
+	tempDir := t.TempDir()
+
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDir := filepath.Join(tempDir, "target")
+
+	files := map[string]string{
+		filepath.Join(sourceDir, "same-a.md"):  "# Same A\n",
+		filepath.Join(sourceDir, "same-b.md"):  "# Same B\n",
+		filepath.Join(sourceDir, "kept.md"):    "# New kept\n",
+		filepath.Join(sourceDir, "ignored.md"): "# Ignored\n",
+		filepath.Join(targetDir, "same-a.md"):  "# Same A\n",
+		filepath.Join(targetDir, "same-b.md"):  "# Same B\n",
+		filepath.Join(targetDir, "kept.md"):    "# Old kept\n",
+	}
+
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{
+				Path: sourceDir,
+				Files: []config.FileSpec{
+					{Pattern: "same-*.md"},
+					{Pattern: "kept.md", Overwrite: config.OverwriteNever},
+					{Pattern: "ignored.md"},
+				},
+			},
+		},
+		TargetDirs: []config.TargetDir{
+			{Path: targetDir, IgnoreFiles: []string{"ignored.md"}},
+		},
+	}
+
+	syncer := NewSyncer(cfg, false, true)
+	report, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	// Each result is categorized by why it wasn't written
+	expectedKinds := map[string]SkipKind{
+		"same-a.md":  SkipUnchanged,
+		"same-b.md":  SkipUnchanged,
+		"kept.md":    SkipOverwrite,
+		"ignored.md": SkipIgnored,
+	}
+	for _, result := range report.Results {
+		name := filepath.Base(result.TargetFile)
+		if kind := result.SkipCategory(); kind != expectedKinds[name] {
+			t.Errorf("Expected '%s' to be categorized as '%s', got '%s'", name, expectedKinds[name], kind)
+		}
+	}
+
+	var out strings.Builder
+	syncer.Out = &out
+	syncer.PrintReport(report, false)
+
+	for _, expected := range []string{
+		"* skipped (unchanged): 2",
+		"* skipped (overwrite=false): 1",
+		"* skipped (ignored): 1",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected verbose report to contain '%s', got:\n%s", expected, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "skipped (other)") {
+		t.Errorf("Expected no other skip category, got:\n%s", out.String())
+	}
+}
+
+func TestSyncFromFS(t *testing.T) {
+	// Source paths are adjusted relative to the working directory
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
+
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{Path: "rules", Files: []config.FileSpec{{Pattern: ".clinerules"}}},
+		},
+		TargetDirs: []config.TargetDir{
+			{Path: "sub"},
+		},
+	}
+
+	syncer := NewSyncer(cfg, false, false)
+	syncer.Scanner.FS = fstest.MapFS{
+		"rules/.clinerules": {Data: []byte("See [guide](./docs/guide.md)\n")},
+	}
+	report, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	if len(report.Results) != 1 || !report.Results[0].Success {
+		t.Fatalf("Expected a single successful result, got: %+v", report.Results)
+	}
+
+	// The target is written to the real file system
+	content, err := os.ReadFile(filepath.Join(tempDir, "sub", ".clinerules"))
+	if err != nil {
+		t.Fatalf("Failed to read target file: %v", err)
+	}
+	expected := "See [guide](../rules/docs/guide.md)\n"
+	if string(content) != expected {
+		t.Errorf("Expected target content %q, got %q", expected, content)
+	}
+}
+
+func TestSyncReportTargetDirsWritten(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDirs := []string{
+		filepath.Join(tempDir, "target-a"),
+		filepath.Join(tempDir, "target-b"),
+		filepath.Join(tempDir, "target-c"),
+	}
+
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+	for _, name := range []string{".clinerules", ".roomodes"} {
+		if err := os.WriteFile(filepath.Join(sourceDir, name), []byte("# Rules\n"), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	// The third target ignores both files
+	cfg := &config.Config{
+		SourceDirs: []config.SourceDir{
+			{
+				Path:  sourceDir,
+				Files: []config.FileSpec{{Pattern: ".clinerules"}, {Pattern: ".roomodes"}},
+			},
+		},
+		TargetDirs: []config.TargetDir{
+			{Path: targetDirs[0]},
+			{Path: targetDirs[1]},
+			{Path: targetDirs[2], IgnoreFiles: []string{".clinerules", ".roomodes"}},
+		},
+	}
+
+	syncer := NewSyncer(cfg, false, false)
+	report, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	if written := report.TargetDirsWritten(); written != 2 {
+		t.Errorf("Expected 2 target directories written, got %d", written)
+	}
+
+	var out strings.Builder
+	syncer.Out = &out
+	syncer.PrintReport(report, false)
+	if !strings.Contains(out.String(), "- Target directories written: 2\n") {
+		t.Errorf("Expected the report to count 2 target directories written, got:\n%s", out.String())
+	}
+
+	// Nothing is written again by a second run
+	report, err = syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	if written := report.TargetDirsWritten(); written != 0 {
+		t.Errorf("Expected no target directories written by the second run, got %d", written)
+	}
+}
+
+func TestSyncWithHeader(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	targetDir := filepath.Join(tempDir, "target")
+
+	files := map[string]string{
+		".clinerules":          "# Rules\n",
+		".cursor/rules/go.mdc": "---\ndescription: Go\n---\n# Go\n",
+		"settings.json":        "{\"rules\": []}\n",
+		"scripts/lint.sh":      "echo lint\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(sourceDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	// The shell script overrides the global header
+	cfg := &config.Config{
+		Header: "Synced by airulesync from {source} - do not edit",
+		SourceDirs: []config.SourceDir{
+			{
+				Path: sourceDir,
+				Files: []config.FileSpec{
+					{Pattern: ".clinerules"},
+					{Pattern: ".cursor/rules/*.mdc"},
+					{Pattern: "settings.json"},
+					{Pattern: "scripts/lint.sh", Header: "Generated file", HeaderComment: "###"},
+				},
+			},
+		},
+		TargetDirs: []config.TargetDir{
+			{
+				Path: targetDir,
+			},
+		},
+	}
+
+	syncer := NewSyncer(cfg, false, false)
+	if _, err := syncer.Sync(); err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	source := func(name string) string {
+		return filepath.ToSlash(filepath.Join(sourceDir, name))
+	}
+	expected := map[string]string{
+		".clinerules":          "<!-- Synced by airulesync from " + source(".clinerules") + " - do not edit -->\n# Rules\n",
+		".cursor/rules/go.mdc": "---\ndescription: Go\n---\n<!-- Synced by airulesync from " + source(".cursor/rules/go.mdc") + " - do not edit -->\n# Go\n",
+		"settings.json":        "{\"rules\": []}\n",
+		"scripts/lint.sh":      "### Generated file\necho lint\n",
+	}
+	for name, want := range expected {
+		content, err := os.ReadFile(filepath.Join(targetDir, name))
+		if err != nil {
+			t.Fatalf("Failed to read target file: %v", err)
+		}
+		if string(content) != want {
+			t.Errorf("Expected '%s' to contain %q, got %q", name, want, string(content))
+		}
+	}
+
+	// The header is stable, so a second run changes nothing
+	report, err := syncer.Sync()
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	if report.HasChanges() {
+		t.Errorf("Expected no changes on the second run")
+	}
+
+	// Files copied verbatim get no header
+	syncer.NoAdjust = true
+	if _, err := syncer.Sync(); err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(targetDir, ".clinerules"))
+	if err != nil {
+		t.Fatalf("Failed to read target file: %v", err)
+	}
+	if string(content) != "# Rules\n" {
+		t.Errorf("Expected no header with --no-adjust, got %q", string(content))
+	}
+}
@@ -0,0 +1,106 @@
+package sync
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/upamune/airulesync/internal/config"
+	"github.com/upamune/airulesync/internal/scanner"
+)
+
+// createTargetDirs creates the directories of all target files up front, so
+// that directories that can't be created are known before any file is
+// written. The files of such a directory fail with the error recorded in
+// dirErrors instead of being attempted. Targets skipped for a missing marker
+// file, a missing directory or an ignore pattern don't get directories. The
+// created directories are returned, so that those left empty can be removed.
+func (s *Syncer) createTargetDirs(files []scanner.FileInfo) []string {
+	needed := make(map[string]bool)
+	for _, targetDir := range s.Config.TargetDirs {
+		if _, ok := s.missingMarker(targetDir); ok {
+			continue
+		}
+		if s.missingTargetDir(targetDir) {
+			continue
+		}
+		for _, file := range files {
+			if _, ok := s.matchTargetIgnore(targetRelPath(file, targetDir), targetDir); ok {
+				continue
+			}
+			needed[filepath.Dir(s.targetPath(file, targetDir))] = true
+		}
+	}
+
+	// Create the directories in order, so parents are created first
+	dirs := make([]string, 0, len(needed))
+	for dir := range needed {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	var created []string
+	s.dirErrors = make(map[string]error)
+	for _, dir := range dirs {
+		missing := missingDirs(dir)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			if blocker, ok := findNonDir(dir); ok {
+				s.dirErrors[dir] = fmt.Errorf("failed to create target directory %s: %s exists and is not a directory", dir, blocker)
+			} else {
+				s.dirErrors[dir] = fmt.Errorf("failed to create target directory %s: %w", dir, err)
+			}
+			continue
+		}
+		created = append(created, missing...)
+	}
+
+	return created
+}
+
+// missingDirs returns dir and its parents that don't exist yet, parents first
+func missingDirs(dir string) []string {
+	var missing []string
+	for path := dir; ; path = filepath.Dir(path) {
+		if _, err := os.Lstat(path); err == nil || filepath.Dir(path) == path {
+			break
+		}
+		missing = append([]string{path}, missing...)
+	}
+	return missing
+}
+
+// removeEmptyDirs removes the created directories that no file was written
+// to, such as those of files deferred by Limit, deepest first
+func removeEmptyDirs(created []string) {
+	for i := len(created) - 1; i >= 0; i-- {
+		entries, err := os.ReadDir(created[i])
+		if err == nil && len(entries) == 0 {
+			_ = os.Remove(created[i])
+		}
+	}
+}
+
+// missingTargetDir reports whether a target directory doesn't exist and may
+// not be created, as with NoCreateDirs. Directories below an existing target
+// directory are still created for nested files.
+func (s *Syncer) missingTargetDir(targetDir config.TargetDir) bool {
+	if !s.NoCreateDirs {
+		return false
+	}
+	info, err := os.Stat(s.targetRoot(targetDir))
+	return err != nil || !info.IsDir()
+}
+
+// findNonDir returns the deepest existing path of dir and its parents if it
+// is not a directory
+func findNonDir(dir string) (string, bool) {
+	for path := dir; ; path = filepath.Dir(path) {
+		if info, err := os.Stat(path); err == nil {
+			return path, !info.IsDir()
+		}
+		if filepath.Dir(path) == path {
+			return "", false
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package sync
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/upamune/airulesync/internal/scanner"
+)
+
+// PrintTree prints the resolved sync mapping as an ASCII tree: each source
+// directory, its files, and the target files they are synchronized to
+func (s *Syncer) PrintTree() error {
+	files, _, err := s.scanFiles()
+	if err != nil {
+		return fmt.Errorf("failed to scan source directories: %w", err)
+	}
+
+	// Group files by source directory, keeping the config order
+	filesByDir := make(map[string][]scanner.FileInfo)
+	for _, file := range files {
+		filesByDir[file.SourceDir] = append(filesByDir[file.SourceDir], file)
+	}
+
+	sourceDirs, err := s.Scanner.SourceDirs()
+	if err != nil {
+		return err
+	}
+
+	for _, sourceDir := range sourceDirs {
+		fmt.Fprintln(s.Out, sourceDir.Path)
+
+		dirFiles := filesByDir[sourceDir.Path]
+		for i, file := range dirFiles {
+			fileBranch, fileIndent := treeBranch(i == len(dirFiles)-1)
+			fmt.Fprintf(s.Out, "%s%s\n", fileBranch, file.RelativePath)
+
+			var targets []string
+			for _, targetDir := range s.Config.TargetDirs {
+				if _, ok := s.missingMarker(targetDir); ok {
+					continue
+				}
+				if _, ok := s.matchTargetIgnore(targetRelPath(file, targetDir), targetDir); ok {
+					continue
+				}
+				targets = append(targets, s.targetPath(file, targetDir))
+			}
+
+			printTreeTargets(s.Out, fileIndent, targets)
+		}
+	}
+
+	return nil
+}
+
+// printTreeTargets prints the targets of a file below it
+func printTreeTargets(w io.Writer, indent string, targets []string) {
+	for i, target := range targets {
+		branch, _ := treeBranch(i == len(targets)-1)
+		fmt.Fprintf(w, "%s%s-> %s\n", indent, branch, target)
+	}
+}
+
+// treeBranch returns the branch of a tree entry and the indent of its children
+func treeBranch(last bool) (string, string) {
+	if last {
+		return "└── ", "    "
+	}
+	return "├── ", "│   "
+}
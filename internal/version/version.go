@@ -3,6 +3,7 @@ package version
 import (
 	"fmt"
 	"runtime"
+	"runtime/debug"
 	"time"
 )
 
@@ -17,37 +18,68 @@ var (
 
 // BuildInfo represents the build information for the application
 type BuildInfo struct {
-	Version   string
-	Commit    string
-	BuildTime string
-	GoVersion string
-	Platform  string
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"buildTime"`
+	GoVersion string `json:"goVersion"`
+	Platform  string `json:"platform"`
 }
 
-// GetBuildInfo returns the build information
+// readBuildInfo reads the build information embedded by the Go toolchain
+var readBuildInfo = debug.ReadBuildInfo
+
+// GetBuildInfo returns the build information, falling back to the module
+// version and VCS information embedded by the Go toolchain when the ldflags
+// values are unset, as with `go install`
 func GetBuildInfo() BuildInfo {
-	return BuildInfo{
+	info := BuildInfo{
 		Version:   Version,
 		Commit:    Commit,
 		BuildTime: BuildTime,
 		GoVersion: GoVersion,
 		Platform:  Platform,
 	}
+
+	embedded, ok := readBuildInfo()
+	if !ok {
+		return info
+	}
+
+	if (info.Version == "" || info.Version == "dev") && embedded.Main.Version != "" && embedded.Main.Version != "(devel)" {
+		info.Version = embedded.Main.Version
+	}
+
+	for _, setting := range embedded.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if info.Commit == "" || info.Commit == "none" {
+				info.Commit = setting.Value
+			}
+		case "vcs.time":
+			if info.BuildTime == "" {
+				info.BuildTime = setting.Value
+			}
+		}
+	}
+
+	return info
 }
 
 // FormatBuildInfo returns a formatted string with the build information
 func FormatBuildInfo() string {
-	buildTime := BuildTime
+	info := GetBuildInfo()
+
+	buildTime := info.BuildTime
 	if buildTime == "" {
 		buildTime = time.Now().Format(time.RFC3339)
 	}
 
 	return fmt.Sprintf(
 		"airulesync version %s\ncommit: %s\nbuilt: %s\ngo version: %s\nplatform: %s",
-		Version,
-		Commit,
+		info.Version,
+		info.Commit,
 		buildTime,
-		GoVersion,
-		Platform,
+		info.GoVersion,
+		info.Platform,
 	)
 }
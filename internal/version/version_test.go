@@ -0,0 +1,92 @@
+package version
+
+import (
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+func TestGetBuildInfoFallback(t *testing.T) {
+	embedded := &debug.BuildInfo{
+		Main: debug.Module{Path: "github.com/upamune/airulesync", Version: "v1.2.3"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "0123456789abcdef"},
+			{Key: "vcs.time", Value: "2025-04-01T12:00:00Z"},
+		},
+	}
+
+	origVersion, origCommit, origBuildTime, origRead := Version, Commit, BuildTime, readBuildInfo
+	t.Cleanup(func() {
+		Version, Commit, BuildTime, readBuildInfo = origVersion, origCommit, origBuildTime, origRead
+	})
+	readBuildInfo = func() (*debug.BuildInfo, bool) { return embedded, true }
+
+	testCases := []struct {
+		name      string
+		version   string
+		commit    string
+		buildTime string
+		expected  BuildInfo
+	}{
+		{
+			name:      "ldflags unset",
+			version:   "dev",
+			commit:    "none",
+			buildTime: "",
+			expected: BuildInfo{
+				Version:   "v1.2.3",
+				Commit:    "0123456789abcdef",
+				BuildTime: "2025-04-01T12:00:00Z",
+			},
+		},
+		{
+			name:      "ldflags set",
+			version:   "v2.0.0",
+			commit:    "fedcba",
+			buildTime: "2025-05-01T00:00:00Z",
+			expected: BuildInfo{
+				Version:   "v2.0.0",
+				Commit:    "fedcba",
+				BuildTime: "2025-05-01T00:00:00Z",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			Version, Commit, BuildTime = tc.version, tc.commit, tc.buildTime
+
+			info := GetBuildInfo()
+			if info.Version != tc.expected.Version {
+				t.Errorf("Expected version '%s', got '%s'", tc.expected.Version, info.Version)
+			}
+			if info.Commit != tc.expected.Commit {
+				t.Errorf("Expected commit '%s', got '%s'", tc.expected.Commit, info.Commit)
+			}
+			if info.BuildTime != tc.expected.BuildTime {
+				t.Errorf("Expected build time '%s', got '%s'", tc.expected.BuildTime, info.BuildTime)
+			}
+
+			formatted := FormatBuildInfo()
+			if !strings.Contains(formatted, "airulesync version "+tc.expected.Version+"\n") {
+				t.Errorf("Expected formatted build info to contain version '%s', got:\n%s", tc.expected.Version, formatted)
+			}
+		})
+	}
+}
+
+func TestGetBuildInfoWithoutEmbeddedInfo(t *testing.T) {
+	origVersion, origCommit, origRead := Version, Commit, readBuildInfo
+	t.Cleanup(func() {
+		Version, Commit, readBuildInfo = origVersion, origCommit, origRead
+	})
+	Version, Commit = "dev", "none"
+	readBuildInfo = func() (*debug.BuildInfo, bool) {
+		return &debug.BuildInfo{Main: debug.Module{Version: "(devel)"}}, true
+	}
+
+	info := GetBuildInfo()
+	if info.Version != "dev" || info.Commit != "none" {
+		t.Errorf("Expected version 'dev' and commit 'none', got '%s' and '%s'", info.Version, info.Commit)
+	}
+}
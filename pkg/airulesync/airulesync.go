@@ -0,0 +1,127 @@
+// Package airulesync exposes the rule file synchronization as a library so it
+// can be embedded in other Go tooling without shelling out to the binary.
+package airulesync
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+
+	"github.com/upamune/airulesync/internal/app"
+	"github.com/upamune/airulesync/internal/config"
+	"github.com/upamune/airulesync/internal/pathadjust"
+	"github.com/upamune/airulesync/internal/scanner"
+	"github.com/upamune/airulesync/internal/sync"
+)
+
+// Config is the synchronization configuration
+type Config = config.Config
+
+// SourceDir is a source directory configuration
+type SourceDir = config.SourceDir
+
+// TargetDir is a target directory configuration
+type TargetDir = config.TargetDir
+
+// FileSpec is a file specification within a source directory
+type FileSpec = config.FileSpec
+
+// SyncReport is a report of all synchronization operations
+type SyncReport = sync.SyncReport
+
+// SyncResult is the result of synchronizing a single file to a single target
+type SyncResult = sync.SyncResult
+
+// AdjustmentResult is a path adjusted in a synchronized file
+type AdjustmentResult = pathadjust.AdjustmentResult
+
+// Adjuster adjusts references in a line of a synchronized file with custom
+// logic, such as a project-specific reference syntax
+type Adjuster = pathadjust.Adjuster
+
+// AdjustContext describes the line passed to an Adjuster. Its AdjustPath
+// method rewrites a path relative to the source directory for the target.
+type AdjustContext = pathadjust.AdjustContext
+
+// ErrConfigNotFound is returned by LoadConfig when the configuration file,
+// or a file it includes, doesn't exist
+var ErrConfigNotFound = config.ErrConfigNotFound
+
+// ValidationError is an invalid setting of a configuration, returned by
+// LoadConfig and Sync
+type ValidationError = config.ValidationError
+
+// SyncError is the failure to synchronize a single file, as returned by
+// SyncReport.Err
+type SyncError = sync.SyncError
+
+// Options controls how a synchronization is performed
+type Options struct {
+	// DryRun simulates the synchronization without writing any files
+	DryRun bool
+	// Verbose enables verbose diagnostics
+	Verbose bool
+	// Logger receives diagnostics of the synchronization. Nothing is logged when nil.
+	Logger *slog.Logger
+	// FS is the file system source files are read from, such as an embed.FS.
+	// Source directory paths are taken relative to its root. Targets are
+	// always written to the operating system's file system.
+	FS fs.FS
+	// Adjusters are run on each line of files whose paths are adjusted,
+	// after the built-in path patterns unless NoBuiltinPatterns is set
+	Adjusters         []Adjuster
+	NoBuiltinPatterns bool
+}
+
+// LoadConfig loads and validates a configuration file
+func LoadConfig(configPath string) (*Config, error) {
+	return config.LoadConfig(configPath)
+}
+
+// Sync synchronizes rule files according to the given configuration
+func Sync(cfg *Config, opts Options) (*SyncReport, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("no configuration given")
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	syncer := sync.NewSyncer(cfg, opts.DryRun, opts.Verbose)
+	if opts.Logger != nil {
+		syncer.SetLogger(opts.Logger)
+	}
+	syncer.Scanner.FS = opts.FS
+	syncer.PathAdjuster.Adjusters = opts.Adjusters
+	syncer.PathAdjuster.NoBuiltinPatterns = opts.NoBuiltinPatterns
+	report, err := syncer.Sync()
+	if err != nil {
+		return nil, fmt.Errorf("synchronization failed: %w", err)
+	}
+
+	return report, nil
+}
+
+// Init scans a directory for rule files and returns a generated configuration
+// without writing it to disk
+func Init(dir string) (*Config, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("failed to access directory %s: %w", dir, err)
+	}
+
+	s := scanner.NewScanner(nil)
+
+	ruleFiles, err := s.ScanDirectory(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan directory: %w", err)
+	}
+
+	targetDirs, err := s.FindPotentialTargetDirs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find potential target directories: %w", err)
+	}
+
+	return app.GenerateConfig(dir, ruleFiles, targetDirs), nil
+}
@@ -0,0 +1,180 @@
+package airulesync
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestInit(t *testing.T) {
+	// Create a temporary project with a rule file
+	tempDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, ".clinerules"), []byte("# Test clinerules file"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	// Generate the configuration
+	cfg, err := Init(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to init: %v", err)
+	}
+
+	if len(cfg.SourceDirs) != 1 {
+		t.Fatalf("Expected 1 source directory, got %d", len(cfg.SourceDirs))
+	}
+
+	if cfg.SourceDirs[0].Files[0].Pattern != ".clinerules" {
+		t.Errorf("Expected pattern '.clinerules', got '%s'", cfg.SourceDirs[0].Files[0].Pattern)
+	}
+
+	// Verify that nothing was written to disk
+	if _, err := os.Stat(filepath.Join(tempDir, ".airulesync.yaml")); !os.IsNotExist(err) {
+		t.Errorf("Expected no configuration file to be written")
+	}
+}
+
+func TestSyncRejectsInvalidConfig(t *testing.T) {
+	if _, err := Sync(&Config{}, Options{}); err == nil {
+		t.Errorf("Expected error for empty configuration, but got nil")
+	}
+}
+
+func TestErrorTypes(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// A missing configuration file
+	if _, err := LoadConfig(filepath.Join(tempDir, "missing.yaml")); !errors.Is(err, ErrConfigNotFound) {
+		t.Errorf("Expected ErrConfigNotFound, got %v", err)
+	}
+
+	// An invalid configuration, reporting the offending field
+	invalidPath := filepath.Join(tempDir, "invalid.yaml")
+	if err := os.WriteFile(invalidPath, []byte("source_dirs:\n- path: src\ntarget_dirs:\n- path: dst\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	_, err := LoadConfig(invalidPath)
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a ValidationError, got %v", err)
+	}
+	if validationErr.Field != "source_dirs[0].files" {
+		t.Errorf("Expected field source_dirs[0].files, got %s", validationErr.Field)
+	}
+
+	// A target that can't be written, as its directory is a regular file
+	sourceDir := filepath.Join(tempDir, "src")
+	targetDir := filepath.Join(tempDir, "dst")
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	for path, content := range map[string]string{
+		filepath.Join(sourceDir, ".clinerules"): "# Rules\n",
+		targetDir:                               "not a directory",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	report, err := Sync(&Config{
+		SourceDirs: []SourceDir{{Path: sourceDir, Files: []FileSpec{{Pattern: ".clinerules"}}}},
+		TargetDirs: []TargetDir{{Path: targetDir}},
+	}, Options{})
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	var syncErr *SyncError
+	if !errors.As(report.Err(), &syncErr) {
+		t.Fatalf("Expected a SyncError, got %v", report.Err())
+	}
+	if syncErr.TargetFile != filepath.Join(targetDir, ".clinerules") {
+		t.Errorf("Expected the failed target file, got %s", syncErr.TargetFile)
+	}
+}
+
+// refAdjuster adjusts the paths of @ref(...) references
+type refAdjuster struct {
+	pattern *regexp.Regexp
+}
+
+func (a refAdjuster) Adjust(line string, ctx AdjustContext) (string, []AdjustmentResult) {
+	var adjustments []AdjustmentResult
+	adjusted := a.pattern.ReplaceAllStringFunc(line, func(match string) string {
+		path := a.pattern.FindStringSubmatch(match)[1]
+		adjustedPath, err := ctx.AdjustPath(path)
+		if err != nil {
+			return match
+		}
+		adjustments = append(adjustments, AdjustmentResult{OriginalPath: path, AdjustedPath: adjustedPath, Matcher: "ref"})
+		return "@ref(" + adjustedPath + ")"
+	})
+	return adjusted, adjustments
+}
+
+func TestSyncWithCustomAdjuster(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "src")
+	targetDir := filepath.Join(tempDir, "dst")
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	content := "See [Guide](./guide.md)\nFollow @ref(./docs/style.md)\n"
+	if err := os.WriteFile(filepath.Join(sourceDir, ".clinerules"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	cfg := &Config{
+		SourceDirs: []SourceDir{{Path: sourceDir, Files: []FileSpec{{Pattern: ".clinerules"}}}},
+		TargetDirs: []TargetDir{{Path: targetDir}},
+	}
+	adjuster := refAdjuster{regexp.MustCompile(`@ref\(([^)]+)\)`)}
+
+	// Test cases for running the adjuster with and without the built-in patterns
+	testCases := []struct {
+		name              string
+		noBuiltinPatterns bool
+		expected          string
+		adjustments       int
+	}{
+		{
+			name:        "with built-in patterns",
+			expected:    "See [Guide](../src/guide.md)\nFollow @ref(../src/docs/style.md)\n",
+			adjustments: 2,
+		},
+		{
+			name:              "instead of built-in patterns",
+			noBuiltinPatterns: true,
+			expected:          "See [Guide](./guide.md)\nFollow @ref(../src/docs/style.md)\n",
+			adjustments:       1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			report, err := Sync(cfg, Options{Adjusters: []Adjuster{adjuster}, NoBuiltinPatterns: tc.noBuiltinPatterns})
+			if err != nil {
+				t.Fatalf("Failed to sync: %v", err)
+			}
+
+			data, err := os.ReadFile(filepath.Join(targetDir, ".clinerules"))
+			if err != nil {
+				t.Fatalf("Failed to read target file: %v", err)
+			}
+			if string(data) != tc.expected {
+				t.Errorf("Expected content %q, got %q", tc.expected, string(data))
+			}
+
+			// The adjustment of the custom adjuster is recorded with its line
+			adjustments := report.Results[0].PathAdjustments
+			if len(adjustments) != tc.adjustments {
+				t.Fatalf("Expected %d adjustments, got %+v", tc.adjustments, adjustments)
+			}
+			last := adjustments[len(adjustments)-1]
+			if last.Matcher != "ref" || last.LineNumber != 2 || last.AdjustedPath != "../src/docs/style.md" {
+				t.Errorf("Unexpected custom adjustment %+v", last)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package airulesync_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/upamune/airulesync/pkg/airulesync"
+)
+
+func ExampleSync() {
+	// Create a temporary project with a rule file and a sub-project
+	tempDir, err := os.MkdirTemp("", "airulesync-example")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	sourceDir := filepath.Join(tempDir, "project")
+	targetDir := filepath.Join(sourceDir, "sub-project")
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		panic(err)
+	}
+
+	rules := "[Guide](./docs/guide.md)\n"
+	if err := os.WriteFile(filepath.Join(sourceDir, ".clinerules"), []byte(rules), 0644); err != nil {
+		panic(err)
+	}
+
+	// Synchronize the rule file into the sub-project
+	cfg := &airulesync.Config{
+		SourceDirs: []airulesync.SourceDir{
+			{Path: sourceDir, Files: []airulesync.FileSpec{{Pattern: ".clinerules"}}},
+		},
+		TargetDirs: []airulesync.TargetDir{
+			{Path: targetDir},
+		},
+	}
+
+	report, err := airulesync.Sync(cfg, airulesync.Options{})
+	if err != nil {
+		panic(err)
+	}
+
+	synced, err := os.ReadFile(filepath.Join(targetDir, ".clinerules"))
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("results: %d\n", len(report.Results))
+	fmt.Print(string(synced))
+	// Output:
+	// results: 1
+	// [Guide](../docs/guide.md)
+}